@@ -50,13 +50,50 @@ func (cfg *apiConfig) addColumnHandler(w http.ResponseWriter, r *http.Request, i
 		Required: params.Col.Required,
 		SheetID:  sheet_id,
 	}
-	newCol, err := cfg.db.AddColumn(r.Context(), addColumnParams)
+	sheet, err := cfg.db.GetSheet(r.Context(), sheet_id)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Sheet not found")
+		return
+	}
+
+	tx, err := cfg.rawDB.BeginTx(r.Context(), nil)
+	if err != nil {
+		msg := fmt.Sprintf("Could not begin transaction: %s", err)
+		respondWithError(w, http.StatusInternalServerError, msg)
+		return
+	}
+	defer tx.Rollback()
+	txQueries := cfg.db.WithTx(tx)
+
+	newCol, err := txQueries.AddColumn(r.Context(), addColumnParams)
 	if err != nil {
 		msg := fmt.Sprintf("Column could not be updated: %s", err)
 		respondWithError(w, http.StatusInternalServerError, msg)
 		return
 	}
 
+	err = recordHistory(r.Context(), txQueries, id, historyEntry{
+		branchId: sheet.BranchID,
+		sheetId:  sheet.ID,
+		columnId: uuid.NullUUID{UUID: newCol.ID, Valid: true},
+		entity:   HistoryEntityColumn,
+		action:   HistoryActionCreate,
+		newValue: historyColumnValue(newCol),
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		msg := fmt.Sprintf("Could not commit column: %s", err)
+		respondWithError(w, http.StatusInternalServerError, msg)
+		return
+	}
+
+	cfg.invalidateSheetJson(sheet_id, r.Context())
+
 	response := ColumnResponse{
 		ID:       newCol.ID.String(),
 		Name:     newCol.Name,
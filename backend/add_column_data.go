@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"slices"
 
 	"github.com/Dass33/administratum/backend/internal/database"
 	"github.com/google/uuid"
@@ -31,6 +32,40 @@ func (cfg *apiConfig) addColumnDataHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
+	sheet, err := cfg.db.GetSheet(r.Context(), params.Sheet_id)
+	if err != nil {
+		msg := fmt.Sprintf("Could not get sheet: %s", err)
+		respondWithError(w, http.StatusInternalServerError, msg)
+		return
+	}
+
+	columns, err := cfg.db.GetColumnsFromSheet(r.Context(), params.Sheet_id)
+	if err != nil {
+		msg := fmt.Sprintf("Could not get columns: %s", err)
+		respondWithError(w, http.StatusInternalServerError, msg)
+		return
+	}
+	colIdx := slices.IndexFunc(columns, func(col database.Column) bool { return col.Name == params.Col.Name })
+	if colIdx == -1 {
+		respondWithError(w, http.StatusNotFound, "Column not found")
+		return
+	}
+	column := columns[colIdx]
+
+	valueType := cellValueType(column.Type, params.Data.Type)
+	enumVals, err := cfg.getCellEnumVals(sheet.BranchID, valueType, r.Context())
+	if err != nil {
+		msg := fmt.Sprintf("Could not get enums for branch: %s", err)
+		respondWithError(w, http.StatusInternalServerError, msg)
+		return
+	}
+	err = validateCell(params.Data.Value, valueType, column.Required, enumVals)
+	if err != nil {
+		msg := fmt.Sprintf("Invalid value for column %s: %s", column.Name, err)
+		respondWithError(w, http.StatusUnprocessableEntity, msg)
+		return
+	}
+
 	addColumnDataParams := database.AddColumnDataParams{
 		Idx:     params.Data.Idx,
 		Value:   params.Data.Value,
@@ -38,12 +73,49 @@ func (cfg *apiConfig) addColumnDataHandler(w http.ResponseWriter, r *http.Reques
 		Name:    params.Col.Name,
 		SheetID: params.Sheet_id,
 	}
-	_, err = cfg.db.AddColumnData(r.Context(), addColumnDataParams)
+	tx, err := cfg.rawDB.BeginTx(r.Context(), nil)
+	if err != nil {
+		msg := fmt.Sprintf("Could not begin transaction: %s", err)
+		respondWithError(w, http.StatusInternalServerError, msg)
+		return
+	}
+	defer tx.Rollback()
+	txQueries := cfg.db.WithTx(tx)
+
+	colData, err := txQueries.AddColumnData(r.Context(), addColumnDataParams)
 	if err != nil {
 		msg := fmt.Sprintf("Column data could not be updated: %s", err)
 		respondWithError(w, http.StatusInternalServerError, msg)
 		return
 	}
 
+	row, err := sheetRow(r.Context(), txQueries, sheet.ID, colData.Idx)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	err = recordHistory(r.Context(), txQueries, id, historyEntry{
+		branchId: sheet.BranchID,
+		sheetId:  sheet.ID,
+		columnId: uuid.NullUUID{UUID: column.ID, Valid: true},
+		rowId:    uuid.NullUUID{UUID: row.ID, Valid: true},
+		cellId:   uuid.NullUUID{UUID: colData.ID, Valid: true},
+		entity:   HistoryEntityCell,
+		action:   HistoryActionCreate,
+		newValue: historyCellValue(colData.Value, colData.Type),
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		msg := fmt.Sprintf("Could not commit column data: %s", err)
+		respondWithError(w, http.StatusInternalServerError, msg)
+		return
+	}
+
+	cfg.invalidateSheetJson(params.Sheet_id, r.Context())
 	respondWithJSON(w, http.StatusCreated, "")
 }
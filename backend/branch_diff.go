@@ -0,0 +1,327 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+
+	"github.com/Dass33/administratum/backend/internal/database"
+	"github.com/Dass33/administratum/backend/internal/jsondiff"
+	"github.com/google/uuid"
+)
+
+const (
+	DiffModeStructure = "structure"
+	DiffModeJson      = "json"
+
+	DiffAdded   = "added"
+	DiffRemoved = "removed"
+	DiffChanged = "changed"
+)
+
+// BranchDiff lists what differs in the "to" branch compared with the "from"
+// branch. Sheets, columns and rows are matched by their origin, the item
+// their source_sheet_id and source_column_id chains lead back to, so renamed
+// items are still matched.
+type BranchDiff struct {
+	FromBranchID uuid.UUID   `json:"from_branch_id"`
+	ToBranchID   uuid.UUID   `json:"to_branch_id"`
+	Sheets       []SheetDiff `json:"sheets"`
+}
+
+type SheetDiff struct {
+	Change string `json:"change"`
+	// SheetID is the id in the "to" branch, or in the "from" branch for
+	// removed sheets.
+	SheetID uuid.UUID    `json:"sheet_id"`
+	Name    string       `json:"name"`
+	OldName string       `json:"old_name,omitempty"`
+	Columns []ColumnDiff `json:"columns"`
+	// OldOrder and NewOrder list the column names by order_index when the
+	// columns found in both branches were reordered.
+	OldOrder []string   `json:"old_order,omitempty"`
+	NewOrder []string   `json:"new_order,omitempty"`
+	Cells    []CellDiff `json:"cells"`
+}
+
+type ColumnDiff struct {
+	Change   string    `json:"change"`
+	ColumnID uuid.UUID `json:"column_id"`
+	Name     string    `json:"name"`
+	OldName  string    `json:"old_name,omitempty"`
+	Type     string    `json:"type"`
+	OldType  string    `json:"old_type,omitempty"`
+}
+
+// CellDiff is a cell of a column found in both branches. Cells of added rows
+// have no old value and cells of removed rows no new value.
+type CellDiff struct {
+	ColumnID   uuid.UUID `json:"column_id"`
+	ColumnName string    `json:"column_name"`
+	RowID      uuid.UUID `json:"row_id"`
+	RowIndex   int64     `json:"row_index"`
+	OldValue   *string   `json:"old_value"`
+	NewValue   *string   `json:"new_value"`
+}
+
+type BranchJsonDiff struct {
+	FromBranchID uuid.UUID         `json:"from_branch_id"`
+	ToBranchID   uuid.UUID         `json:"to_branch_id"`
+	Changes      []jsondiff.Change `json:"changes"`
+}
+
+// branchDiffHandler compares two branches of a table without merging them.
+// The structure mode compares the sheets, the json mode the exported json
+// in the object shape, so rows of keyed sheets are matched by their key.
+func (cfg *apiConfig) branchDiffHandler(w http.ResponseWriter, r *http.Request, userId uuid.UUID) {
+	query := r.URL.Query()
+	fromBranchId, err := uuid.Parse(query.Get("from_branch_id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid from_branch_id format")
+		return
+	}
+	toBranchId, err := uuid.Parse(query.Get("to_branch_id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid to_branch_id format")
+		return
+	}
+	mode := query.Get("mode")
+	if mode == "" {
+		mode = DiffModeStructure
+	}
+	if mode != DiffModeStructure && mode != DiffModeJson {
+		respondWithError(w, http.StatusBadRequest, "Invalid diff mode")
+		return
+	}
+
+	ctx := r.Context()
+
+	fromBranch, err := cfg.db.GetBranch(ctx, fromBranchId)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Branch not found")
+		return
+	}
+	toBranch, err := cfg.db.GetBranch(ctx, toBranchId)
+	if err != nil || toBranch.TableID != fromBranch.TableID {
+		respondWithError(w, http.StatusNotFound, "Branch not found")
+		return
+	}
+
+	if !cfg.checkBranchPermission(userId, fromBranch.ID, "read", ctx) ||
+		!cfg.checkBranchPermission(userId, toBranch.ID, "read", ctx) {
+		respondWithError(w, http.StatusForbidden, "Insufficient read permissions")
+		return
+	}
+
+	if mode == DiffModeJson {
+		changes, err := cfg.diffBranchJson(fromBranch.ID, toBranch.ID, r)
+		if err != nil {
+			respondWithBranchJsonError(w, err)
+			return
+		}
+		respondWithJSON(w, http.StatusOK, BranchJsonDiff{
+			FromBranchID: fromBranch.ID,
+			ToBranchID:   toBranch.ID,
+			Changes:      changes,
+		})
+		return
+	}
+
+	sides := make([]mergeSide, 0, 2)
+	for _, branch := range []database.Branch{fromBranch, toBranch} {
+		// Unlike a merge the diff does not give ids to the rows, a GET
+		// request does not write.
+		rows, err := cfg.db.GetBranchDataForMerge(ctx, branch.ID)
+		if err != nil {
+			msg := fmt.Sprintf("Could not get branch data: %s", err)
+			respondWithError(w, http.StatusInternalServerError, msg)
+			return
+		}
+		sides = append(sides, buildMergeSide(rows))
+	}
+
+	respondWithJSON(w, http.StatusOK, BranchDiff{
+		FromBranchID: fromBranch.ID,
+		ToBranchID:   toBranch.ID,
+		Sheets:       diffSides(sides[0], sides[1]),
+	})
+}
+
+func (cfg *apiConfig) diffBranchJson(fromBranchId, toBranchId uuid.UUID, r *http.Request) ([]jsondiff.Change, error) {
+	docs := make([]any, 0, 2)
+	for _, branchId := range []uuid.UUID{fromBranchId, toBranchId} {
+		sheets, err := cfg.getBranchJson(branchId, ExportShapeObject, r.Context())
+		if err != nil {
+			return nil, err
+		}
+		// The round trip gives both documents the types jsondiff compares.
+		data, err := json.Marshal(renderSheetsObject(sheets))
+		if err != nil {
+			return nil, fmt.Errorf("Could not encode branch json: %s", err)
+		}
+		var doc any
+		if err := json.Unmarshal(data, &doc); err != nil {
+			return nil, fmt.Errorf("Could not decode branch json: %s", err)
+		}
+		docs = append(docs, doc)
+	}
+	return jsondiff.Diff(docs[0], docs[1]), nil
+}
+
+// diffSides lists the sheets that differ, sheets of the "to" branch first in
+// their order followed by the removed ones.
+func diffSides(from, to mergeSide) []SheetDiff {
+	diffs := []SheetDiff{}
+	for _, key := range to.sheetKeys {
+		toSheet := to.sheets[key]
+		fromSheet, ok := from.sheets[key]
+		if !ok {
+			diffs = append(diffs, SheetDiff{
+				Change:  DiffAdded,
+				SheetID: toSheet.id,
+				Name:    toSheet.name,
+				Columns: []ColumnDiff{},
+				Cells:   []CellDiff{},
+			})
+			continue
+		}
+		if diff, changed := diffSheet(fromSheet, toSheet); changed {
+			diffs = append(diffs, diff)
+		}
+	}
+
+	for _, key := range from.sheetKeys {
+		if _, ok := to.sheets[key]; ok {
+			continue
+		}
+		fromSheet := from.sheets[key]
+		diffs = append(diffs, SheetDiff{
+			Change:  DiffRemoved,
+			SheetID: fromSheet.id,
+			Name:    fromSheet.name,
+			Columns: []ColumnDiff{},
+			Cells:   []CellDiff{},
+		})
+	}
+	return diffs
+}
+
+func diffSheet(from, to *mergeSheet) (SheetDiff, bool) {
+	diff := SheetDiff{
+		Change:  DiffChanged,
+		SheetID: to.id,
+		Name:    to.name,
+		Columns: []ColumnDiff{},
+		Cells:   []CellDiff{},
+	}
+	if from.name != to.name {
+		diff.OldName = from.name
+	}
+
+	for _, columnKey := range to.columnKeys {
+		toColumn := to.columns[columnKey]
+		columnDiff := ColumnDiff{
+			Change:   DiffChanged,
+			ColumnID: toColumn.id,
+			Name:     toColumn.name,
+			Type:     toColumn.colType,
+		}
+		fromColumn, ok := from.columns[columnKey]
+		if !ok {
+			columnDiff.Change = DiffAdded
+			diff.Columns = append(diff.Columns, columnDiff)
+			continue
+		}
+		if fromColumn.name != toColumn.name {
+			columnDiff.OldName = fromColumn.name
+		}
+		if fromColumn.colType != toColumn.colType {
+			columnDiff.OldType = fromColumn.colType
+		}
+		if columnDiff.OldName != "" || columnDiff.OldType != "" {
+			diff.Columns = append(diff.Columns, columnDiff)
+		}
+		diff.Cells = append(diff.Cells, diffCells(from, to, fromColumn, toColumn)...)
+	}
+	for _, columnKey := range from.columnKeys {
+		if _, ok := to.columns[columnKey]; ok {
+			continue
+		}
+		fromColumn := from.columns[columnKey]
+		diff.Columns = append(diff.Columns, ColumnDiff{
+			Change:   DiffRemoved,
+			ColumnID: fromColumn.id,
+			Name:     fromColumn.name,
+			Type:     fromColumn.colType,
+		})
+	}
+
+	if !slices.Equal(commonOrder(from.columnKeys, to.columns), commonOrder(to.columnKeys, from.columns)) {
+		diff.OldOrder = columnNameList(from)
+		diff.NewOrder = columnNameList(to)
+	}
+
+	changed := diff.OldName != "" || len(diff.Columns) > 0 || diff.OldOrder != nil || len(diff.Cells) > 0
+	return diff, changed
+}
+
+// commonOrder returns the keys also found in the other sheet in their order.
+func commonOrder(keys []uuid.UUID, other map[uuid.UUID]*mergeColumn) []uuid.UUID {
+	var common []uuid.UUID
+	for _, key := range keys {
+		if _, ok := other[key]; ok {
+			common = append(common, key)
+		}
+	}
+	return common
+}
+
+func columnNameList(sheet *mergeSheet) []string {
+	names := make([]string, 0, len(sheet.columnKeys))
+	for _, key := range sheet.columnKeys {
+		names = append(names, sheet.columns[key].name)
+	}
+	return names
+}
+
+func diffCells(from, to *mergeSheet, fromColumn, toColumn *mergeColumn) []CellDiff {
+	var cells []CellDiff
+	cellValue := func(column *mergeColumn, rowKey uuid.UUID) *string {
+		value := column.cells[rowKey].cell.value
+		return &value
+	}
+
+	for _, rowKey := range to.rowKeys {
+		toRow := to.rows[rowKey]
+		cell := CellDiff{
+			ColumnID:   toColumn.id,
+			ColumnName: toColumn.name,
+			RowID:      toRow.id,
+			RowIndex:   toRow.idx,
+			NewValue:   cellValue(toColumn, rowKey),
+		}
+		if _, ok := from.rows[rowKey]; ok {
+			cell.OldValue = cellValue(fromColumn, rowKey)
+			if *cell.OldValue == *cell.NewValue {
+				continue
+			}
+		}
+		cells = append(cells, cell)
+	}
+
+	for _, rowKey := range from.rowKeys {
+		if _, ok := to.rows[rowKey]; ok {
+			continue
+		}
+		fromRow := from.rows[rowKey]
+		cells = append(cells, CellDiff{
+			ColumnID:   fromColumn.id,
+			ColumnName: fromColumn.name,
+			RowID:      fromRow.id,
+			RowIndex:   fromRow.idx,
+			OldValue:   cellValue(fromColumn, rowKey),
+		})
+	}
+	return cells
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	"github.com/Dass33/administratum/backend/internal/database"
+	"github.com/google/uuid"
+)
+
+// BranchSnapshot is the content of a branch at one point in time. The base
+// snapshot of a branch is taken from its parent when the branch is created,
+// merges compare both branches against it.
+type BranchSnapshot struct {
+	Sheets []SnapshotSheet `json:"sheets"`
+}
+
+type SnapshotSheet struct {
+	ID      uuid.UUID        `json:"id"`
+	Name    string           `json:"name"`
+	Type    string           `json:"type"`
+	Columns []SnapshotColumn `json:"columns"`
+	Rows    []SnapshotRow    `json:"rows"`
+}
+
+type SnapshotRow struct {
+	ID  uuid.UUID `json:"id"`
+	Idx int64     `json:"idx"`
+}
+
+type SnapshotColumn struct {
+	ID         uuid.UUID      `json:"id"`
+	Name       string         `json:"name"`
+	Type       string         `json:"type"`
+	Required   bool           `json:"required"`
+	IsKey      bool           `json:"is_key"`
+	OrderIndex int64          `json:"order_index"`
+	Cells      []SnapshotCell `json:"cells"`
+}
+
+type SnapshotCell struct {
+	RowID uuid.UUID `json:"row_id"`
+	Value string    `json:"value"`
+	Type  string    `json:"type,omitempty"`
+}
+
+func newBranchSnapshot(side mergeSide) BranchSnapshot {
+	snapshot := BranchSnapshot{Sheets: make([]SnapshotSheet, 0, len(side.sheetKeys))}
+	for _, key := range side.sheetKeys {
+		sheet := side.sheets[key]
+		snapshotSheet := SnapshotSheet{
+			ID:      key,
+			Name:    sheet.name,
+			Type:    sheet.sheetType,
+			Columns: make([]SnapshotColumn, 0, len(sheet.columnKeys)),
+			Rows:    make([]SnapshotRow, 0, len(sheet.rowKeys)),
+		}
+		for _, rowKey := range sheet.rowKeys {
+			snapshotSheet.Rows = append(snapshotSheet.Rows, SnapshotRow{
+				ID:  rowKey,
+				Idx: sheet.rows[rowKey].idx,
+			})
+		}
+
+		for _, columnKey := range sheet.columnKeys {
+			column := sheet.columns[columnKey]
+			snapshotColumn := SnapshotColumn{
+				ID:         columnKey,
+				Name:       column.name,
+				Type:       column.colType,
+				Required:   column.required,
+				IsKey:      column.isKey,
+				OrderIndex: column.orderIndex,
+				Cells:      make([]SnapshotCell, 0, len(column.cells)),
+			}
+			for _, rowKey := range sheet.rowKeys {
+				cell, ok := column.cells[rowKey]
+				if !ok {
+					continue
+				}
+				snapshotColumn.Cells = append(snapshotColumn.Cells, SnapshotCell{
+					RowID: rowKey,
+					Value: cell.cell.value,
+					Type:  cell.cell.cellType,
+				})
+			}
+			snapshotSheet.Columns = append(snapshotSheet.Columns, snapshotColumn)
+		}
+		snapshot.Sheets = append(snapshot.Sheets, snapshotSheet)
+	}
+	return snapshot
+}
+
+// getBranchSnapshot takes a snapshot of the current data of a branch, with
+// its sheets in the order they were created in.
+func getBranchSnapshot(ctx context.Context, q *database.Queries, branchId uuid.UUID) (BranchSnapshot, error) {
+	rows, err := getBranchMergeRows(ctx, q, branchId)
+	if err != nil {
+		return BranchSnapshot{}, err
+	}
+	side := buildMergeSide(rows)
+	slices.SortStableFunc(side.sheetKeys, func(a, b uuid.UUID) int {
+		return side.sheets[a].createdAt.Compare(side.sheets[b].createdAt)
+	})
+	return newBranchSnapshot(side), nil
+}
+
+func (snapshot BranchSnapshot) mergeSide() mergeSide {
+	side := mergeSide{sheets: make(map[uuid.UUID]*mergeSheet)}
+	for _, snapshotSheet := range snapshot.Sheets {
+		sheet := &mergeSheet{
+			id:        snapshotSheet.ID,
+			name:      snapshotSheet.Name,
+			sheetType: snapshotSheet.Type,
+			columns:   make(map[uuid.UUID]*mergeColumn),
+			rows:      make(map[uuid.UUID]*mergeRow),
+		}
+		for _, snapshotRow := range snapshotSheet.Rows {
+			sheet.rows[snapshotRow.ID] = &mergeRow{id: snapshotRow.ID, idx: snapshotRow.Idx}
+			sheet.rowKeys = append(sheet.rowKeys, snapshotRow.ID)
+		}
+
+		for _, snapshotColumn := range snapshotSheet.Columns {
+			column := &mergeColumn{
+				id:         snapshotColumn.ID,
+				name:       snapshotColumn.Name,
+				colType:    snapshotColumn.Type,
+				required:   snapshotColumn.Required,
+				isKey:      snapshotColumn.IsKey,
+				orderIndex: snapshotColumn.OrderIndex,
+				cells:      make(map[uuid.UUID]mergeCellData),
+			}
+			for _, cell := range snapshotColumn.Cells {
+				column.cells[cell.RowID] = mergeCellData{
+					cell: mergeCell{value: cell.Value, cellType: cell.Type},
+				}
+			}
+			sheet.columns[snapshotColumn.ID] = column
+			sheet.columnKeys = append(sheet.columnKeys, snapshotColumn.ID)
+		}
+
+		side.sheets[snapshotSheet.ID] = sheet
+		side.sheetKeys = append(side.sheetKeys, snapshotSheet.ID)
+	}
+	return side
+}
+
+// setBaseSnapshotInTx records the current state of the parent as the base of
+// the branch.
+func (cfg *apiConfig) setBaseSnapshotInTx(ctx context.Context, txQueries *database.Queries, parentBranchId, branchId uuid.UUID) error {
+	rows, err := getBranchMergeRows(ctx, txQueries, parentBranchId)
+	if err != nil {
+		return fmt.Errorf("could not get parent branch data: %w", err)
+	}
+	return saveBaseSnapshotInTx(ctx, txQueries, rows, parentBranchId, branchId)
+}
+
+// rebaseSnapshotInTx records the current state of a branch merged into the
+// parent as its base, so the next merge only brings over later changes.
+func (cfg *apiConfig) rebaseSnapshotInTx(ctx context.Context, txQueries *database.Queries, parentBranchId, branchId uuid.UUID) error {
+	rows, err := getBranchMergeRows(ctx, txQueries, branchId)
+	if err != nil {
+		return fmt.Errorf("could not get branch data: %w", err)
+	}
+	return saveBaseSnapshotInTx(ctx, txQueries, rows, parentBranchId, branchId)
+}
+
+func saveBaseSnapshotInTx(ctx context.Context, txQueries *database.Queries, rows []database.GetBranchDataForMergeRow, parentBranchId, branchId uuid.UUID) error {
+	return storeBaseSnapshotInTx(ctx, txQueries, newBranchSnapshot(buildMergeSide(rows)), parentBranchId, branchId)
+}
+
+func storeBaseSnapshotInTx(ctx context.Context, txQueries *database.Queries, snapshot BranchSnapshot, parentBranchId, branchId uuid.UUID) error {
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return fmt.Errorf("could not encode base snapshot: %w", err)
+	}
+
+	err = txQueries.SetBaseSnapshot(ctx, database.SetBaseSnapshotParams{
+		BranchID:       branchId,
+		ParentBranchID: uuid.NullUUID{UUID: parentBranchId, Valid: true},
+		Data:           string(data),
+	})
+	if err != nil {
+		return fmt.Errorf("could not save base snapshot: %w", err)
+	}
+	return nil
+}
+
+// getMergeBase picks the base snapshot closest to the common ancestor of the
+// two branches. Branches created before base snapshots were stored get one
+// guessed from the timestamps.
+func (cfg *apiConfig) getMergeBase(txQueries *database.Queries, sourceBranch, targetBranch database.Branch, source, target mergeSide, ctx context.Context) (mergeBase, error) {
+	sourceBase, err := txQueries.GetBaseSnapshot(ctx, sourceBranch.ID)
+	hasSource := err == nil
+	if err != nil && err != sql.ErrNoRows {
+		return mergeBase{}, fmt.Errorf("Could not get base snapshot: %s", err)
+	}
+	targetBase, err := txQueries.GetBaseSnapshot(ctx, targetBranch.ID)
+	hasTarget := err == nil
+	if err != nil && err != sql.ErrNoRows {
+		return mergeBase{}, fmt.Errorf("Could not get base snapshot: %s", err)
+	}
+
+	var baseSnapshot database.BaseSnapshot
+	switch {
+	case hasSource && sourceBase.ParentBranchID.UUID == targetBranch.ID:
+		baseSnapshot = sourceBase
+	case hasTarget && targetBase.ParentBranchID.UUID == sourceBranch.ID:
+		baseSnapshot = targetBase
+	case hasSource && hasTarget && sourceBase.ParentBranchID.Valid &&
+		sourceBase.ParentBranchID == targetBase.ParentBranchID:
+		// Siblings share the older of the two snapshots of their parent.
+		baseSnapshot = sourceBase
+		if targetBase.CreatedAt.Before(sourceBase.CreatedAt) {
+			baseSnapshot = targetBase
+		}
+	case hasSource:
+		baseSnapshot = sourceBase
+	default:
+		return legacyMergeBase(source, target, sourceBranch.CreatedAt), nil
+	}
+
+	var snapshot BranchSnapshot
+	err = json.Unmarshal([]byte(baseSnapshot.Data), &snapshot)
+	if err != nil {
+		return mergeBase{}, fmt.Errorf("Could not decode base snapshot: %s", err)
+	}
+
+	return mergeBase{
+		mergeSide: snapshot.mergeSide(),
+		unknown:   make(map[string]bool),
+	}, nil
+}
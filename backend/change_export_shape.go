@@ -0,0 +1,66 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/Dass33/administratum/backend/internal/database"
+	"github.com/google/uuid"
+)
+
+type changeExportShapeParams struct {
+	ExportShape string `json:"export_shape"`
+	TableId     string `json:"table_id"`
+}
+
+func (cfg *apiConfig) changeExportShapeHandler(w http.ResponseWriter, r *http.Request, userId uuid.UUID) {
+	decoder := json.NewDecoder(r.Body)
+	params := changeExportShapeParams{}
+
+	err := decoder.Decode(&params)
+	if err != nil {
+		msg := fmt.Sprintf("Error decoding parameters: %s", err)
+		respondWithError(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	if !isValidExportShape(params.ExportShape) {
+		respondWithError(w, http.StatusBadRequest, "Given export shape is not valid")
+		return
+	}
+
+	projectId, err := uuid.Parse(params.TableId)
+	if err != nil {
+		msg := fmt.Sprintf("Could not parse the project id: %s", err)
+		respondWithError(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	if !cfg.checkTablePermission(userId, projectId, "write", r.Context()) {
+		respondWithError(w, http.StatusForbidden, "Insufficient write permissions")
+		return
+	}
+
+	changeExportShapeParams := database.ChangeExportShapeParams{
+		ExportShape: params.ExportShape,
+		ID:          projectId,
+	}
+	err = cfg.db.ChangeExportShape(r.Context(), changeExportShapeParams)
+	if err != nil {
+		msg := fmt.Sprintf("Export shape could not be changed: %s", err)
+		respondWithError(w, http.StatusInternalServerError, msg)
+		return
+	}
+
+	branches, err := cfg.db.GetBranchesFromTable(r.Context(), projectId)
+	if err != nil {
+		msg := fmt.Sprintf("Could not get branches from project: %s", err)
+		respondWithError(w, http.StatusInternalServerError, msg)
+		return
+	}
+	for _, branch := range branches {
+		cfg.invalidateBranchJson(branch.ID)
+	}
+	respondWithJSON(w, http.StatusOK, "")
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/Dass33/administratum/backend/internal/database"
+	"github.com/google/uuid"
+)
+
+type commentMergeRequestParams struct {
+	MergeRequestId string `json:"merge_request_id"`
+	Body           string `json:"body"`
+}
+
+func (cfg *apiConfig) commentMergeRequestHandler(w http.ResponseWriter, r *http.Request, userId uuid.UUID) {
+	decoder := json.NewDecoder(r.Body)
+	params := commentMergeRequestParams{}
+	err := decoder.Decode(&params)
+	if err != nil {
+		msg := fmt.Sprintf("Error decoding parameters: %s", err)
+		respondWithError(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	mergeRequestId, err := uuid.Parse(params.MergeRequestId)
+	if err != nil {
+		msg := fmt.Sprintf("Could not parse the merge request id: %s", err)
+		respondWithError(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	if strings.TrimSpace(params.Body) == "" {
+		respondWithError(w, http.StatusBadRequest, "Comment can not be empty")
+		return
+	}
+
+	mergeRequestDb, err := cfg.db.GetMergeRequest(r.Context(), mergeRequestId)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Merge request not found")
+		return
+	}
+
+	if !cfg.checkTablePermission(userId, mergeRequestDb.TableID, "read", r.Context()) {
+		respondWithError(w, http.StatusForbidden, "Insufficient read permissions")
+		return
+	}
+
+	createParams := database.CreateMergeRequestCommentParams{
+		MergeRequestID: mergeRequestId,
+		AuthorID:       uuid.NullUUID{UUID: userId, Valid: true},
+		Body:           params.Body,
+	}
+	comment, err := cfg.db.CreateMergeRequestComment(r.Context(), createParams)
+	if err != nil {
+		msg := fmt.Sprintf("Could not create comment: %s", err)
+		respondWithError(w, http.StatusInternalServerError, msg)
+		return
+	}
+
+	user, err := cfg.db.GetUser(r.Context(), userId)
+	if err != nil {
+		msg := fmt.Sprintf("User with id not found: %s", err)
+		respondWithError(w, http.StatusInternalServerError, msg)
+		return
+	}
+
+	response := MergeRequestComment{
+		ID:          comment.ID,
+		AuthorEmail: user.Email,
+		Body:        comment.Body,
+		CreatedAt:   comment.CreatedAt,
+	}
+	respondWithJSON(w, http.StatusCreated, response)
+}
@@ -16,6 +16,12 @@ type createBranchParam struct {
 	Name        string `json:"name"`
 	IsProtected bool   `json:"is_protected"`
 	TableId     string `json:"table_id"`
+	// ParentBranchId is the branch the sheets are copied from, the oldest
+	// branch of the table when left empty.
+	ParentBranchId string `json:"parent_branch_id"`
+	// TagId creates the branch from the snapshot of a tag instead of a
+	// parent branch.
+	TagId string `json:"tag_id"`
 }
 
 func (cfg *apiConfig) createBranchHandler(w http.ResponseWriter, r *http.Request, userId uuid.UUID) {
@@ -39,6 +45,43 @@ func (cfg *apiConfig) createBranchHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	if params.TagId != "" && params.ParentBranchId != "" {
+		respondWithError(w, http.StatusBadRequest, "Branch can not be created from both a tag and a parent branch")
+		return
+	}
+
+	var tag *database.Tag
+	if params.TagId != "" {
+		id, err := uuid.Parse(params.TagId)
+		if err != nil {
+			msg := fmt.Sprintf("Could not parse the tag id: %s", err)
+			respondWithError(w, http.StatusBadRequest, msg)
+			return
+		}
+		tagDb, err := cfg.db.GetTag(r.Context(), id)
+		if err != nil || tagDb.TableID != tableId {
+			respondWithError(w, http.StatusBadRequest, "Tag does not belong to the table")
+			return
+		}
+		tag = &tagDb
+	}
+
+	parentBranchId := uuid.NullUUID{}
+	if params.ParentBranchId != "" {
+		id, err := uuid.Parse(params.ParentBranchId)
+		if err != nil {
+			msg := fmt.Sprintf("Could not parse the parent branch id: %s", err)
+			respondWithError(w, http.StatusBadRequest, msg)
+			return
+		}
+		parentBranch, err := cfg.db.GetBranch(r.Context(), id)
+		if err != nil || parentBranch.TableID != tableId {
+			respondWithError(w, http.StatusBadRequest, "Parent branch does not belong to the table")
+			return
+		}
+		parentBranchId = uuid.NullUUID{UUID: id, Valid: true}
+	}
+
 	createBranchParams := database.CreateBranchParams{
 		Name:        params.Name,
 		IsProtected: params.IsProtected,
@@ -52,21 +95,35 @@ func (cfg *apiConfig) createBranchHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	oldestBranch, err := cfg.db.GetOldestBranchFromTable(r.Context(), tableId)
-	if err != nil {
-		if err != sql.ErrNoRows {
+	if tag != nil {
+		err = cfg.copyTagSheetsWithTransaction(r.Context(), *tag, branch.ID)
+		if err != nil {
+			msg := fmt.Sprintf("Could not copy tag to new branch: %s", err)
+			respondWithError(w, http.StatusInternalServerError, msg)
+			return
+		}
+		cfg.switchBranch(w, r, branch.ID, userId, http.StatusCreated)
+		return
+	}
+
+	if !parentBranchId.Valid {
+		oldestBranch, err := cfg.db.GetOldestBranchFromTable(r.Context(), tableId)
+		if err != nil && err != sql.ErrNoRows {
 			msg := fmt.Sprintf("Could not get oldest branch: %s", err)
 			respondWithError(w, http.StatusInternalServerError, msg)
 			return
 		}
-	} else {
-		if oldestBranch.ID != branch.ID {
-			err = cfg.copyBranchSheetsWithTransaction(r.Context(), oldestBranch.ID, branch.ID)
-			if err != nil {
-				msg := fmt.Sprintf("Could not copy sheets to new branch: %s", err)
-				respondWithError(w, http.StatusInternalServerError, msg)
-				return
-			}
+		if err == nil && oldestBranch.ID != branch.ID {
+			parentBranchId = uuid.NullUUID{UUID: oldestBranch.ID, Valid: true}
+		}
+	}
+
+	if parentBranchId.Valid {
+		err = cfg.copyBranchSheetsWithTransaction(r.Context(), parentBranchId.UUID, branch.ID)
+		if err != nil {
+			msg := fmt.Sprintf("Could not copy sheets to new branch: %s", err)
+			respondWithError(w, http.StatusInternalServerError, msg)
+			return
 		}
 	}
 
@@ -91,6 +148,11 @@ func (cfg *apiConfig) copyBranchSheetsWithTransaction(ctx context.Context, sourc
 		return err
 	}
 
+	err = cfg.setBaseSnapshotInTx(ctx, txQueries, sourceBranchId, targetBranchId)
+	if err != nil {
+		return err
+	}
+
 	err = tx.Commit()
 	if err != nil {
 		return fmt.Errorf("could not commit transaction: %w", err)
@@ -99,7 +161,48 @@ func (cfg *apiConfig) copyBranchSheetsWithTransaction(ctx context.Context, sourc
 	return nil
 }
 
+// copyTagSheetsWithTransaction fills the branch with the snapshot of the tag.
+// While the tagged branch still exists, the snapshot becomes the base of the
+// new branch, so its changes since the tag can be merged back.
+func (cfg *apiConfig) copyTagSheetsWithTransaction(ctx context.Context, tag database.Tag, targetBranchId uuid.UUID) error {
+	snapshot, err := decodeTagSnapshot(tag)
+	if err != nil {
+		return err
+	}
+
+	tx, err := cfg.rawDB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("could not begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	txQueries := cfg.db.WithTx(tx)
+
+	err = cfg.copySnapshotSheetsInTx(ctx, tx, txQueries, snapshot, targetBranchId)
+	if err != nil {
+		return err
+	}
+
+	if tag.BranchID.Valid {
+		err = storeBaseSnapshotInTx(ctx, txQueries, snapshot, tag.BranchID.UUID, targetBranchId)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("could not commit transaction: %w", err)
+	}
+	return nil
+}
+
 func (cfg *apiConfig) copyBranchSheetsInTx(ctx context.Context, tx *sql.Tx, txQueries *database.Queries, sourceBranchId, targetBranchId uuid.UUID) error {
+	err := txQueries.EnsureSheetRows(ctx, sourceBranchId)
+	if err != nil {
+		return fmt.Errorf("could not create row ids: %w", err)
+	}
+
 	dbSheets, err := txQueries.GetSheetsFromBranch(ctx, sourceBranchId)
 	if err != nil {
 		return fmt.Errorf("could not get source branch sheets: %w", err)
@@ -111,6 +214,7 @@ func (cfg *apiConfig) copyBranchSheetsInTx(ctx context.Context, tx *sql.Tx, txQu
 			Name:          dbSheets[i].Name,
 			Type:          dbSheets[i].Type,
 			SourceSheetID: sql.NullString{String: dbSheets[i].ID.String(), Valid: true},
+			OriginSheetID: uuid.NullUUID{UUID: originKey(dbSheets[i].OriginSheetID, dbSheets[i].ID), Valid: true},
 		}
 		sheet, err := txQueries.CreateSheet(ctx, createSheetParams)
 		if err != nil {
@@ -121,6 +225,78 @@ func (cfg *apiConfig) copyBranchSheetsInTx(ctx context.Context, tx *sql.Tx, txQu
 		if err != nil {
 			return fmt.Errorf("could not copy columns for sheet %s: %w", sheet.Name, err)
 		}
+
+		err = txQueries.CopySheetRows(ctx, database.CopySheetRowsParams{
+			TargetSheetID: sheet.ID,
+			SourceSheetID: dbSheets[i].ID,
+		})
+		if err != nil {
+			return fmt.Errorf("could not copy rows for sheet %s: %w", sheet.Name, err)
+		}
+	}
+	return nil
+}
+
+// copySnapshotSheetsInTx creates the sheets of a snapshot in the branch like
+// copyBranchSheetsInTx, the copies keep the origins recorded in the snapshot
+// so merges still match them up.
+func (cfg *apiConfig) copySnapshotSheetsInTx(ctx context.Context, tx *sql.Tx, txQueries *database.Queries, snapshot BranchSnapshot, targetBranchId uuid.UUID) error {
+	for _, snapshotSheet := range snapshot.Sheets {
+		createSheetParams := database.CreateSheetParams{
+			BranchID:      targetBranchId,
+			Name:          snapshotSheet.Name,
+			Type:          snapshotSheet.Type,
+			OriginSheetID: uuid.NullUUID{UUID: snapshotSheet.ID, Valid: true},
+		}
+		sheet, err := txQueries.CreateSheet(ctx, createSheetParams)
+		if err != nil {
+			return fmt.Errorf("could not create sheet: %w", err)
+		}
+
+		idxs := make(map[uuid.UUID]int64, len(snapshotSheet.Rows))
+		for _, row := range snapshotSheet.Rows {
+			_, err = txQueries.CreateSheetRow(ctx, database.CreateSheetRowParams{
+				SheetID:     sheet.ID,
+				Idx:         row.Idx,
+				OriginRowID: uuid.NullUUID{UUID: row.ID, Valid: true},
+			})
+			if err != nil {
+				return fmt.Errorf("could not create row for sheet %s: %w", sheet.Name, err)
+			}
+			idxs[row.ID] = row.Idx
+		}
+
+		for _, snapshotColumn := range snapshotSheet.Columns {
+			addColumnParams := database.AddColumnParams{
+				Name:           snapshotColumn.Name,
+				Type:           snapshotColumn.Type,
+				Required:       snapshotColumn.Required,
+				SheetID:        sheet.ID,
+				IsKey:          snapshotColumn.IsKey,
+				OriginColumnID: uuid.NullUUID{UUID: snapshotColumn.ID, Valid: true},
+			}
+			newColumn, err := txQueries.AddColumn(ctx, addColumnParams)
+			if err != nil {
+				return fmt.Errorf("could not add column: %w", err)
+			}
+
+			data := make([]ColumnData, 0, len(snapshotColumn.Cells))
+			for _, cell := range snapshotColumn.Cells {
+				idx, ok := idxs[cell.RowID]
+				if !ok {
+					continue
+				}
+				data = append(data, ColumnData{
+					Idx:   idx,
+					Value: sql.NullString{String: cell.Value, Valid: true},
+					Type:  nullCellType(cell.Type),
+				})
+			}
+			err = cfg.copyColumnDataBulk(ctx, tx, data, newColumn.ID)
+			if err != nil {
+				return fmt.Errorf("could not copy data for column %s: %w", snapshotColumn.Name, err)
+			}
+		}
 	}
 	return nil
 }
@@ -130,6 +306,14 @@ func (cfg *apiConfig) copySheetColumnsInTx(ctx context.Context, tx *sql.Tx, txQu
 	if err != nil {
 		return fmt.Errorf("could not get columns: %w", err)
 	}
+	dbColumns, err := txQueries.GetColumnsFromSheet(ctx, sourceSheetId)
+	if err != nil {
+		return fmt.Errorf("could not get column origins: %w", err)
+	}
+	origins := make(map[uuid.UUID]uuid.UUID, len(dbColumns))
+	for _, column := range dbColumns {
+		origins[column.ID] = originKey(column.OriginColumnID, column.ID)
+	}
 
 	for e := range columns {
 		addColumnParams := database.AddColumnParams{
@@ -138,6 +322,8 @@ func (cfg *apiConfig) copySheetColumnsInTx(ctx context.Context, tx *sql.Tx, txQu
 			Required:       columns[e].Required,
 			SheetID:        targetSheetId,
 			SourceColumnID: sql.NullString{String: columns[e].ID.String(), Valid: true},
+			IsKey:          columns[e].IsKey,
+			OriginColumnID: uuid.NullUUID{UUID: origins[columns[e].ID], Valid: true},
 		}
 		newColumn, err := txQueries.AddColumn(ctx, addColumnParams)
 		if err != nil {
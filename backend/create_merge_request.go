@@ -0,0 +1,288 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Dass33/administratum/backend/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	MergeRequestOpen   = "open"
+	MergeRequestMerged = "merged"
+	MergeRequestClosed = "closed"
+)
+
+const (
+	ReviewApproved         = "approved"
+	ReviewChangesRequested = "changes_requested"
+)
+
+type createMergeRequestParams struct {
+	SourceBranchId string `json:"source_branch_id"`
+	// TargetBranchId defaults to the oldest branch of the table.
+	TargetBranchId string `json:"target_branch_id"`
+	Title          string `json:"title"`
+	Description    string `json:"description"`
+}
+
+type MergeRequestReview struct {
+	UserID    uuid.UUID `json:"user_id"`
+	UserEmail string    `json:"user_email"`
+	State     string    `json:"state"`
+	// Stale is set when the source branch changed after the review, a stale
+	// approval does not count.
+	Stale     bool      `json:"stale"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type MergeRequestComment struct {
+	ID          uuid.UUID `json:"id"`
+	AuthorEmail string    `json:"author_email"`
+	Body        string    `json:"body"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// MergeRequest keeps the names of both branches, so it still reads well after
+// the source branch was merged and deleted.
+type MergeRequest struct {
+	ID                uuid.UUID             `json:"id"`
+	TableID           uuid.UUID             `json:"table_id"`
+	SourceBranchID    uuid.NullUUID         `json:"source_branch_id"`
+	SourceBranchName  string                `json:"source_branch_name"`
+	TargetBranchID    uuid.NullUUID         `json:"target_branch_id"`
+	TargetBranchName  string                `json:"target_branch_name"`
+	Title             string                `json:"title"`
+	Description       string                `json:"description"`
+	Status            string                `json:"status"`
+	AuthorEmail       string                `json:"author_email"`
+	MergedByEmail     string                `json:"merged_by_email,omitempty"`
+	Conflicts         []MergeConflict       `json:"conflicts"`
+	Approvals         int64                 `json:"approvals"`
+	ChangesRequested  int64                 `json:"changes_requested"`
+	RequiredApprovals int64                 `json:"required_approvals"`
+	Mergeable         bool                  `json:"mergeable"`
+	Reviews           []MergeRequestReview  `json:"reviews"`
+	Comments          []MergeRequestComment `json:"comments"`
+	CreatedAt         time.Time             `json:"created_at"`
+	UpdatedAt         time.Time             `json:"updated_at"`
+	MergedAt          *time.Time            `json:"merged_at,omitempty"`
+}
+
+func (cfg *apiConfig) createMergeRequestHandler(w http.ResponseWriter, r *http.Request, userId uuid.UUID) {
+	decoder := json.NewDecoder(r.Body)
+	params := createMergeRequestParams{}
+	err := decoder.Decode(&params)
+	if err != nil {
+		msg := fmt.Sprintf("Error decoding parameters: %s", err)
+		respondWithError(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	sourceBranchId, err := uuid.Parse(params.SourceBranchId)
+	if err != nil {
+		msg := fmt.Sprintf("Could not parse the source branch id: %s", err)
+		respondWithError(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	title := strings.TrimSpace(params.Title)
+	if title == "" {
+		respondWithError(w, http.StatusBadRequest, "Merge request needs a title")
+		return
+	}
+
+	if !cfg.checkBranchPermission(userId, sourceBranchId, "read", r.Context()) {
+		respondWithError(w, http.StatusForbidden, "Insufficient read permissions")
+		return
+	}
+
+	sourceBranch, err := cfg.db.GetBranch(r.Context(), sourceBranchId)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Source branch not found")
+		return
+	}
+
+	targetBranchId := uuid.NullUUID{}
+	if params.TargetBranchId != "" {
+		id, err := uuid.Parse(params.TargetBranchId)
+		if err != nil {
+			msg := fmt.Sprintf("Could not parse the target branch id: %s", err)
+			respondWithError(w, http.StatusBadRequest, msg)
+			return
+		}
+		targetBranchId = uuid.NullUUID{UUID: id, Valid: true}
+	}
+
+	targetBranch, ok := cfg.getMergeTarget(w, sourceBranch, targetBranchId, r.Context())
+	if !ok {
+		return
+	}
+
+	_, err = cfg.db.GetOpenMergeRequestFromBranch(r.Context(), uuid.NullUUID{UUID: sourceBranch.ID, Valid: true})
+	if err == nil {
+		respondWithError(w, http.StatusConflict, "Branch already has an open merge request")
+		return
+	} else if err != sql.ErrNoRows {
+		msg := fmt.Sprintf("Could not get open merge requests: %s", err)
+		respondWithError(w, http.StatusInternalServerError, msg)
+		return
+	}
+
+	conflicts, err := cfg.getMergeConflicts(sourceBranch, targetBranch, r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	conflictsJson, err := json.Marshal(conflicts)
+	if err != nil {
+		msg := fmt.Sprintf("Could not encode conflicts: %s", err)
+		respondWithError(w, http.StatusInternalServerError, msg)
+		return
+	}
+
+	createParams := database.CreateMergeRequestParams{
+		TableID:          sourceBranch.TableID,
+		SourceBranchID:   uuid.NullUUID{UUID: sourceBranch.ID, Valid: true},
+		SourceBranchName: sourceBranch.Name,
+		TargetBranchID:   uuid.NullUUID{UUID: targetBranch.ID, Valid: true},
+		TargetBranchName: targetBranch.Name,
+		AuthorID:         uuid.NullUUID{UUID: userId, Valid: true},
+		Title:            title,
+		Description:      params.Description,
+		Conflicts:        string(conflictsJson),
+	}
+	mergeRequestDb, err := cfg.db.CreateMergeRequest(r.Context(), createParams)
+	if err != nil {
+		msg := fmt.Sprintf("Could not create merge request: %s", err)
+		respondWithError(w, http.StatusInternalServerError, msg)
+		return
+	}
+
+	mergeRequest, err := cfg.getMergeRequest(mergeRequestDb.ID, r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	respondWithJSON(w, http.StatusCreated, mergeRequest)
+}
+
+// getMergeRequest loads a merge request with its reviews and comments and
+// works out whether it has enough approvals to be merged.
+func (cfg *apiConfig) getMergeRequest(mergeRequestId uuid.UUID, ctx context.Context) (MergeRequest, error) {
+	mergeRequestDb, err := cfg.db.GetMergeRequest(ctx, mergeRequestId)
+	if err != nil {
+		return MergeRequest{}, fmt.Errorf("Could not get merge request: %s", err)
+	}
+
+	mergeRequest := MergeRequest{
+		ID:               mergeRequestDb.ID,
+		TableID:          mergeRequestDb.TableID,
+		SourceBranchID:   mergeRequestDb.SourceBranchID,
+		SourceBranchName: mergeRequestDb.SourceBranchName,
+		TargetBranchID:   mergeRequestDb.TargetBranchID,
+		TargetBranchName: mergeRequestDb.TargetBranchName,
+		Title:            mergeRequestDb.Title,
+		Description:      mergeRequestDb.Description,
+		Status:           mergeRequestDb.Status,
+		Conflicts:        []MergeConflict{},
+		Reviews:          []MergeRequestReview{},
+		Comments:         []MergeRequestComment{},
+		CreatedAt:        mergeRequestDb.CreatedAt,
+		UpdatedAt:        mergeRequestDb.UpdatedAt,
+	}
+	if mergeRequestDb.MergedAt.Valid {
+		mergeRequest.MergedAt = &mergeRequestDb.MergedAt.Time
+	}
+
+	err = json.Unmarshal([]byte(mergeRequestDb.Conflicts), &mergeRequest.Conflicts)
+	if err != nil {
+		return MergeRequest{}, fmt.Errorf("Could not decode merge request conflicts: %s", err)
+	}
+
+	if mergeRequestDb.AuthorID.Valid {
+		if author, err := cfg.db.GetUser(ctx, mergeRequestDb.AuthorID.UUID); err == nil {
+			mergeRequest.AuthorEmail = author.Email
+		}
+	}
+	if mergeRequestDb.MergedBy.Valid {
+		if mergedBy, err := cfg.db.GetUser(ctx, mergeRequestDb.MergedBy.UUID); err == nil {
+			mergeRequest.MergedByEmail = mergedBy.Email
+		}
+	}
+
+	reviews, err := cfg.db.GetMergeRequestReviews(ctx, mergeRequestId)
+	if err != nil {
+		return MergeRequest{}, fmt.Errorf("Could not get merge request reviews: %s", err)
+	}
+	sourceToken := ""
+	if mergeRequestDb.SourceBranchID.Valid {
+		sourceToken, err = getSourceToken(ctx, cfg.db, mergeRequestDb.SourceBranchID.UUID)
+		if err != nil {
+			return MergeRequest{}, err
+		}
+	}
+	for _, review := range reviews {
+		mergeRequest.Reviews = append(mergeRequest.Reviews, MergeRequestReview{
+			UserID:    review.UserID,
+			UserEmail: review.UserEmail,
+			State:     review.State,
+			Stale:     review.SourceToken != sourceToken,
+			UpdatedAt: review.UpdatedAt,
+		})
+	}
+	mergeRequest.Approvals, mergeRequest.ChangesRequested = countReviews(reviews, mergeRequestDb.AuthorID, sourceToken)
+
+	comments, err := cfg.db.GetMergeRequestComments(ctx, mergeRequestId)
+	if err != nil {
+		return MergeRequest{}, fmt.Errorf("Could not get merge request comments: %s", err)
+	}
+	for _, comment := range comments {
+		mergeRequest.Comments = append(mergeRequest.Comments, MergeRequestComment{
+			ID:          comment.ID,
+			AuthorEmail: comment.AuthorEmail.String,
+			Body:        comment.Body,
+			CreatedAt:   comment.CreatedAt,
+		})
+	}
+
+	if mergeRequestDb.TargetBranchID.Valid {
+		if targetBranch, err := cfg.db.GetBranch(ctx, mergeRequestDb.TargetBranchID.UUID); err == nil {
+			mergeRequest.RequiredApprovals = targetBranch.RequiredApprovals
+		}
+	}
+
+	mergeRequest.Mergeable = mergeRequest.Status == MergeRequestOpen &&
+		mergeRequest.SourceBranchID.Valid && mergeRequest.TargetBranchID.Valid &&
+		mergeRequest.ChangesRequested == 0 &&
+		mergeRequest.Approvals >= mergeRequest.RequiredApprovals
+	return mergeRequest, nil
+}
+
+// countReviews counts the approvals and the requests for changes, reviews of
+// the author never count towards the threshold. Approvals only count when
+// they were given on the data the source branch has now, requests for
+// changes stay until the reviewer replaces them.
+func countReviews(reviews []database.GetMergeRequestReviewsRow, authorId uuid.NullUUID, sourceToken string) (int64, int64) {
+	var approvals, changesRequested int64
+	for _, review := range reviews {
+		if authorId.Valid && review.UserID == authorId.UUID {
+			continue
+		}
+		switch review.State {
+		case ReviewApproved:
+			if review.SourceToken == sourceToken {
+				approvals++
+			}
+		case ReviewChangesRequested:
+			changesRequested++
+		}
+	}
+	return approvals, changesRequested
+}
@@ -12,6 +12,7 @@ import (
 
 const SheetTypeMap = "map"
 const SheetTypeList = "list"
+const SheetTypeEnums = "enums"
 
 func (cfg *apiConfig) createSheetHandler(w http.ResponseWriter, r *http.Request, userId uuid.UUID) {
 	decoder := json.NewDecoder(r.Body)
@@ -28,16 +29,25 @@ func (cfg *apiConfig) createSheetHandler(w http.ResponseWriter, r *http.Request,
 		return
 	}
 
+	tx, err := cfg.rawDB.BeginTx(r.Context(), nil)
+	if err != nil {
+		msg := fmt.Sprintf("Could not begin transaction: %s", err)
+		respondWithError(w, http.StatusInternalServerError, msg)
+		return
+	}
+	defer tx.Rollback()
+	txQueries := cfg.db.WithTx(tx)
+
 	sheetId := uuid.UUID{}
 	if params.Type == SheetTypeMap {
-		sheetId, err = cfg.createMapSheet(r.Context(), params.Name, params.BranchID)
+		sheetId, err = cfg.createMapSheetWithTx(txQueries, r.Context(), params.Name, params.BranchID)
 		if err != nil {
 			msg := fmt.Sprintf("Could not create map sheet: %s", err)
 			respondWithError(w, http.StatusInternalServerError, msg)
 			return
 		}
 	} else {
-		sheet, err := cfg.db.CreateSheet(r.Context(), params)
+		sheet, err := txQueries.CreateSheet(r.Context(), params)
 		if err != nil {
 			msg := fmt.Sprintf("Could not create list sheet: %s", err)
 			respondWithError(w, http.StatusInternalServerError, msg)
@@ -46,6 +56,26 @@ func (cfg *apiConfig) createSheetHandler(w http.ResponseWriter, r *http.Request,
 		sheetId = sheet.ID
 	}
 
+	err = recordHistory(r.Context(), txQueries, userId, historyEntry{
+		branchId: params.BranchID,
+		sheetId:  sheetId,
+		entity:   HistoryEntitySheet,
+		action:   HistoryActionCreate,
+		newValue: HistorySheetValue{Name: params.Name, Type: params.Type},
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		msg := fmt.Sprintf("Could not commit sheet: %s", err)
+		respondWithError(w, http.StatusInternalServerError, msg)
+		return
+	}
+	cfg.invalidateBranchJson(params.BranchID)
+
 	optionalSheetId := uuid.NullUUID{
 		UUID:  sheetId,
 		Valid: true,
@@ -72,16 +102,20 @@ func (cfg *apiConfig) createSheetHandler(w http.ResponseWriter, r *http.Request,
 }
 
 func (cfg *apiConfig) createMapSheet(ctx context.Context, name string, branchID uuid.UUID) (uuid.UUID, error) {
+	return cfg.createMapSheetWithTx(cfg.db, ctx, name, branchID)
+}
+
+func (cfg *apiConfig) createMapSheetWithTx(txQueries *database.Queries, ctx context.Context, name string, branchID uuid.UUID) (uuid.UUID, error) {
 	createMapSheetParams := database.CreateMapSheetParams{
 		Name:     name,
 		BranchID: branchID,
 	}
-	sheetId, err := cfg.db.CreateMapSheet(ctx, createMapSheetParams)
+	sheetId, err := txQueries.CreateMapSheet(ctx, createMapSheetParams)
 	if err != nil {
 		return uuid.UUID{}, err
 	}
 
-	err = cfg.db.CreateMapSheetColumns(ctx, sheetId)
+	err = txQueries.CreateMapSheetColumns(ctx, sheetId)
 	if err != nil {
 		return uuid.UUID{}, err
 	}
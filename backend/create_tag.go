@@ -0,0 +1,165 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Dass33/administratum/backend/internal/database"
+	"github.com/google/uuid"
+)
+
+type createTagParams struct {
+	BranchId string `json:"branch_id"`
+	Name     string `json:"name"`
+	Message  string `json:"message"`
+}
+
+// Tag is a named read-only snapshot of a branch. It keeps the sheets,
+// columns and cells of the branch when it was created, and its json export
+// when the branch could be exported then.
+type Tag struct {
+	ID          uuid.UUID     `json:"id"`
+	BranchID    uuid.NullUUID `json:"branch_id"`
+	BranchName  string        `json:"branch_name"`
+	Name        string        `json:"name"`
+	Message     string        `json:"message"`
+	AuthorEmail string        `json:"author_email"`
+	CreatedAt   time.Time     `json:"created_at"`
+}
+
+func (cfg *apiConfig) createTagHandler(w http.ResponseWriter, r *http.Request, userId uuid.UUID) {
+	decoder := json.NewDecoder(r.Body)
+	params := createTagParams{}
+	err := decoder.Decode(&params)
+	if err != nil {
+		msg := fmt.Sprintf("Error decoding parameters: %s", err)
+		respondWithError(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	branchId, err := uuid.Parse(params.BranchId)
+	if err != nil {
+		msg := fmt.Sprintf("Could not parse the branch id: %s", err)
+		respondWithError(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	name := strings.TrimSpace(params.Name)
+	if name == "" {
+		respondWithError(w, http.StatusBadRequest, "Tag name can not be empty")
+		return
+	}
+
+	if !cfg.checkBranchPermission(userId, branchId, "write", r.Context()) {
+		respondWithError(w, http.StatusForbidden, "Insufficient write permissions")
+		return
+	}
+
+	branch, err := cfg.db.GetBranch(r.Context(), branchId)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Branch not found")
+		return
+	}
+
+	_, err = cfg.db.GetTagByName(r.Context(), database.GetTagByNameParams{
+		TableID: branch.TableID,
+		Name:    name,
+	})
+	if err == nil {
+		respondWithError(w, http.StatusConflict, "A tag with this name already exists")
+		return
+	}
+	if err != sql.ErrNoRows {
+		msg := fmt.Sprintf("Could not get tag: %s", err)
+		respondWithError(w, http.StatusInternalServerError, msg)
+		return
+	}
+
+	// Sheets are restored in the order they were created in.
+	snapshot, err := getBranchSnapshot(r.Context(), cfg.db, branchId)
+	if err != nil {
+		msg := fmt.Sprintf("Could not get branch data: %s", err)
+		respondWithError(w, http.StatusInternalServerError, msg)
+		return
+	}
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		msg := fmt.Sprintf("Could not encode branch snapshot: %s", err)
+		respondWithError(w, http.StatusInternalServerError, msg)
+		return
+	}
+
+	// A branch with invalid cells can still be tagged, the tag just can not
+	// be exported.
+	exportData := sql.NullString{}
+	shape, err := cfg.resolveExportShape(branchId, "", r.Context())
+	if err != nil {
+		msg := fmt.Sprintf("Could not get export shape: %s", err)
+		respondWithError(w, http.StatusInternalServerError, msg)
+		return
+	}
+	sheets, err := cfg.getBranchJson(branchId, shape, r.Context())
+	var invalidCells *InvalidCellsError
+	if err != nil && !errors.As(err, &invalidCells) {
+		msg := fmt.Sprintf("Could not get branch json: %s", err)
+		respondWithError(w, http.StatusInternalServerError, msg)
+		return
+	}
+	if err == nil {
+		sheetsJson, err := json.Marshal(sheets)
+		if err != nil {
+			msg := fmt.Sprintf("Could not encode branch json: %s", err)
+			respondWithError(w, http.StatusInternalServerError, msg)
+			return
+		}
+		exportData = sql.NullString{String: string(sheetsJson), Valid: true}
+	}
+
+	tag, err := cfg.db.CreateTag(r.Context(), database.CreateTagParams{
+		TableID:    branch.TableID,
+		BranchID:   uuid.NullUUID{UUID: branch.ID, Valid: true},
+		BranchName: branch.Name,
+		Name:       name,
+		Message:    params.Message,
+		AuthorID:   uuid.NullUUID{UUID: userId, Valid: true},
+		Data:       string(data),
+		ExportData: exportData,
+	})
+	if err != nil {
+		msg := fmt.Sprintf("Could not create tag: %s", err)
+		respondWithError(w, http.StatusInternalServerError, msg)
+		return
+	}
+
+	user, err := cfg.db.GetUser(r.Context(), userId)
+	if err != nil {
+		msg := fmt.Sprintf("User with id not found: %s", err)
+		respondWithError(w, http.StatusInternalServerError, msg)
+		return
+	}
+
+	response := Tag{
+		ID:          tag.ID,
+		BranchID:    tag.BranchID,
+		BranchName:  tag.BranchName,
+		Name:        tag.Name,
+		Message:     tag.Message,
+		AuthorEmail: user.Email,
+		CreatedAt:   tag.CreatedAt,
+	}
+	respondWithJSON(w, http.StatusCreated, response)
+}
+
+func decodeTagSnapshot(tag database.Tag) (BranchSnapshot, error) {
+	var snapshot BranchSnapshot
+	err := json.Unmarshal([]byte(tag.Data), &snapshot)
+	if err != nil {
+		return BranchSnapshot{}, fmt.Errorf("Could not decode tag snapshot: %s", err)
+	}
+	return snapshot, nil
+}
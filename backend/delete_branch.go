@@ -31,7 +31,7 @@ func (cfg *apiConfig) deleteBranchHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	rowsAffected, err := cfg.db.DeleteBranchWithPermissionCheck(r.Context(), database.DeleteBranchWithPermissionCheckParams{
+	rowsAffected, err := cfg.db.TrashBranchWithPermissionCheck(r.Context(), database.TrashBranchWithPermissionCheckParams{
 		ID:     branchId,
 		UserID: userId,
 	})
@@ -45,5 +45,6 @@ func (cfg *apiConfig) deleteBranchHandler(w http.ResponseWriter, r *http.Request
 		respondWithError(w, http.StatusForbidden, "Branch not found or insufficient permissions")
 		return
 	}
+	cfg.invalidateBranchJson(branchId)
 	respondWithJSON(w, http.StatusNoContent, "")
 }
@@ -1,9 +1,11 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"slices"
 
 	"github.com/Dass33/administratum/backend/internal/database"
 	"github.com/google/uuid"
@@ -32,15 +34,102 @@ func (cfg *apiConfig) deleteColumnHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	deleteColumnParams := database.DeleteColumnParams{
-		Name:    params.Col.Name,
-		SheetID: sheet_id,
+	sheet, err := cfg.db.GetSheet(r.Context(), sheet_id)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Sheet not found")
+		return
+	}
+
+	tx, err := cfg.rawDB.BeginTx(r.Context(), nil)
+	if err != nil {
+		msg := fmt.Sprintf("Could not begin transaction: %s", err)
+		respondWithError(w, http.StatusInternalServerError, msg)
+		return
+	}
+	defer tx.Rollback()
+	txQueries := cfg.db.WithTx(tx)
+
+	deleted, err := deletedColumnValue(r.Context(), txQueries, sheet, params.Col.Name)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if deleted != nil {
+		trashColumnParams := database.TrashColumnParams{
+			DeletedBy: uuid.NullUUID{UUID: id, Valid: true},
+			ID:        deleted.columnId.UUID,
+		}
+		err = txQueries.TrashColumn(r.Context(), trashColumnParams)
+		if err != nil {
+			msg := fmt.Sprintf("Column could not be deleted: %s", err)
+			respondWithError(w, http.StatusInternalServerError, msg)
+			return
+		}
+
+		err = recordUndoableHistory(r.Context(), txQueries, id, *deleted)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
 	}
-	err = cfg.db.DeleteColumn(r.Context(), deleteColumnParams)
+
+	err = tx.Commit()
 	if err != nil {
-		msg := fmt.Sprintf("Column could not be deleted: %s", err)
+		msg := fmt.Sprintf("Could not commit column deletion: %s", err)
 		respondWithError(w, http.StatusInternalServerError, msg)
 		return
 	}
+	cfg.invalidateSheetJson(sheet_id, r.Context())
 	respondWithJSON(w, http.StatusNoContent, "")
 }
+
+// deletedColumnValue builds the history entry of deleting the named column
+// with its cells, nil when the sheet has no such column.
+func deletedColumnValue(ctx context.Context, q *database.Queries, sheet database.Sheet, name string) (*historyEntry, error) {
+	columns, err := q.GetColumnsFromSheet(ctx, sheet.ID)
+	if err != nil {
+		return nil, fmt.Errorf("Could not get columns: %s", err)
+	}
+	colIdx := slices.IndexFunc(columns, func(col database.Column) bool { return col.Name == name })
+	if colIdx == -1 {
+		return nil, nil
+	}
+	column := columns[colIdx]
+
+	err = q.EnsureSheetRows(ctx, sheet.BranchID)
+	if err != nil {
+		return nil, fmt.Errorf("Could not create row ids: %s", err)
+	}
+	rows, err := q.GetSheetRows(ctx, sheet.ID)
+	if err != nil {
+		return nil, fmt.Errorf("Could not get rows: %s", err)
+	}
+	rowIds := make(map[int64]uuid.UUID, len(rows))
+	for _, row := range rows {
+		rowIds[row.Idx] = row.ID
+	}
+
+	data, err := q.GetColumnsData(ctx, column.ID)
+	if err != nil {
+		return nil, fmt.Errorf("Could not get column data: %s", err)
+	}
+	value := historyColumnValue(column)
+	for _, cell := range data {
+		value.Cells = append(value.Cells, HistoryColumnCell{
+			CellID:           cell.ID,
+			RowID:            rowIds[cell.Idx],
+			Idx:              cell.Idx,
+			HistoryCellValue: historyCellValue(cell.Value, cell.Type),
+		})
+	}
+
+	return &historyEntry{
+		branchId: sheet.BranchID,
+		sheetId:  sheet.ID,
+		columnId: uuid.NullUUID{UUID: column.ID, Valid: true},
+		entity:   HistoryEntityColumn,
+		action:   HistoryActionDelete,
+		oldValue: value,
+	}, nil
+}
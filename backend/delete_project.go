@@ -31,7 +31,14 @@ func (cfg *apiConfig) deleteProjectHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	rowsAffected, err := cfg.db.DeleteTableWithPermissionCheck(r.Context(), database.DeleteTableWithPermissionCheckParams{
+	branches, err := cfg.db.GetBranchesFromTable(r.Context(), projectId)
+	if err != nil {
+		msg := fmt.Sprintf("Could not get branches from project: %s", err)
+		respondWithError(w, http.StatusInternalServerError, msg)
+		return
+	}
+
+	rowsAffected, err := cfg.db.TrashTableWithPermissionCheck(r.Context(), database.TrashTableWithPermissionCheckParams{
 		ID:     projectId,
 		UserID: userId,
 	})
@@ -45,5 +52,8 @@ func (cfg *apiConfig) deleteProjectHandler(w http.ResponseWriter, r *http.Reques
 		respondWithError(w, http.StatusForbidden, "Project not found or insufficient permissions")
 		return
 	}
+	for _, branch := range branches {
+		cfg.invalidateBranchJson(branch.ID)
+	}
 	respondWithJSON(w, http.StatusNoContent, "")
 }
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -37,15 +38,88 @@ func (cfg *apiConfig) deleteRowHandler(w http.ResponseWriter, r *http.Request, i
 		return
 	}
 
+	sheet, err := cfg.db.GetSheet(r.Context(), sheet_id)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Sheet not found")
+		return
+	}
+
+	tx, err := cfg.rawDB.BeginTx(r.Context(), nil)
+	if err != nil {
+		msg := fmt.Sprintf("Could not begin transaction: %s", err)
+		respondWithError(w, http.StatusInternalServerError, msg)
+		return
+	}
+	defer tx.Rollback()
+	txQueries := cfg.db.WithTx(tx)
+
+	deleted, err := deletedRowValue(r.Context(), txQueries, sheet, params.RowIdx)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
 	deleteRowParams := database.DeleteRowParams{
 		SheetID: sheet_id,
 		Idx:     params.RowIdx,
 	}
-	err = cfg.db.DeleteRow(r.Context(), deleteRowParams)
+	err = txQueries.DeleteRow(r.Context(), deleteRowParams)
 	if err != nil {
 		msg := fmt.Sprintf("Row could not be deleted: %s", err)
 		respondWithError(w, http.StatusInternalServerError, msg)
 		return
 	}
+
+	err = recordUndoableHistory(r.Context(), txQueries, id, deleted)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		msg := fmt.Sprintf("Could not commit row deletion: %s", err)
+		respondWithError(w, http.StatusInternalServerError, msg)
+		return
+	}
+	cfg.invalidateSheetJson(sheet_id, r.Context())
 	respondWithJSON(w, http.StatusNoContent, "")
 }
+
+// deletedRowValue builds the history entry of deleting the row at idx with
+// its cells.
+func deletedRowValue(ctx context.Context, q *database.Queries, sheet database.Sheet, idx int64) (historyEntry, error) {
+	row, err := sheetRow(ctx, q, sheet.ID, idx)
+	if err != nil {
+		return historyEntry{}, err
+	}
+	cells, err := q.GetRowCells(ctx, database.GetRowCellsParams{
+		SheetID: sheet.ID,
+		Idx:     idx,
+	})
+	if err != nil {
+		return historyEntry{}, fmt.Errorf("Could not get row cells: %s", err)
+	}
+
+	value := HistoryRowValue{
+		Idx:      idx,
+		OriginID: originKey(row.OriginRowID, row.ID),
+		Cells:    make([]HistoryRowCell, 0, len(cells)),
+	}
+	for _, cell := range cells {
+		value.Cells = append(value.Cells, HistoryRowCell{
+			CellID:           cell.ID,
+			ColumnID:         cell.ColumnID,
+			HistoryCellValue: historyCellValue(cell.Value, cell.Type),
+		})
+	}
+
+	return historyEntry{
+		branchId: sheet.BranchID,
+		sheetId:  sheet.ID,
+		rowId:    uuid.NullUUID{UUID: row.ID, Valid: true},
+		entity:   HistoryEntityRow,
+		action:   HistoryActionDelete,
+		oldValue: value,
+	}, nil
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"net/http"
 
+	"github.com/Dass33/administratum/backend/internal/database"
 	"github.com/google/uuid"
 )
 
@@ -35,11 +36,49 @@ func (cfg *apiConfig) deleteSheetHandler(w http.ResponseWriter, r *http.Request,
 		return
 	}
 
-	err = cfg.db.DeleteSheet(r.Context(), sheetId)
+	sheet, err := cfg.db.GetSheet(r.Context(), sheetId)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Sheet not found")
+		return
+	}
+
+	tx, err := cfg.rawDB.BeginTx(r.Context(), nil)
+	if err != nil {
+		msg := fmt.Sprintf("Could not begin transaction: %s", err)
+		respondWithError(w, http.StatusInternalServerError, msg)
+		return
+	}
+	defer tx.Rollback()
+	txQueries := cfg.db.WithTx(tx)
+
+	err = txQueries.TrashSheet(r.Context(), database.TrashSheetParams{
+		DeletedBy: uuid.NullUUID{UUID: userId, Valid: true},
+		ID:        sheetId,
+	})
 	if err != nil {
 		msg := fmt.Sprintf("Sheet could not be deleted: %s", err)
 		respondWithError(w, http.StatusInternalServerError, msg)
 		return
 	}
+
+	err = recordHistory(r.Context(), txQueries, userId, historyEntry{
+		branchId: sheet.BranchID,
+		sheetId:  sheet.ID,
+		entity:   HistoryEntitySheet,
+		action:   HistoryActionDelete,
+		oldValue: HistorySheetValue{Name: sheet.Name, Type: sheet.Type},
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		msg := fmt.Sprintf("Could not commit sheet deletion: %s", err)
+		respondWithError(w, http.StatusInternalServerError, msg)
+		return
+	}
+	cfg.invalidateBranchJson(sheet.BranchID)
 	respondWithJSON(w, http.StatusNoContent, "")
 }
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+type deleteTagParams struct {
+	TagId string `json:"tag_id"`
+}
+
+func (cfg *apiConfig) deleteTagHandler(w http.ResponseWriter, r *http.Request, userId uuid.UUID) {
+	decoder := json.NewDecoder(r.Body)
+	params := deleteTagParams{}
+	err := decoder.Decode(&params)
+	if err != nil {
+		msg := fmt.Sprintf("Error decoding parameters: %s", err)
+		respondWithError(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	tagId, err := uuid.Parse(params.TagId)
+	if err != nil {
+		msg := fmt.Sprintf("Could not parse the tag id: %s", err)
+		respondWithError(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	tag, err := cfg.db.GetTag(r.Context(), tagId)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Tag not found")
+		return
+	}
+
+	if !cfg.checkTablePermission(userId, tag.TableID, "write", r.Context()) {
+		respondWithError(w, http.StatusForbidden, "Insufficient write permissions")
+		return
+	}
+
+	err = cfg.db.DeleteTag(r.Context(), tag.ID)
+	if err != nil {
+		msg := fmt.Sprintf("Could not delete tag: %s", err)
+		respondWithError(w, http.StatusInternalServerError, msg)
+		return
+	}
+	respondWithJSON(w, http.StatusNoContent, "")
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+const (
+	SheetFormatCsv = "csv"
+	SheetFormatTsv = "tsv"
+)
+
+// mapSheetTypeHeader is the extra column carrying the value cell types of a map sheet.
+const mapSheetTypeHeader = "type"
+
+func sheetFormatDelimiter(format string) (rune, bool) {
+	switch format {
+	case "", SheetFormatCsv:
+		return ',', true
+	case SheetFormatTsv:
+		return '\t', true
+	}
+	return 0, false
+}
+
+func (cfg *apiConfig) exportSheetHandler(w http.ResponseWriter, r *http.Request, userId uuid.UUID) {
+	sheetIdStr := chi.URLParam(r, "sheet_id")
+	sheetId, err := uuid.Parse(sheetIdStr)
+	if err != nil {
+		msg := fmt.Sprintf("Could not parse the sheet id from url: %s", err)
+		respondWithError(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	if !cfg.checkSheetPermission(userId, sheetId, "read", r.Context()) {
+		respondWithError(w, http.StatusForbidden, "Insufficient read permissions")
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	delimiter, ok := sheetFormatDelimiter(format)
+	if !ok {
+		respondWithError(w, http.StatusBadRequest, "Invalid sheet format")
+		return
+	}
+	if format == "" {
+		format = SheetFormatCsv
+	}
+
+	sheet, err := cfg.db.GetSheet(r.Context(), sheetId)
+	if err != nil {
+		msg := fmt.Sprintf("Could not get sheet: %s", err)
+		respondWithError(w, http.StatusInternalServerError, msg)
+		return
+	}
+
+	columns, err := cfg.GetColumns(sheetId, r.Context())
+	if err != nil {
+		msg := fmt.Sprintf("Could not get columns: %s", err)
+		respondWithError(w, http.StatusInternalServerError, msg)
+		return
+	}
+
+	isMap := sheet.Type == SheetTypeMap
+	header := make([]string, 0, len(columns)+1)
+	for i := range columns {
+		header = append(header, columns[i].Name)
+	}
+	if isMap {
+		header = append(header, mapSheetTypeHeader)
+	}
+
+	records := [][]string{header}
+	for i := range sheetRowCount(columns) {
+		record := make([]string, len(header))
+		cellType := ""
+		for e := range columns {
+			cell, ok := getDataAtColIdx(columns[e].Data, i)
+			if !ok {
+				continue
+			}
+			record[e] = cell.Value.String
+			if cell.Type.Valid {
+				cellType = cell.Type.String
+			}
+		}
+		if isMap {
+			record[len(columns)] = cellType
+		}
+		records = append(records, record)
+	}
+
+	contentType := "text/csv"
+	if format == SheetFormatTsv {
+		contentType = "text/tab-separated-values"
+	}
+	w.Header().Set("Content-Type", contentType+"; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", sheet.Name+"."+format))
+	w.WriteHeader(http.StatusOK)
+
+	writer := csv.NewWriter(w)
+	writer.Comma = delimiter
+	writer.WriteAll(records)
+}
+
+// sheetRowCount returns one past the highest row index, the column data
+// may have gaps so the longest column is not enough.
+func sheetRowCount(columns []Column) int64 {
+	var rowCount int64 = 0
+	for i := range columns {
+		data := columns[i].Data
+		if len(data) > 0 && data[len(data)-1].Idx+1 > rowCount {
+			rowCount = data[len(data)-1].Idx + 1
+		}
+	}
+	return rowCount
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Dass33/administratum/backend/internal/xlsx"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+const xlsxContentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+
+// workbookDataRow is the first row of data in a worksheet, it follows the
+// header row with the column names and the hidden row with their types.
+const workbookDataRow = 2
+
+// requiredTypeSuffix marks the types of required columns in the types row.
+const requiredTypeSuffix = "*"
+
+// workbookSheetSuffixes mark the sheet type in worksheet names, worksheets
+// without a suffix are list sheets.
+var workbookSheetSuffixes = map[string]string{
+	SheetTypeMap:   " (map)",
+	SheetTypeEnums: " (enums)",
+}
+
+func (cfg *apiConfig) exportWorkbookHandler(w http.ResponseWriter, r *http.Request, userId uuid.UUID) {
+	branchIdStr := chi.URLParam(r, "branch_id")
+	branchId, err := uuid.Parse(branchIdStr)
+	if err != nil {
+		msg := fmt.Sprintf("Could not parse the branch id from url: %s", err)
+		respondWithError(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	if !cfg.checkBranchPermission(userId, branchId, "read", r.Context()) {
+		respondWithError(w, http.StatusForbidden, "Insufficient read permissions")
+		return
+	}
+
+	branch, err := cfg.db.GetBranch(r.Context(), branchId)
+	if err != nil {
+		msg := fmt.Sprintf("Could not get branch: %s", err)
+		respondWithError(w, http.StatusInternalServerError, msg)
+		return
+	}
+
+	sheetsDb, err := cfg.db.GetSheetsFromBranch(r.Context(), branchId)
+	if err != nil {
+		msg := fmt.Sprintf("Could not get sheets from branch id: %s", err)
+		respondWithError(w, http.StatusInternalServerError, msg)
+		return
+	}
+	if len(sheetsDb) == 0 {
+		respondWithError(w, http.StatusBadRequest, "Branch does not have any sheets")
+		return
+	}
+
+	sheetColumns := make([][]Column, len(sheetsDb))
+	sheetNames := make([]string, len(sheetsDb))
+	usedNames := make(map[string]bool)
+	enumRanges := make(map[string]string)
+	for i, sheet := range sheetsDb {
+		sheetColumns[i], err = cfg.GetColumns(sheet.ID, r.Context())
+		if err != nil {
+			msg := fmt.Sprintf("Could not get columns: %s", err)
+			respondWithError(w, http.StatusInternalServerError, msg)
+			return
+		}
+		sheetNames[i] = workbookSheetName(sheet.Name, sheet.Type, usedNames)
+
+		rowCount := sheetRowCount(sheetColumns[i])
+		if sheet.Type == SheetTypeEnums && rowCount > 0 {
+			enumRanges[sheet.Name] = xlsx.RangeRef(sheetNames[i], 0, workbookDataRow, workbookDataRow+int(rowCount)-1)
+		}
+	}
+
+	sheets := make([]xlsx.Sheet, len(sheetsDb))
+	for i, sheet := range sheetsDb {
+		sheets[i] = buildWorksheet(sheetNames[i], sheet.Type == SheetTypeMap, sheetColumns[i], enumRanges)
+	}
+
+	var buf bytes.Buffer
+	err = xlsx.Write(&buf, sheets)
+	if err != nil {
+		msg := fmt.Sprintf("Could not write workbook: %s", err)
+		respondWithError(w, http.StatusInternalServerError, msg)
+		return
+	}
+
+	w.Header().Set("Content-Type", xlsxContentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", branch.Name+".xlsx"))
+	w.WriteHeader(http.StatusOK)
+	w.Write(buf.Bytes())
+}
+
+// buildWorksheet lays out a sheet as the header row, the hidden types row and
+// its data. Enum typed cells get a list validation over the enum worksheet.
+func buildWorksheet(name string, isMap bool, columns []Column, enumRanges map[string]string) xlsx.Sheet {
+	header := make([]xlsx.Cell, 0, len(columns)+1)
+	types := make([]xlsx.Cell, 0, len(columns)+1)
+	for i := range columns {
+		header = append(header, xlsx.String(columns[i].Name))
+		colType := columns[i].Type
+		if columns[i].Required {
+			colType += requiredTypeSuffix
+		}
+		types = append(types, xlsx.String(colType))
+	}
+	if isMap {
+		header = append(header, xlsx.String(mapSheetTypeHeader))
+		types = append(types, xlsx.String("text"))
+	}
+
+	sheet := xlsx.Sheet{
+		Name:       name,
+		Rows:       [][]xlsx.Cell{header, types},
+		HiddenRows: []int{1},
+		HeaderRows: workbookDataRow,
+	}
+
+	if !isMap {
+		for e := range columns {
+			if enumRange, ok := enumRanges[columns[e].Type]; ok {
+				sheet.Validations = append(sheet.Validations, xlsx.Validation{
+					Ref:     xlsx.CellRef(e, workbookDataRow) + ":" + xlsx.CellRef(e, xlsx.MaxRows-1),
+					Formula: enumRange,
+				})
+			}
+		}
+	}
+
+	rowCount := sheetRowCount(columns)
+	for i := range rowCount {
+		row := make([]xlsx.Cell, len(header))
+		for e := range columns {
+			cell, ok := getDataAtColIdx(columns[e].Data, i)
+			if !ok {
+				continue
+			}
+
+			valueType := columns[e].Type
+			if isMap && cell.Type.Valid {
+				valueType = cell.Type.String
+				row[len(columns)] = xlsx.String(valueType)
+
+				if enumRange, ok := enumRanges[valueType]; ok {
+					sheet.Validations = append(sheet.Validations, xlsx.Validation{
+						Ref:     xlsx.CellRef(e, workbookDataRow+int(i)),
+						Formula: enumRange,
+					})
+				}
+			}
+			row[e] = workbookCell(cell.Value.String, valueType)
+		}
+		sheet.Rows = append(sheet.Rows, row)
+	}
+	return sheet
+}
+
+// workbookCell stores numbers and bools as native spreadsheet values when
+// they can be read back unchanged, everything else as text.
+func workbookCell(value string, valueType string) xlsx.Cell {
+	switch strings.ToLower(valueType) {
+	case "number", "int", "float":
+		if _, err := strconv.ParseFloat(value, 64); err == nil {
+			return xlsx.Number(value)
+		}
+	case "bool", "boolean":
+		if strings.EqualFold(value, "true") || strings.EqualFold(value, "false") {
+			return xlsx.Bool(strings.EqualFold(value, "true"))
+		}
+	}
+	return xlsx.String(value)
+}
+
+// workbookSheetName returns a unique worksheet name carrying the sheet type
+// as a suffix, long names are shortened to fit the worksheet name limit.
+func workbookSheetName(name string, sheetType string, used map[string]bool) string {
+	suffix := workbookSheetSuffixes[sheetType]
+	base := []rune(xlsx.SanitizeSheetName(name))
+	maxBase := xlsx.MaxSheetName - len([]rune(suffix))
+	if len(base) > maxBase {
+		base = base[:maxBase]
+	}
+
+	candidate := string(base) + suffix
+	for i := 2; used[strings.ToLower(candidate)]; i++ {
+		counter := "~" + strconv.Itoa(i)
+		if len(base) > maxBase-len(counter) {
+			base = base[:maxBase-len(counter)]
+		}
+		candidate = string(base) + counter + suffix
+	}
+	used[strings.ToLower(candidate)] = true
+	return candidate
+}
+
+func parseWorkbookSheetName(name string) (string, string) {
+	for sheetType, suffix := range workbookSheetSuffixes {
+		if trimmed, ok := strings.CutSuffix(name, suffix); ok && trimmed != "" {
+			return trimmed, sheetType
+		}
+	}
+	return name, SheetTypeList
+}
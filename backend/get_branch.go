@@ -17,10 +17,11 @@ type Enum struct {
 }
 
 type Branch struct {
-	ID          uuid.UUID `json:"id"`
-	Name        string    `json:"name"`
-	IsProtected bool      `json:"is_protected"`
-	Enums       []Enum    `json:"enums"`
+	ID                uuid.UUID `json:"id"`
+	Name              string    `json:"name"`
+	IsProtected       bool      `json:"is_protected"`
+	RequiredApprovals int64     `json:"required_approvals"`
+	Enums             []Enum    `json:"enums"`
 }
 
 func (cfg *apiConfig) getBranchHandler(w http.ResponseWriter, r *http.Request, userId uuid.UUID) {
@@ -57,10 +58,11 @@ func (cfg *apiConfig) GetBranch(user_id uuid.UUID, optional_branch_id uuid.NullU
 	}
 
 	data := Branch{
-		ID:          branch_id,
-		Name:        branch.Name,
-		IsProtected: branch.IsProtected,
-		Enums:       enums,
+		ID:                branch_id,
+		Name:              branch.Name,
+		IsProtected:       branch.IsProtected,
+		RequiredApprovals: branch.RequiredApprovals,
+		Enums:             enums,
 	}
 	return data, nil
 }
@@ -74,7 +76,7 @@ func (cfg *apiConfig) getEnumsForBranch(branchID uuid.UUID, ctx context.Context)
 	var enums []Enum
 
 	for _, sheet := range sheets {
-		if sheet.Type == "enums" {
+		if sheet.Type == SheetTypeEnums {
 			columns, err := cfg.db.GetColumnsFromSheet(ctx, sheet.ID)
 			if err != nil {
 				continue
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// getCellHistoryHandler lists the edits of a cell, newest first. The history
+// stays readable after the row of the cell is deleted.
+func (cfg *apiConfig) getCellHistoryHandler(w http.ResponseWriter, r *http.Request, userId uuid.UUID) {
+	cellIdStr := chi.URLParam(r, "cell_id")
+	cellId, err := uuid.Parse(cellIdStr)
+	if err != nil {
+		msg := fmt.Sprintf("Could not parse the cell id from url: %s", err)
+		respondWithError(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	historyDb, err := cfg.db.GetCellHistory(r.Context(), uuid.NullUUID{UUID: cellId, Valid: true})
+	if err != nil {
+		msg := fmt.Sprintf("Could not get cell history: %s", err)
+		respondWithError(w, http.StatusInternalServerError, msg)
+		return
+	}
+
+	if len(historyDb) == 0 {
+		column, err := cfg.db.GetColumnDataColumn(r.Context(), cellId)
+		if err != nil {
+			respondWithError(w, http.StatusNotFound, "Cell not found")
+			return
+		}
+		if !cfg.checkBranchPermission(userId, column.BranchID, "read", r.Context()) {
+			respondWithError(w, http.StatusForbidden, "Insufficient read permissions")
+			return
+		}
+		respondWithJSON(w, http.StatusOK, []HistoryEntry{})
+		return
+	}
+
+	if !cfg.checkBranchPermission(userId, historyDb[0].BranchID, "read", r.Context()) {
+		respondWithError(w, http.StatusForbidden, "Insufficient read permissions")
+		return
+	}
+
+	history := make([]HistoryEntry, 0, len(historyDb))
+	for i := range historyDb {
+		history = append(history, historyEntryResponse(historyDb[i]))
+	}
+	respondWithJSON(w, http.StatusOK, history)
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/Dass33/administratum/backend/internal/codegen"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+func (cfg *apiConfig) getCodegenHandler(w http.ResponseWriter, r *http.Request, userId uuid.UUID) {
+	branchIdStr := chi.URLParam(r, "branch_id")
+	branchId, err := uuid.Parse(branchIdStr)
+	if err != nil {
+		msg := fmt.Sprintf("Could not parse the branch id from url: %s", err)
+		respondWithError(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	if !cfg.checkBranchPermission(userId, branchId, "read", r.Context()) {
+		respondWithError(w, http.StatusForbidden, "Insufficient read permissions")
+		return
+	}
+
+	lang := r.URL.Query().Get("lang")
+	if lang == "" {
+		lang = codegen.LangTypeScript
+	}
+
+	shapeParam := r.URL.Query().Get("shape")
+	if shapeParam != "" && !isValidExportShape(shapeParam) {
+		respondWithError(w, http.StatusBadRequest, "Invalid export shape")
+		return
+	}
+
+	shape, err := cfg.resolveExportShape(branchId, shapeParam, r.Context())
+	if err != nil {
+		msg := fmt.Sprintf("Could not get export shape: %s", err)
+		respondWithError(w, http.StatusInternalServerError, msg)
+		return
+	}
+
+	model, err := codegen.LoadModel(r.Context(), cfg.db, branchId, shape)
+	if err != nil {
+		msg := fmt.Sprintf("Could not read branch for code generation: %s", err)
+		respondWithError(w, http.StatusInternalServerError, msg)
+		return
+	}
+
+	opts := codegen.Options{Namespace: r.URL.Query().Get("namespace")}
+	code, err := codegen.Generate(lang, model, opts)
+	if err != nil {
+		msg := fmt.Sprintf("Could not generate code: %s", err)
+		respondWithError(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(code))
+}
@@ -21,6 +21,7 @@ type Column struct {
 	Name     string       `json:"name"`
 	Type     string       `json:"type"`
 	Required bool         `json:"required"`
+	IsKey    bool         `json:"is_key"`
 	Data     []ColumnData `json:"data"`
 }
 
@@ -42,6 +43,7 @@ func (cfg *apiConfig) GetColumnsWithTx(txQueries *database.Queries, sheet_id uui
 				Name:     row.ColumnName,
 				Type:     row.ColumnType,
 				Required: row.ColumnRequired,
+				IsKey:    row.ColumnIsKey,
 				Data:     make([]ColumnData, 0),
 			}
 			columnOrder = append(columnOrder, columnID)
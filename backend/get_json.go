@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -9,12 +10,23 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/Dass33/administratum/backend/internal/database"
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
 )
 
+const ExportShapeArray = "array"
+const ExportShapeObject = "object"
+
+type SheetJson struct {
+	Name      string `json:"name"`
+	Type      string `json:"type"`
+	KeyColumn string `json:"key_column,omitempty"`
+	Data      any    `json:"data"`
+}
+
 func (cfg *apiConfig) getJsonHandler(w http.ResponseWriter, r *http.Request) {
 	branchIdStr := chi.URLParam(r, "branch_id")
 	branchId, err := uuid.Parse(branchIdStr)
@@ -24,36 +36,340 @@ func (cfg *apiConfig) getJsonHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	sheetsDb, err := cfg.db.GetSheetsFromBranch(r.Context(), branchId)
+	var version int64
+	cacheKey := "latest"
+	versionStr := r.URL.Query().Get("version")
+	if versionStr != "" {
+		version, err = strconv.ParseInt(versionStr, 10, 64)
+		if err != nil {
+			msg := fmt.Sprintf("Could not parse the release version: %s", err)
+			respondWithError(w, http.StatusBadRequest, msg)
+			return
+		}
+		cacheKey = "version:" + versionStr
+	}
+
+	shapeParam := r.URL.Query().Get("shape")
+	if shapeParam != "" && !isValidExportShape(shapeParam) {
+		respondWithError(w, http.StatusBadRequest, "Invalid export shape")
+		return
+	}
+	cacheKey += "|" + shapeParam
+
+	tagName := r.URL.Query().Get("tag")
+	if tagName != "" {
+		if versionStr != "" {
+			respondWithError(w, http.StatusBadRequest, "Version and tag can not be combined")
+			return
+		}
+		cfg.serveTagJson(w, r, branchId, tagName, shapeParam)
+		return
+	}
+
+	if entry, ok := cfg.jsonCache.get(branchId, cacheKey); ok {
+		serveJsonEntry(w, r, entry)
+		return
+	}
+	generation := cfg.jsonCache.generation(branchId)
+
+	shape, err := cfg.resolveExportShape(branchId, shapeParam, r.Context())
+	if err != nil {
+		msg := fmt.Sprintf("Could not get export shape: %s", err)
+		respondWithError(w, http.StatusInternalServerError, msg)
+		return
+	}
+
+	var release database.Release
+	if versionStr != "" {
+		release, err = cfg.db.GetReleaseByVersion(r.Context(), database.GetReleaseByVersionParams{
+			BranchID: branchId,
+			Version:  version,
+		})
+		if err == sql.ErrNoRows {
+			respondWithError(w, http.StatusNotFound, "Release with given version not found")
+			return
+		}
+	} else {
+		release, err = cfg.db.GetLatestRelease(r.Context(), branchId)
+		if err == sql.ErrNoRows {
+			// branches that were never published keep serving the live data
+			entry, err := cfg.buildDraftJsonEntry(branchId, shape, r.Context())
+			if err != nil {
+				respondWithBranchJsonError(w, err)
+				return
+			}
+			cfg.jsonCache.set(branchId, cacheKey, generation, entry)
+			serveJsonEntry(w, r, entry)
+			return
+		}
+	}
+	if err != nil {
+		msg := fmt.Sprintf("Could not get release: %s", err)
+		respondWithError(w, http.StatusInternalServerError, msg)
+		return
+	}
+
+	sheets := []SheetJson{}
+	err = json.Unmarshal([]byte(release.Data), &sheets)
 	if err != nil {
-		msg := fmt.Sprintf("Could not get sheets from branch id: %s", err)
+		msg := fmt.Sprintf("Could not decode release data: %s", err)
 		respondWithError(w, http.StatusInternalServerError, msg)
 		return
 	}
 
-	data := make([]any, 0, len(sheetsDb))
+	body, err := json.Marshal(renderSheetsJson(sheets, shape))
+	if err != nil {
+		msg := fmt.Sprintf("Could not encode release data: %s", err)
+		respondWithError(w, http.StatusInternalServerError, msg)
+		return
+	}
+
+	entry := newJsonCacheEntry(body, release.CreatedAt, release.Version)
+	entry.immutable = versionStr != ""
+	cfg.jsonCache.set(branchId, cacheKey, generation, entry)
+	serveJsonEntry(w, r, entry)
+}
+
+// serveTagJson serves the export stored with a tag of the branch's table.
+// Tags are looked up by name, which can be reused once a tag is deleted, so
+// the entries are not cached.
+func (cfg *apiConfig) serveTagJson(w http.ResponseWriter, r *http.Request, branchId uuid.UUID, tagName, shapeParam string) {
+	branch, err := cfg.db.GetBranch(r.Context(), branchId)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Branch not found")
+		return
+	}
+
+	tag, err := cfg.db.GetTagByName(r.Context(), database.GetTagByNameParams{
+		TableID: branch.TableID,
+		Name:    tagName,
+	})
+	if err == sql.ErrNoRows {
+		respondWithError(w, http.StatusNotFound, "Tag with given name not found")
+		return
+	}
+	if err != nil {
+		msg := fmt.Sprintf("Could not get tag: %s", err)
+		respondWithError(w, http.StatusInternalServerError, msg)
+		return
+	}
+	if !tag.ExportData.Valid {
+		respondWithError(w, http.StatusUnprocessableEntity, "Tag was created from a branch with invalid cells")
+		return
+	}
+
+	shape, err := cfg.resolveExportShape(branchId, shapeParam, r.Context())
+	if err != nil {
+		msg := fmt.Sprintf("Could not get export shape: %s", err)
+		respondWithError(w, http.StatusInternalServerError, msg)
+		return
+	}
+
+	sheets := []SheetJson{}
+	err = json.Unmarshal([]byte(tag.ExportData.String), &sheets)
+	if err != nil {
+		msg := fmt.Sprintf("Could not decode tag data: %s", err)
+		respondWithError(w, http.StatusInternalServerError, msg)
+		return
+	}
+
+	body, err := json.Marshal(renderSheetsJson(sheets, shape))
+	if err != nil {
+		msg := fmt.Sprintf("Could not encode tag data: %s", err)
+		respondWithError(w, http.StatusInternalServerError, msg)
+		return
+	}
+	serveJsonEntry(w, r, newJsonCacheEntry(body, tag.CreatedAt, 0))
+}
+
+func (cfg *apiConfig) getDraftJsonHandler(w http.ResponseWriter, r *http.Request) {
+	branchIdStr := chi.URLParam(r, "branch_id")
+	branchId, err := uuid.Parse(branchIdStr)
+	if err != nil {
+		msg := fmt.Sprintf("Could not parse the sheet id from url: %s", err)
+		respondWithError(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	shapeParam := r.URL.Query().Get("shape")
+	if shapeParam != "" && !isValidExportShape(shapeParam) {
+		respondWithError(w, http.StatusBadRequest, "Invalid export shape")
+		return
+	}
+	cacheKey := "draft|" + shapeParam
+
+	if entry, ok := cfg.jsonCache.get(branchId, cacheKey); ok {
+		serveJsonEntry(w, r, entry)
+		return
+	}
+	generation := cfg.jsonCache.generation(branchId)
+
+	shape, err := cfg.resolveExportShape(branchId, shapeParam, r.Context())
+	if err != nil {
+		msg := fmt.Sprintf("Could not get export shape: %s", err)
+		respondWithError(w, http.StatusInternalServerError, msg)
+		return
+	}
+
+	entry, err := cfg.buildDraftJsonEntry(branchId, shape, r.Context())
+	if err != nil {
+		respondWithBranchJsonError(w, err)
+		return
+	}
+	cfg.jsonCache.set(branchId, cacheKey, generation, entry)
+	serveJsonEntry(w, r, entry)
+}
+
+func (cfg *apiConfig) buildDraftJsonEntry(branchId uuid.UUID, shape string, ctx context.Context) (*jsonCacheEntry, error) {
+	sheets, err := cfg.getBranchJson(branchId, shape, ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := json.Marshal(renderSheetsJson(sheets, shape))
+	if err != nil {
+		return nil, fmt.Errorf("Could not encode branch json: %s", err)
+	}
+
+	lastModified, err := cfg.getBranchLastModified(branchId, ctx)
+	if err != nil {
+		return nil, fmt.Errorf("Could not get last modification of branch: %s", err)
+	}
+
+	return newJsonCacheEntry(body, lastModified, 0), nil
+}
+
+// getBranchLastModified returns the time of the last write to the data of
+// the branch, zero time when it was not written since it was created.
+func (cfg *apiConfig) getBranchLastModified(branchId uuid.UUID, ctx context.Context) (time.Time, error) {
+	modifiedAt, err := cfg.db.GetBranchDataModified(ctx, branchId)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return modifiedAt.Time, nil
+}
+
+// getBranchJson builds the export of the live data of a branch. The keys of
+// list sheets are only checked for the object shape, which needs them.
+func (cfg *apiConfig) getBranchJson(branchId uuid.UUID, shape string, ctx context.Context) ([]SheetJson, error) {
+	sheetsDb, err := cfg.db.GetSheetsFromBranch(ctx, branchId)
+	if err != nil {
+		return nil, fmt.Errorf("Could not get sheets from branch id: %s", err)
+	}
+
+	enums, err := cfg.getEnumsForBranch(branchId, ctx)
+	if err != nil {
+		return nil, fmt.Errorf("Could not get enums for branch: %s", err)
+	}
+	enumTypes := getEnumTypes(enums)
+
+	sheets := make([]SheetJson, 0, len(sheetsDb))
+	invalidCells := []CellError{}
 	for _, sheet := range sheetsDb {
 		if sheet.Type == SheetTypeMap {
-			row, err := cfg.getMapSheetJson(sheet, r.Context())
+			row, cellErrors, err := cfg.getMapSheetJson(sheet, enumTypes, ctx)
 			if err != nil {
-				msg := fmt.Sprintf("Could not get row from map sheet: %s", err)
-				respondWithError(w, http.StatusInternalServerError, msg)
-				return
+				return nil, fmt.Errorf("Could not get row from map sheet: %s", err)
 			}
-			data = append(data, row)
+			invalidCells = append(invalidCells, cellErrors...)
+			sheets = append(sheets, SheetJson{Name: sheet.Name, Type: sheet.Type, Data: row})
 			continue
 		}
 
-		rows, err := cfg.getListSheetJson(sheet, r.Context())
+		rows, keyColumn, cellErrors, err := cfg.getListSheetJson(sheet, enumTypes, shape == ExportShapeObject, ctx)
 		if err != nil {
-			msg := fmt.Sprintf("Could not get rows from list sheet: %s", err)
-			respondWithError(w, http.StatusInternalServerError, msg)
-			return
+			return nil, fmt.Errorf("Could not get rows from list sheet: %s", err)
 		}
+		invalidCells = append(invalidCells, cellErrors...)
+		sheets = append(sheets, SheetJson{Name: sheet.Name, Type: sheet.Type, KeyColumn: keyColumn, Data: rows})
+	}
+
+	if len(invalidCells) > 0 {
+		return nil, &InvalidCellsError{Cells: invalidCells}
+	}
+	return sheets, nil
+}
+
+func renderSheetsJson(sheets []SheetJson, shape string) any {
+	if shape == ExportShapeObject {
+		return renderSheetsObject(sheets)
+	}
+
+	data := make([]any, 0, len(sheets))
+	for i := range sheets {
+		data = append(data, sheets[i].Data)
+	}
+	return data
+}
+
+// renderSheetsObject keys the sheets by their name, list sheets with a key
+// column become objects keyed by the value of that column. getBranchJson
+// refuses missing and duplicate keys for this shape, so no live row is lost
+// here.
+func renderSheetsObject(sheets []SheetJson) map[string]any {
+	data := make(map[string]any, len(sheets))
+	for i := range sheets {
+		sheet := &sheets[i]
+		if sheet.KeyColumn == "" {
+			data[sheet.Name] = sheet.Data
+			continue
+		}
+
+		keyed := make(map[string]any)
+		for _, row := range listSheetRows(sheet.Data) {
+			key, ok := row[sheet.KeyColumn]
+			if !ok || key == nil {
+				continue
+			}
+			keyed[exportKey(key)] = row
+		}
+		data[sheet.Name] = keyed
+	}
+	return data
+}
+
+// listSheetRows accepts both freshly built rows and rows decoded from a release.
+func listSheetRows(data any) []map[string]any {
+	switch rows := data.(type) {
+	case []map[string]any:
+		return rows
+	case []any:
+		result := make([]map[string]any, 0, len(rows))
+		for _, row := range rows {
+			if obj, ok := row.(map[string]any); ok {
+				result = append(result, obj)
+			}
+		}
+		return result
+	}
+	return nil
+}
+
+func isValidExportShape(shape string) bool {
+	return shape == ExportShapeArray || shape == ExportShapeObject
+}
+
+func (cfg *apiConfig) resolveExportShape(branchId uuid.UUID, shapeParam string, ctx context.Context) (string, error) {
+	if shapeParam != "" {
+		return shapeParam, nil
+	}
 
-		data = append(data, rows)
+	branch, err := cfg.db.GetBranch(ctx, branchId)
+	if err == sql.ErrNoRows {
+		return ExportShapeArray, nil
 	}
-	respondWithJSON(w, http.StatusOK, data)
+	if err != nil {
+		return "", err
+	}
+
+	table, err := cfg.db.GetTable(ctx, branch.TableID)
+	if err != nil {
+		return "", err
+	}
+	if !isValidExportShape(table.ExportShape) {
+		return ExportShapeArray, nil
+	}
+	return table.ExportShape, nil
 }
 
 func (cfg *apiConfig) getColumnsWitRowCount(sheetId uuid.UUID, ctx context.Context) ([]Column, int64, error) {
@@ -72,61 +388,158 @@ func (cfg *apiConfig) getColumnsWitRowCount(sheetId uuid.UUID, ctx context.Conte
 	return columns, rowCount, nil
 }
 
-func (cfg *apiConfig) getMapSheetJson(sheet database.Sheet, ctx context.Context) (map[string]any, error) {
+// getMapSheetJson returns the entries of the sheet and every value cell that
+// does not parse, the error is only set when the sheet could not be read.
+func (cfg *apiConfig) getMapSheetJson(sheet database.Sheet, enumTypes map[string]bool, ctx context.Context) (map[string]any, []CellError, error) {
 	columns, rowCount, err := cfg.getColumnsWitRowCount(sheet.ID, ctx)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	if len(columns) < 2 {
-		return nil, errors.New("There are not enough columns")
+		return nil, nil, errors.New("There are not enough columns")
 	}
 
 	row := make(map[string]any)
+	cellErrors := []CellError{}
 
 	for i := range rowCount {
 		nameCell, ok := getDataAtColIdx(columns[0].Data, i)
 		if !ok || !nameCell.Value.Valid {
 			continue
 		}
-		valCell, ok := getDataAtColIdx(columns[1].Data, i)
-		if !ok || !valCell.Value.Valid || !valCell.Type.Valid {
+		valCell, _ := getDataAtColIdx(columns[1].Data, i)
+		// the value of an entry is always required
+		if empty, err := checkEmptyCell(valCell.Value, true); empty {
+			cellErrors = append(cellErrors, newCellError(sheet, &columns[1], valCell, err))
+			continue
+		}
+		if !valCell.Type.Valid {
 			continue
 		}
 
-		val, err := ParseValue(valCell.Value.String, valCell.Type.String)
+		val, err := parseExportValue(valCell.Value.String, valCell.Type.String, enumTypes)
 		if err != nil {
-			return nil, err
+			cellErrors = append(cellErrors, newCellError(sheet, &columns[1], valCell, err))
+			continue
 		}
 		row[nameCell.Value.String] = val
 	}
-	return row, nil
+	return row, cellErrors, nil
 }
 
-func (cfg *apiConfig) getListSheetJson(sheet database.Sheet, ctx context.Context) ([]map[string]any, error) {
+// getListSheetJson returns the rows of the sheet, the name of its key column
+// and every cell that does not export. Keys are only checked when checkKeys
+// is set, as only the object shape needs every row to have its own key.
+func (cfg *apiConfig) getListSheetJson(sheet database.Sheet, enumTypes map[string]bool, checkKeys bool, ctx context.Context) ([]map[string]any, string, []CellError, error) {
 	columns, rowCount, err := cfg.getColumnsWitRowCount(sheet.ID, ctx)
 	if err != nil {
-		return nil, err
+		return nil, "", nil, err
+	}
+
+	keyColumn := ""
+	for i := range columns {
+		if columns[i].IsKey {
+			keyColumn = columns[i].Name
+		}
+	}
+
+	rows, cellErrors := listSheetJsonRows(sheet, columns, rowCount, enumTypes, checkKeys)
+	return rows, keyColumn, cellErrors, nil
+}
+
+// listSheetJsonRows builds the rows of a list sheet. Empty cells follow
+// checkEmptyCell, they are left out of the row and only fail in required
+// columns of rows that are not empty.
+func listSheetJsonRows(sheet database.Sheet, columns []Column, rowCount int64, enumTypes map[string]bool, checkKeys bool) ([]map[string]any, []CellError) {
+	var keyCol *Column
+	if checkKeys {
+		for i := range columns {
+			if columns[i].IsKey {
+				keyCol = &columns[i]
+			}
+		}
 	}
 
 	rows := make([]map[string]any, 0, rowCount)
+	cellErrors := []CellError{}
+	// keyRows tells the row a key was first used in, the object shape needs
+	// every key once.
+	keyRows := make(map[string]int64)
 
 	for i := range rowCount {
 		row := make(map[string]any)
+		emptyRow := isEmptyRow(columns, i)
+		keyReported := false
 
 		for e := range columns {
 			col := &columns[e]
-			if cell, ok := getDataAtColIdx(col.Data, i); ok {
-				val, err := ParseValue(cell.Value.String, col.Type)
-				if err != nil {
-					return nil, err
+			cell, _ := getDataAtColIdx(col.Data, i)
+			// cells that were never written still report their row
+			cell.Idx = i
+			if empty, err := checkEmptyCell(cell.Value, col.Required); empty {
+				if err != nil && !emptyRow {
+					cellErrors = append(cellErrors, newCellError(sheet, col, cell, err))
+					keyReported = keyReported || col == keyCol
 				}
-				row[col.Name] = val
+				continue
+			}
+
+			val, err := parseExportValue(cell.Value.String, col.Type, enumTypes)
+			if err != nil {
+				cellErrors = append(cellErrors, newCellError(sheet, col, cell, err))
+				keyReported = keyReported || col == keyCol
+				continue
 			}
+			row[col.Name] = val
 		}
 		rows = append(rows, row)
+
+		if keyCol == nil || emptyRow || keyReported {
+			continue
+		}
+		key, ok := row[keyCol.Name]
+		keyStr := ""
+		if ok {
+			keyStr = exportKey(key)
+		}
+		if keyStr == "" {
+			cellErrors = append(cellErrors, newKeyError(sheet, keyCol, i, keyStr, "missing key"))
+			continue
+		}
+		if first, ok := keyRows[keyStr]; ok {
+			msg := fmt.Sprintf("duplicate key, already used in row %d", first)
+			cellErrors = append(cellErrors, newKeyError(sheet, keyCol, i, keyStr, msg))
+			continue
+		}
+		keyRows[keyStr] = i
+	}
+	return rows, cellErrors
+}
+
+func newKeyError(sheet database.Sheet, keyCol *Column, idx int64, value string, msg string) CellError {
+	return CellError{
+		SheetID:  sheet.ID,
+		Sheet:    sheet.Name,
+		ColumnID: keyCol.ID,
+		Column:   keyCol.Name,
+		Row:      idx,
+		Value:    value,
+		Error:    msg,
+	}
+}
+
+// exportKey is the object key of a row, numbers are written out in full
+// instead of in exponent form.
+func exportKey(key any) string {
+	switch v := key.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprint(v)
 	}
-	return rows, nil
 }
 
 // the column data has to be sorted ascending by their index
@@ -142,6 +555,30 @@ func getDataAtColIdx(data []ColumnData, idx int64) (ColumnData, bool) {
 	return data[i], true
 }
 
+func getEnumTypes(enums []Enum) map[string]bool {
+	enumTypes := make(map[string]bool, len(enums))
+	for i := range enums {
+		enumTypes[enums[i].Name] = true
+	}
+	return enumTypes
+}
+
+func getEnumVals(enums []Enum) map[string][]string {
+	enumVals := make(map[string][]string, len(enums))
+	for i := range enums {
+		enumVals[enums[i].Name] = enums[i].Vals
+	}
+	return enumVals
+}
+
+// parseExportValue exports values of enum typed columns as their plain string.
+func parseExportValue(input string, valueType string, enumTypes map[string]bool) (any, error) {
+	if enumTypes[valueType] {
+		return input, nil
+	}
+	return ParseValue(input, valueType)
+}
+
 func ParseValue(input string, valueType string) (any, error) {
 	switch strings.ToLower(valueType) {
 	case "text", "string":
@@ -0,0 +1,444 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Dass33/administratum/backend/internal/database"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+const jsonSchemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+// schemaSheet is a sheet with the columns its schema is built from, taken
+// from the live branch or from the snapshot of a release or tag.
+type schemaSheet struct {
+	sheet   database.Sheet
+	columns []Column
+}
+
+// getJsonSchemaHandler describes the document getJsonHandler serves for the
+// same version, tag and shape.
+func (cfg *apiConfig) getJsonSchemaHandler(w http.ResponseWriter, r *http.Request) {
+	branchIdStr := chi.URLParam(r, "branch_id")
+	branchId, err := uuid.Parse(branchIdStr)
+	if err != nil {
+		msg := fmt.Sprintf("Could not parse the branch id from url: %s", err)
+		respondWithError(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	var version int64
+	cacheKey := "schema|latest"
+	versionStr := r.URL.Query().Get("version")
+	if versionStr != "" {
+		version, err = strconv.ParseInt(versionStr, 10, 64)
+		if err != nil {
+			msg := fmt.Sprintf("Could not parse the release version: %s", err)
+			respondWithError(w, http.StatusBadRequest, msg)
+			return
+		}
+		cacheKey = "schema|version:" + versionStr
+	}
+
+	shapeParam := r.URL.Query().Get("shape")
+	if shapeParam != "" && !isValidExportShape(shapeParam) {
+		respondWithError(w, http.StatusBadRequest, "Invalid export shape")
+		return
+	}
+	cacheKey += "|" + shapeParam
+
+	tagName := r.URL.Query().Get("tag")
+	if tagName != "" {
+		if versionStr != "" {
+			respondWithError(w, http.StatusBadRequest, "Version and tag can not be combined")
+			return
+		}
+		cfg.serveTagJsonSchema(w, r, branchId, tagName, shapeParam)
+		return
+	}
+
+	if entry, ok := cfg.jsonCache.get(branchId, cacheKey); ok {
+		serveJsonEntry(w, r, entry)
+		return
+	}
+	generation := cfg.jsonCache.generation(branchId)
+
+	shape, err := cfg.resolveExportShape(branchId, shapeParam, r.Context())
+	if err != nil {
+		msg := fmt.Sprintf("Could not get export shape: %s", err)
+		respondWithError(w, http.StatusInternalServerError, msg)
+		return
+	}
+
+	var release database.Release
+	if versionStr != "" {
+		release, err = cfg.db.GetReleaseByVersion(r.Context(), database.GetReleaseByVersionParams{
+			BranchID: branchId,
+			Version:  version,
+		})
+		if err == sql.ErrNoRows {
+			respondWithError(w, http.StatusNotFound, "Release with given version not found")
+			return
+		}
+	} else {
+		release, err = cfg.db.GetLatestRelease(r.Context(), branchId)
+		if err == sql.ErrNoRows {
+			// branches that were never published serve the live data
+			entry, err := cfg.buildDraftJsonSchemaEntry(branchId, shape, r.Context())
+			if err != nil {
+				msg := fmt.Sprintf("Could not build json schema: %s", err)
+				respondWithError(w, http.StatusInternalServerError, msg)
+				return
+			}
+			cfg.jsonCache.set(branchId, cacheKey, generation, entry)
+			serveJsonEntry(w, r, entry)
+			return
+		}
+	}
+	if err != nil {
+		msg := fmt.Sprintf("Could not get release: %s", err)
+		respondWithError(w, http.StatusInternalServerError, msg)
+		return
+	}
+
+	if !release.Snapshot.Valid {
+		// releases published before their snapshot was kept are described
+		// by the live columns
+		entry, err := cfg.buildDraftJsonSchemaEntry(branchId, shape, r.Context())
+		if err != nil {
+			msg := fmt.Sprintf("Could not build json schema: %s", err)
+			respondWithError(w, http.StatusInternalServerError, msg)
+			return
+		}
+		cfg.jsonCache.set(branchId, cacheKey, generation, entry)
+		serveJsonEntry(w, r, entry)
+		return
+	}
+
+	var snapshot BranchSnapshot
+	err = json.Unmarshal([]byte(release.Snapshot.String), &snapshot)
+	if err != nil {
+		msg := fmt.Sprintf("Could not decode release snapshot: %s", err)
+		respondWithError(w, http.StatusInternalServerError, msg)
+		return
+	}
+
+	entry, err := cfg.buildSnapshotJsonSchemaEntry(branchId, snapshot, shape, release.CreatedAt, r.Context())
+	if err != nil {
+		msg := fmt.Sprintf("Could not build json schema: %s", err)
+		respondWithError(w, http.StatusInternalServerError, msg)
+		return
+	}
+	entry.immutable = versionStr != ""
+	cfg.jsonCache.set(branchId, cacheKey, generation, entry)
+	serveJsonEntry(w, r, entry)
+}
+
+// serveTagJsonSchema describes the export stored with a tag. Like the
+// export itself it is not cached, as tag names can be reused.
+func (cfg *apiConfig) serveTagJsonSchema(w http.ResponseWriter, r *http.Request, branchId uuid.UUID, tagName, shapeParam string) {
+	branch, err := cfg.db.GetBranch(r.Context(), branchId)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Branch not found")
+		return
+	}
+
+	tag, err := cfg.db.GetTagByName(r.Context(), database.GetTagByNameParams{
+		TableID: branch.TableID,
+		Name:    tagName,
+	})
+	if err == sql.ErrNoRows {
+		respondWithError(w, http.StatusNotFound, "Tag with given name not found")
+		return
+	}
+	if err != nil {
+		msg := fmt.Sprintf("Could not get tag: %s", err)
+		respondWithError(w, http.StatusInternalServerError, msg)
+		return
+	}
+
+	shape, err := cfg.resolveExportShape(branchId, shapeParam, r.Context())
+	if err != nil {
+		msg := fmt.Sprintf("Could not get export shape: %s", err)
+		respondWithError(w, http.StatusInternalServerError, msg)
+		return
+	}
+
+	snapshot, err := decodeTagSnapshot(tag)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	entry, err := cfg.buildSnapshotJsonSchemaEntry(branchId, snapshot, shape, tag.CreatedAt, r.Context())
+	if err != nil {
+		msg := fmt.Sprintf("Could not build json schema: %s", err)
+		respondWithError(w, http.StatusInternalServerError, msg)
+		return
+	}
+	serveJsonEntry(w, r, entry)
+}
+
+func (cfg *apiConfig) buildDraftJsonSchemaEntry(branchId uuid.UUID, shape string, ctx context.Context) (*jsonCacheEntry, error) {
+	branch, err := cfg.db.GetBranch(ctx, branchId)
+	if err != nil {
+		return nil, fmt.Errorf("Could not get branch: %s", err)
+	}
+
+	sheets, enumVals, err := cfg.getLiveSchemaSheets(branchId, ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	schema, err := buildJsonSchema(branch.Name, sheets, shape, enumVals)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := json.Marshal(schema)
+	if err != nil {
+		return nil, fmt.Errorf("Could not encode json schema: %s", err)
+	}
+
+	lastModified, err := cfg.getBranchLastModified(branchId, ctx)
+	if err != nil {
+		return nil, fmt.Errorf("Could not get last modification of branch: %s", err)
+	}
+	return newJsonCacheEntry(body, lastModified, 0), nil
+}
+
+func (cfg *apiConfig) buildSnapshotJsonSchemaEntry(branchId uuid.UUID, snapshot BranchSnapshot, shape string, createdAt time.Time, ctx context.Context) (*jsonCacheEntry, error) {
+	branch, err := cfg.db.GetBranch(ctx, branchId)
+	if err != nil {
+		return nil, fmt.Errorf("Could not get branch: %s", err)
+	}
+
+	sheets, enumVals := snapshot.schemaSheets()
+	schema, err := buildJsonSchema(branch.Name, sheets, shape, enumVals)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := json.Marshal(schema)
+	if err != nil {
+		return nil, fmt.Errorf("Could not encode json schema: %s", err)
+	}
+	return newJsonCacheEntry(body, createdAt, 0), nil
+}
+
+// getLiveSchemaSheets reads the sheets and enums of the branch as they are now.
+func (cfg *apiConfig) getLiveSchemaSheets(branchId uuid.UUID, ctx context.Context) ([]schemaSheet, map[string][]string, error) {
+	sheetsDb, err := cfg.db.GetSheetsFromBranch(ctx, branchId)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Could not get sheets from branch id: %s", err)
+	}
+
+	enums, err := cfg.getEnumsForBranch(branchId, ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Could not get enums for branch: %s", err)
+	}
+
+	sheets := make([]schemaSheet, 0, len(sheetsDb))
+	for _, sheet := range sheetsDb {
+		columns, err := cfg.GetColumns(sheet.ID, ctx)
+		if err != nil {
+			return nil, nil, fmt.Errorf("Could not get columns of sheet %s: %s", sheet.Name, err)
+		}
+		sheets = append(sheets, schemaSheet{sheet: sheet, columns: columns})
+	}
+	return sheets, getEnumVals(enums), nil
+}
+
+// schemaSheets turns the snapshot back into sheets with their columns and
+// cells, and collects its enums the way getEnumsForBranch does.
+func (snapshot BranchSnapshot) schemaSheets() ([]schemaSheet, map[string][]string) {
+	sheets := make([]schemaSheet, 0, len(snapshot.Sheets))
+	enumVals := make(map[string][]string)
+	for _, snapshotSheet := range snapshot.Sheets {
+		rowIdx := make(map[uuid.UUID]int64, len(snapshotSheet.Rows))
+		for _, row := range snapshotSheet.Rows {
+			rowIdx[row.ID] = row.Idx
+		}
+
+		columns := make([]Column, 0, len(snapshotSheet.Columns))
+		for _, snapshotColumn := range snapshotSheet.Columns {
+			column := Column{
+				ID:       snapshotColumn.ID,
+				Name:     snapshotColumn.Name,
+				Type:     snapshotColumn.Type,
+				Required: snapshotColumn.Required,
+				IsKey:    snapshotColumn.IsKey,
+				Data:     make([]ColumnData, 0, len(snapshotColumn.Cells)),
+			}
+			for _, cell := range snapshotColumn.Cells {
+				idx, ok := rowIdx[cell.RowID]
+				if !ok {
+					continue
+				}
+				column.Data = append(column.Data, ColumnData{
+					Idx:   idx,
+					Value: sql.NullString{String: cell.Value, Valid: true},
+					Type:  sql.NullString{String: cell.Type, Valid: cell.Type != ""},
+				})
+			}
+			slices.SortFunc(column.Data, func(a, b ColumnData) int {
+				return cmp.Compare(a.Idx, b.Idx)
+			})
+			columns = append(columns, column)
+		}
+
+		if snapshotSheet.Type == SheetTypeEnums && len(columns) > 0 {
+			var vals []string
+			for _, cell := range columns[0].Data {
+				if cell.Value.String != "" {
+					vals = append(vals, cell.Value.String)
+				}
+			}
+			enumVals[snapshotSheet.Name] = vals
+		}
+
+		sheets = append(sheets, schemaSheet{
+			sheet:   database.Sheet{ID: snapshotSheet.ID, Name: snapshotSheet.Name, Type: snapshotSheet.Type},
+			columns: columns,
+		})
+	}
+	return sheets, enumVals
+}
+
+// buildJsonSchema describes the document produced by getBranchJson and
+// renderSheetsJson for the given sheets and shape.
+func buildJsonSchema(title string, sheets []schemaSheet, shape string, enumVals map[string][]string) (map[string]any, error) {
+	sheetSchemas := make([]any, 0, len(sheets))
+	properties := make(map[string]any, len(sheets))
+	required := make([]string, 0, len(sheets))
+	for i := range sheets {
+		sheetSchema, err := sheetJsonSchema(sheets[i].sheet, sheets[i].columns, shape, enumVals)
+		if err != nil {
+			return nil, err
+		}
+		name := sheets[i].sheet.Name
+		sheetSchemas = append(sheetSchemas, sheetSchema)
+		if _, exists := properties[name]; !exists {
+			required = append(required, name)
+		}
+		properties[name] = sheetSchema
+	}
+
+	schema := map[string]any{
+		"$schema": jsonSchemaDialect,
+		"title":   title,
+	}
+	if shape == ExportShapeObject {
+		schema["type"] = "object"
+		schema["properties"] = properties
+		schema["required"] = required
+		schema["additionalProperties"] = false
+		return schema, nil
+	}
+
+	schema["type"] = "array"
+	schema["prefixItems"] = sheetSchemas
+	schema["items"] = false
+	schema["minItems"] = len(sheetSchemas)
+	return schema, nil
+}
+
+func sheetJsonSchema(sheet database.Sheet, columns []Column, shape string, enumVals map[string][]string) (map[string]any, error) {
+	if sheet.Type == SheetTypeMap {
+		if len(columns) < 2 {
+			return nil, fmt.Errorf("Sheet %s does not have enough columns", sheet.Name)
+		}
+
+		properties := make(map[string]any)
+		required := make([]string, 0)
+		for i := range sheetRowCount(columns) {
+			nameCell, ok := getDataAtColIdx(columns[0].Data, i)
+			if !ok || !nameCell.Value.Valid {
+				continue
+			}
+			valCell, ok := getDataAtColIdx(columns[1].Data, i)
+			if !ok || !valCell.Value.Valid || !valCell.Type.Valid {
+				continue
+			}
+			if _, exists := properties[nameCell.Value.String]; !exists {
+				required = append(required, nameCell.Value.String)
+			}
+			properties[nameCell.Value.String] = valueTypeJsonSchema(valCell.Type.String, enumVals)
+		}
+
+		return map[string]any{
+			"title":                sheet.Name,
+			"type":                 "object",
+			"properties":           properties,
+			"required":             required,
+			"additionalProperties": false,
+		}, nil
+	}
+
+	properties := make(map[string]any, len(columns))
+	required := make([]string, 0)
+	keyColumn := ""
+	for i := range columns {
+		properties[columns[i].Name] = valueTypeJsonSchema(columns[i].Type, enumVals)
+		if columns[i].Required {
+			required = append(required, columns[i].Name)
+		}
+		if columns[i].IsKey {
+			keyColumn = columns[i].Name
+		}
+	}
+
+	rowSchema := map[string]any{
+		"type":                 "object",
+		"properties":           properties,
+		"required":             required,
+		"additionalProperties": false,
+	}
+
+	if shape == ExportShapeObject && keyColumn != "" {
+		return map[string]any{
+			"title":                sheet.Name,
+			"type":                 "object",
+			"additionalProperties": rowSchema,
+		}, nil
+	}
+
+	return map[string]any{
+		"title": sheet.Name,
+		"type":  "array",
+		"items": rowSchema,
+	}, nil
+}
+
+// valueTypeJsonSchema mirrors the values ParseValue and parseExportValue produce.
+func valueTypeJsonSchema(valueType string, enumVals map[string][]string) map[string]any {
+	if vals, ok := enumVals[valueType]; ok {
+		enum := make([]any, 0, len(vals))
+		for _, val := range vals {
+			enum = append(enum, val)
+		}
+		return map[string]any{"type": "string", "enum": enum}
+	}
+
+	switch strings.ToLower(valueType) {
+	case "text", "string":
+		return map[string]any{"type": "string"}
+	case "number", "int", "float":
+		return map[string]any{"type": "number"}
+	case "bool", "boolean":
+		return map[string]any{"type": "boolean"}
+	case "array":
+		return map[string]any{"type": "array"}
+	}
+	return map[string]any{}
+}
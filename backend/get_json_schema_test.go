@@ -0,0 +1,59 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestSnapshotJsonSchema(t *testing.T) {
+	rows := []SnapshotRow{{ID: uuid.New(), Idx: 0}, {ID: uuid.New(), Idx: 1}}
+	snapshot := BranchSnapshot{Sheets: []SnapshotSheet{
+		{
+			ID:   uuid.New(),
+			Name: "rarity",
+			Type: SheetTypeEnums,
+			Rows: rows,
+			Columns: []SnapshotColumn{{
+				ID:   uuid.New(),
+				Name: "values",
+				Type: "text",
+				Cells: []SnapshotCell{
+					{RowID: rows[1].ID, Value: "rare"},
+					{RowID: rows[0].ID, Value: "common"},
+				},
+			}},
+		},
+		{
+			ID:   uuid.New(),
+			Name: "items",
+			Type: SheetTypeList,
+			Columns: []SnapshotColumn{
+				{ID: uuid.New(), Name: "name", Type: "text", Required: true, IsKey: true},
+				{ID: uuid.New(), Name: "rarity", Type: "rarity"},
+			},
+		},
+	}}
+
+	sheets, enumVals := snapshot.schemaSheets()
+	if want := []string{"common", "rare"}; !reflect.DeepEqual(enumVals["rarity"], want) {
+		t.Errorf("enum values = %v, want %v", enumVals["rarity"], want)
+	}
+
+	schema, err := buildJsonSchema("main", sheets, ExportShapeObject, enumVals)
+	if err != nil {
+		t.Fatal(err)
+	}
+	items := schema["properties"].(map[string]any)["items"].(map[string]any)
+	rowSchema := items["additionalProperties"].(map[string]any)
+	properties := rowSchema["properties"].(map[string]any)
+
+	wantRarity := map[string]any{"type": "string", "enum": []any{"common", "rare"}}
+	if !reflect.DeepEqual(properties["rarity"], wantRarity) {
+		t.Errorf("rarity schema = %v, want %v", properties["rarity"], wantRarity)
+	}
+	if want := []string{"name"}; !reflect.DeepEqual(rowSchema["required"], want) {
+		t.Errorf("required = %v, want %v", rowSchema["required"], want)
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/Dass33/administratum/backend/internal/database"
+	"github.com/google/uuid"
+)
+
+func testCell(idx int64, value string) ColumnData {
+	return ColumnData{ID: uuid.New(), Idx: idx, Value: sql.NullString{String: value, Valid: true}}
+}
+
+func TestListSheetJsonRowsBlankOptionalNumber(t *testing.T) {
+	sheet := database.Sheet{ID: uuid.New(), Name: "items"}
+	columns := []Column{
+		{ID: uuid.New(), Name: "name", Type: "text", Required: true, Data: []ColumnData{testCell(0, "sword"), testCell(1, "shield")}},
+		{ID: uuid.New(), Name: "price", Type: "number", Data: []ColumnData{testCell(0, "10"), testCell(1, "")}},
+	}
+
+	rows, cellErrors := listSheetJsonRows(sheet, columns, 2, nil, false)
+	if len(cellErrors) != 0 {
+		t.Fatalf("blank optional cell was refused: %v", cellErrors)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("got %d rows, want 2", len(rows))
+	}
+	if rows[0]["price"] != int64(10) {
+		t.Errorf("price of row 0 = %v, want 10", rows[0]["price"])
+	}
+	if price, ok := rows[1]["price"]; ok {
+		t.Errorf("blank price of row 1 was exported as %v", price)
+	}
+}
+
+func TestListSheetJsonRowsBlankRequired(t *testing.T) {
+	sheet := database.Sheet{ID: uuid.New(), Name: "items"}
+	columns := []Column{
+		{ID: uuid.New(), Name: "name", Type: "text", Data: []ColumnData{testCell(0, "sword"), testCell(1, "shield")}},
+		{ID: uuid.New(), Name: "price", Type: "number", Required: true, Data: []ColumnData{testCell(0, "10")}},
+		{ID: uuid.New(), Name: "weight", Type: "number", Required: true},
+	}
+
+	// row 2 is empty and left alone
+	_, cellErrors := listSheetJsonRows(sheet, columns, 3, nil, false)
+	want := []CellError{
+		{Column: "weight", Row: 0},
+		{Column: "price", Row: 1},
+		{Column: "weight", Row: 1},
+	}
+	if len(cellErrors) != len(want) {
+		t.Fatalf("got %d cell errors, want %d: %v", len(cellErrors), len(want), cellErrors)
+	}
+	for i := range want {
+		got := cellErrors[i]
+		if got.Column != want[i].Column || got.Row != want[i].Row || got.Error != errValueRequired.Error() {
+			t.Errorf("cell error %d = %+v, want required %s in row %d", i, got, want[i].Column, want[i].Row)
+		}
+	}
+}
+
+func TestListSheetJsonRowsKeys(t *testing.T) {
+	sheet := database.Sheet{ID: uuid.New(), Name: "items"}
+	columns := []Column{
+		{ID: uuid.New(), Name: "id", Type: "number", IsKey: true, Data: []ColumnData{testCell(0, "1"), testCell(1, "1")}},
+		{ID: uuid.New(), Name: "name", Type: "text", Data: []ColumnData{testCell(0, "sword"), testCell(1, "shield"), testCell(2, "bow")}},
+	}
+
+	if _, cellErrors := listSheetJsonRows(sheet, columns, 3, nil, false); len(cellErrors) != 0 {
+		t.Errorf("keys were checked for the array shape: %v", cellErrors)
+	}
+
+	_, cellErrors := listSheetJsonRows(sheet, columns, 3, nil, true)
+	want := []CellError{
+		{Row: 1, Value: "1", Error: "duplicate key, already used in row 0"},
+		{Row: 2, Value: "", Error: "missing key"},
+	}
+	if len(cellErrors) != len(want) {
+		t.Fatalf("got %d cell errors, want %d: %v", len(cellErrors), len(want), cellErrors)
+	}
+	for i := range want {
+		got := cellErrors[i]
+		if got.Column != "id" || got.Row != want[i].Row || got.Value != want[i].Value || got.Error != want[i].Error {
+			t.Errorf("cell error %d = %+v, want %+v", i, got, want[i])
+		}
+	}
+}
+
+func TestExportKey(t *testing.T) {
+	tests := []struct {
+		key  any
+		want string
+	}{
+		{key: "sword", want: "sword"},
+		{key: int64(12), want: "12"},
+		{key: 1e21, want: "1000000000000000000000"},
+		{key: 0.5, want: "0.5"},
+		{key: true, want: "true"},
+	}
+
+	for _, tt := range tests {
+		if got := exportKey(tt.key); got != tt.want {
+			t.Errorf("exportKey(%v) = %q, want %q", tt.key, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+func (cfg *apiConfig) getMergeRequestHandler(w http.ResponseWriter, r *http.Request, userId uuid.UUID) {
+	mergeRequestIdStr := chi.URLParam(r, "merge_request_id")
+	mergeRequestId, err := uuid.Parse(mergeRequestIdStr)
+	if err != nil {
+		msg := fmt.Sprintf("Could not parse the merge request id from url: %s", err)
+		respondWithError(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	mergeRequestDb, err := cfg.db.GetMergeRequest(r.Context(), mergeRequestId)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Merge request not found")
+		return
+	}
+
+	if !cfg.checkTablePermission(userId, mergeRequestDb.TableID, "read", r.Context()) {
+		respondWithError(w, http.StatusForbidden, "Insufficient read permissions")
+		return
+	}
+
+	mergeRequest, err := cfg.getMergeRequest(mergeRequestId, r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	respondWithJSON(w, http.StatusOK, mergeRequest)
+}
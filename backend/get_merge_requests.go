@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+type MergeRequestSummary struct {
+	ID               uuid.UUID     `json:"id"`
+	SourceBranchID   uuid.NullUUID `json:"source_branch_id"`
+	SourceBranchName string        `json:"source_branch_name"`
+	TargetBranchID   uuid.NullUUID `json:"target_branch_id"`
+	TargetBranchName string        `json:"target_branch_name"`
+	Title            string        `json:"title"`
+	Status           string        `json:"status"`
+	AuthorEmail      string        `json:"author_email"`
+	CreatedAt        time.Time     `json:"created_at"`
+	UpdatedAt        time.Time     `json:"updated_at"`
+	MergedAt         *time.Time    `json:"merged_at,omitempty"`
+}
+
+func (cfg *apiConfig) getMergeRequestsHandler(w http.ResponseWriter, r *http.Request, userId uuid.UUID) {
+	tableIdStr := chi.URLParam(r, "table_id")
+	tableId, err := uuid.Parse(tableIdStr)
+	if err != nil {
+		msg := fmt.Sprintf("Could not parse the table id from url: %s", err)
+		respondWithError(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	status := r.URL.Query().Get("status")
+	if status != "" && status != MergeRequestOpen && status != MergeRequestMerged && status != MergeRequestClosed {
+		msg := fmt.Sprintf("Unknown merge request status: %s", status)
+		respondWithError(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	if !cfg.checkTablePermission(userId, tableId, "read", r.Context()) {
+		respondWithError(w, http.StatusForbidden, "Insufficient read permissions")
+		return
+	}
+
+	mergeRequestsDb, err := cfg.db.GetMergeRequestsFromTable(r.Context(), tableId)
+	if err != nil {
+		msg := fmt.Sprintf("Could not get merge requests from table: %s", err)
+		respondWithError(w, http.StatusInternalServerError, msg)
+		return
+	}
+
+	mergeRequests := make([]MergeRequestSummary, 0, len(mergeRequestsDb))
+	for i := range mergeRequestsDb {
+		if status != "" && mergeRequestsDb[i].Status != status {
+			continue
+		}
+
+		summary := MergeRequestSummary{
+			ID:               mergeRequestsDb[i].ID,
+			SourceBranchID:   mergeRequestsDb[i].SourceBranchID,
+			SourceBranchName: mergeRequestsDb[i].SourceBranchName,
+			TargetBranchID:   mergeRequestsDb[i].TargetBranchID,
+			TargetBranchName: mergeRequestsDb[i].TargetBranchName,
+			Title:            mergeRequestsDb[i].Title,
+			Status:           mergeRequestsDb[i].Status,
+			AuthorEmail:      mergeRequestsDb[i].AuthorEmail.String,
+			CreatedAt:        mergeRequestsDb[i].CreatedAt,
+			UpdatedAt:        mergeRequestsDb[i].UpdatedAt,
+		}
+		if mergeRequestsDb[i].MergedAt.Valid {
+			summary.MergedAt = &mergeRequestsDb[i].MergedAt.Time
+		}
+		mergeRequests = append(mergeRequests, summary)
+	}
+	respondWithJSON(w, http.StatusOK, mergeRequests)
+}
@@ -11,9 +11,13 @@ type MergeTarget struct {
 	Name string `json:"name"`
 }
 
+// GetMergeTargetsResponse lists the branches that can be merged into the
+// oldest branch as valid targets, Targets lists every branch the user can
+// merge into.
 type GetMergeTargetsResponse struct {
 	ValidTargets []MergeTarget `json:"valid_targets"`
 	TargetBranch MergeTarget   `json:"target_branch"`
+	Targets      []MergeTarget `json:"targets"`
 }
 
 func (cfg *apiConfig) getMergeTargetsHandler(w http.ResponseWriter, r *http.Request, userId uuid.UUID) {
@@ -34,6 +38,16 @@ func (cfg *apiConfig) getMergeTargetsHandler(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
+	sourceBranchId := uuid.NullUUID{}
+	if sourceIDStr := r.URL.Query().Get("source_branch_id"); sourceIDStr != "" {
+		sourceBranchId.UUID, err = uuid.Parse(sourceIDStr)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid source_branch_id format")
+			return
+		}
+		sourceBranchId.Valid = true
+	}
+
 	ctx := r.Context()
 
 	allBranches, err := cfg.db.GetBranchesFromTable(ctx, tableID)
@@ -48,8 +62,20 @@ func (cfg *apiConfig) getMergeTargetsHandler(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	if !cfg.checkBranchPermission(userId, oldestBranch.ID, "write", ctx) {
-		respondWithError(w, http.StatusForbidden, "No write permission on target branch")
+	targets := make([]MergeTarget, 0)
+	for _, branch := range allBranches {
+		if sourceBranchId.Valid && branch.ID == sourceBranchId.UUID {
+			continue
+		}
+		if cfg.checkBranchPermission(userId, branch.ID, "merge", ctx) {
+			targets = append(targets, MergeTarget{
+				ID:   branch.ID.String(),
+				Name: branch.Name,
+			})
+		}
+	}
+	if len(targets) == 0 {
+		respondWithError(w, http.StatusForbidden, "No write permission on any target branch")
 		return
 	}
 
@@ -75,6 +101,7 @@ func (cfg *apiConfig) getMergeTargetsHandler(w http.ResponseWriter, r *http.Requ
 			ID:   oldestBranch.ID.String(),
 			Name: oldestBranch.Name,
 		},
+		Targets: targets,
 	}
 
 	respondWithJSON(w, http.StatusOK, response)
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// MergeSummary counts the changes a merge applied to the target branch.
+type MergeSummary struct {
+	SheetsCreated   int `json:"sheets_created"`
+	SheetsDeleted   int `json:"sheets_deleted"`
+	SheetsUpdated   int `json:"sheets_updated"`
+	ColumnsCreated  int `json:"columns_created"`
+	ColumnsDeleted  int `json:"columns_deleted"`
+	ColumnsUpdated  int `json:"columns_updated"`
+	RowsInserted    int `json:"rows_inserted"`
+	RowsDeleted     int `json:"rows_deleted"`
+	CellsUpdated    int `json:"cells_updated"`
+	Conflicts       int `json:"conflicts"`
+	ConflictsSource int `json:"conflicts_source"`
+	ConflictsTarget int `json:"conflicts_target"`
+	ConflictsCustom int `json:"conflicts_custom"`
+}
+
+type MergeRecord struct {
+	ID               uuid.UUID         `json:"id"`
+	SourceBranchID   uuid.NullUUID     `json:"source_branch_id"`
+	SourceBranchName string            `json:"source_branch_name"`
+	TargetBranchID   uuid.NullUUID     `json:"target_branch_id"`
+	TargetBranchName string            `json:"target_branch_name"`
+	UserID           uuid.NullUUID     `json:"user_id"`
+	UserEmail        string            `json:"user_email"`
+	MergeRequestID   uuid.NullUUID     `json:"merge_request_id"`
+	SourceKept       bool              `json:"source_kept"`
+	Resolutions      []MergeResolution `json:"resolutions"`
+	Summary          MergeSummary      `json:"summary"`
+	CreatedAt        time.Time         `json:"created_at"`
+}
+
+func (cfg *apiConfig) getMergesHandler(w http.ResponseWriter, r *http.Request, userId uuid.UUID) {
+	tableIdStr := chi.URLParam(r, "table_id")
+	tableId, err := uuid.Parse(tableIdStr)
+	if err != nil {
+		msg := fmt.Sprintf("Could not parse the table id from url: %s", err)
+		respondWithError(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	if !cfg.checkTablePermission(userId, tableId, "read", r.Context()) {
+		respondWithError(w, http.StatusForbidden, "Insufficient read permissions")
+		return
+	}
+
+	mergesDb, err := cfg.db.GetMergesFromTable(r.Context(), tableId)
+	if err != nil {
+		msg := fmt.Sprintf("Could not get merges from table: %s", err)
+		respondWithError(w, http.StatusInternalServerError, msg)
+		return
+	}
+
+	merges := make([]MergeRecord, 0, len(mergesDb))
+	for i := range mergesDb {
+		merge := MergeRecord{
+			ID:               mergesDb[i].ID,
+			SourceBranchID:   mergesDb[i].SourceBranchID,
+			SourceBranchName: mergesDb[i].SourceBranchName,
+			TargetBranchID:   mergesDb[i].TargetBranchID,
+			TargetBranchName: mergesDb[i].TargetBranchName,
+			UserID:           mergesDb[i].UserID,
+			UserEmail:        mergesDb[i].UserEmail.String,
+			MergeRequestID:   mergesDb[i].MergeRequestID,
+			SourceKept:       mergesDb[i].SourceKept,
+			Resolutions:      []MergeResolution{},
+			CreatedAt:        mergesDb[i].CreatedAt,
+		}
+		if err := json.Unmarshal([]byte(mergesDb[i].Resolutions), &merge.Resolutions); err != nil {
+			msg := fmt.Sprintf("Could not decode merge resolutions: %s", err)
+			respondWithError(w, http.StatusInternalServerError, msg)
+			return
+		}
+		if err := json.Unmarshal([]byte(mergesDb[i].Summary), &merge.Summary); err != nil {
+			msg := fmt.Sprintf("Could not decode merge summary: %s", err)
+			respondWithError(w, http.StatusInternalServerError, msg)
+			return
+		}
+		merges = append(merges, merge)
+	}
+	respondWithJSON(w, http.StatusOK, merges)
+}
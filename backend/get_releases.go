@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+func (cfg *apiConfig) getReleasesHandler(w http.ResponseWriter, r *http.Request, userId uuid.UUID) {
+	branchIdStr := chi.URLParam(r, "branch_id")
+	branchId, err := uuid.Parse(branchIdStr)
+	if err != nil {
+		msg := fmt.Sprintf("Could not parse the branch id from url: %s", err)
+		respondWithError(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	if !cfg.checkBranchPermission(userId, branchId, "read", r.Context()) {
+		respondWithError(w, http.StatusForbidden, "Insufficient read permissions")
+		return
+	}
+
+	releasesDb, err := cfg.db.GetReleasesFromBranch(r.Context(), branchId)
+	if err != nil {
+		msg := fmt.Sprintf("Could not get releases from branch: %s", err)
+		respondWithError(w, http.StatusInternalServerError, msg)
+		return
+	}
+
+	releases := make([]Release, 0, len(releasesDb))
+	for i := range releasesDb {
+		releases = append(releases, Release{
+			ID:          releasesDb[i].ID,
+			Version:     releasesDb[i].Version,
+			Message:     releasesDb[i].Message,
+			AuthorEmail: releasesDb[i].AuthorEmail.String,
+			CreatedAt:   releasesDb[i].CreatedAt,
+		})
+	}
+	respondWithJSON(w, http.StatusOK, releases)
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/Dass33/administratum/backend/internal/database"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+const defaultHistoryLimit = 50
+const maxHistoryLimit = 500
+
+// getSheetHistoryHandler lists the recent edits of a sheet, newest first.
+// Older pages are requested with before set to the seq of the last entry.
+func (cfg *apiConfig) getSheetHistoryHandler(w http.ResponseWriter, r *http.Request, userId uuid.UUID) {
+	sheetIdStr := chi.URLParam(r, "sheet_id")
+	sheetId, err := uuid.Parse(sheetIdStr)
+	if err != nil {
+		msg := fmt.Sprintf("Could not parse the sheet id from url: %s", err)
+		respondWithError(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	limit := int64(defaultHistoryLimit)
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		limit, err = strconv.ParseInt(limitStr, 10, 64)
+		if err != nil || limit < 1 || limit > maxHistoryLimit {
+			msg := fmt.Sprintf("Limit has to be a number between 1 and %d", maxHistoryLimit)
+			respondWithError(w, http.StatusBadRequest, msg)
+			return
+		}
+	}
+	before := int64(math.MaxInt64)
+	if beforeStr := r.URL.Query().Get("before"); beforeStr != "" {
+		before, err = strconv.ParseInt(beforeStr, 10, 64)
+		if err != nil {
+			msg := fmt.Sprintf("Could not parse before: %s", err)
+			respondWithError(w, http.StatusBadRequest, msg)
+			return
+		}
+	}
+
+	historyDb, err := cfg.db.GetSheetHistory(r.Context(), database.GetSheetHistoryParams{
+		SheetID:    sheetId,
+		BeforeSeq:  before,
+		LimitCount: limit,
+	})
+	if err != nil {
+		msg := fmt.Sprintf("Could not get sheet history: %s", err)
+		respondWithError(w, http.StatusInternalServerError, msg)
+		return
+	}
+
+	// Deleted sheets keep their history, so the permission is checked on the
+	// branch the entries belong to.
+	var allowed bool
+	if len(historyDb) > 0 {
+		allowed = cfg.checkBranchPermission(userId, historyDb[0].BranchID, "read", r.Context())
+	} else {
+		allowed = cfg.checkSheetPermission(userId, sheetId, "read", r.Context())
+	}
+	if !allowed {
+		respondWithError(w, http.StatusForbidden, "Insufficient read permissions")
+		return
+	}
+
+	history := make([]HistoryEntry, 0, len(historyDb))
+	for i := range historyDb {
+		history = append(history, historyEntryResponse(database.GetCellHistoryRow(historyDb[i])))
+	}
+	respondWithJSON(w, http.StatusOK, history)
+}
@@ -14,6 +14,7 @@ type TableData struct {
 	ID              uuid.UUID      `json:"id"`
 	Name            string         `json:"name"`
 	GameUrl         sql.NullString `json:"game_url"`
+	ExportShape     string         `json:"export_shape"`
 	Permision       string         `json:"permision"`
 	BranchesIdNames []IdName       `json:"branches_id_names"`
 }
@@ -56,6 +57,7 @@ func (cfg *apiConfig) GetTable(user_id uuid.UUID, optional_table_id uuid.NullUUI
 		ID:              table_id,
 		Name:            table.Name,
 		GameUrl:         table.GameUrl,
+		ExportShape:     table.ExportShape,
 		Permision:       userTables.Permission,
 		BranchesIdNames: branchNames,
 	}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+func (cfg *apiConfig) getTagsHandler(w http.ResponseWriter, r *http.Request, userId uuid.UUID) {
+	tableIdStr := chi.URLParam(r, "table_id")
+	tableId, err := uuid.Parse(tableIdStr)
+	if err != nil {
+		msg := fmt.Sprintf("Could not parse the table id from url: %s", err)
+		respondWithError(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	if !cfg.checkTablePermission(userId, tableId, "read", r.Context()) {
+		respondWithError(w, http.StatusForbidden, "Insufficient read permissions")
+		return
+	}
+
+	tagsDb, err := cfg.db.GetTagsFromTable(r.Context(), tableId)
+	if err != nil {
+		msg := fmt.Sprintf("Could not get tags from table: %s", err)
+		respondWithError(w, http.StatusInternalServerError, msg)
+		return
+	}
+
+	tags := make([]Tag, 0, len(tagsDb))
+	for i := range tagsDb {
+		tags = append(tags, Tag{
+			ID:          tagsDb[i].ID,
+			BranchID:    tagsDb[i].BranchID,
+			BranchName:  tagsDb[i].BranchName,
+			Name:        tagsDb[i].Name,
+			Message:     tagsDb[i].Message,
+			AuthorEmail: tagsDb[i].AuthorEmail.String,
+			CreatedAt:   tagsDb[i].CreatedAt,
+		})
+	}
+	respondWithJSON(w, http.StatusOK, tags)
+}
@@ -0,0 +1,272 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/Dass33/administratum/backend/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	HistoryEntityCell   = "cell"
+	HistoryEntityRow    = "row"
+	HistoryEntityColumn = "column"
+	HistoryEntitySheet  = "sheet"
+
+	HistoryActionCreate = "create"
+	HistoryActionUpdate = "update"
+	HistoryActionDelete = "delete"
+	HistoryActionRename = "rename"
+	HistoryActionSwap   = "swap"
+	HistoryActionSetKey = "set_key"
+	// HistoryActionRestore brings back a deleted row or column on undo.
+	HistoryActionRestore = "restore"
+	// Merges, syncs, imports and tag restores change many cells at once,
+	// they leave one HistorySheetChange entry per sheet they changed.
+	HistoryActionMerge      = "merge"
+	HistoryActionImport     = "import"
+	HistoryActionRestoreTag = "restore_tag"
+)
+
+// HistoryEntry is one recorded edit of a sheet. OldValue and NewValue hold
+// the json of the edited item before and after the edit, null for created
+// and deleted items: a HistoryCellValue for cells, HistoryColumnValue for
+// columns, HistoryRowValue for rows and HistorySheetValue for sheets. Column
+// swaps map the column ids to their order_index, key column changes hold
+// the id of the key column.
+type HistoryEntry struct {
+	ID        uuid.UUID       `json:"id"`
+	Seq       int64           `json:"seq"`
+	BranchID  uuid.UUID       `json:"branch_id"`
+	SheetID   uuid.UUID       `json:"sheet_id"`
+	ColumnID  uuid.NullUUID   `json:"column_id"`
+	RowID     uuid.NullUUID   `json:"row_id"`
+	CellID    uuid.NullUUID   `json:"cell_id"`
+	UserEmail string          `json:"user_email"`
+	Entity    string          `json:"entity"`
+	Action    string          `json:"action"`
+	OldValue  json.RawMessage `json:"old_value"`
+	NewValue  json.RawMessage `json:"new_value"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+type HistoryCellValue struct {
+	Value string `json:"value"`
+	Type  string `json:"type,omitempty"`
+}
+
+// HistoryColumnValue holds the cells of the column only when it was
+// deleted, so it can be brought back with its data. OriginID is the origin
+// merges match the column by.
+type HistoryColumnValue struct {
+	Name       string              `json:"name"`
+	Type       string              `json:"type"`
+	Required   bool                `json:"required"`
+	IsKey      bool                `json:"is_key"`
+	OrderIndex int64               `json:"order_index"`
+	OriginID   uuid.UUID           `json:"origin_id"`
+	Cells      []HistoryColumnCell `json:"cells,omitempty"`
+}
+
+type HistoryColumnCell struct {
+	CellID uuid.UUID `json:"cell_id"`
+	RowID  uuid.UUID `json:"row_id"`
+	Idx    int64     `json:"idx"`
+	HistoryCellValue
+}
+
+// HistoryRowValue is a deleted row, the rows after it moved up by one.
+type HistoryRowValue struct {
+	Idx      int64            `json:"idx"`
+	OriginID uuid.UUID        `json:"origin_id"`
+	Cells    []HistoryRowCell `json:"cells"`
+}
+
+type HistoryRowCell struct {
+	CellID   uuid.UUID `json:"cell_id"`
+	ColumnID uuid.UUID `json:"column_id"`
+	HistoryCellValue
+}
+
+type HistorySheetValue struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// HistorySheetChange sums up a bulk change of a sheet. Source names the
+// merged branch, the imported file or the restored tag, Change is added,
+// removed or changed like in a branch diff.
+type HistorySheetChange struct {
+	Source  string `json:"source"`
+	Change  string `json:"change"`
+	Name    string `json:"name"`
+	OldName string `json:"old_name,omitempty"`
+	Columns int    `json:"columns"`
+	Cells   int    `json:"cells"`
+}
+
+// historyEntry is an edit to record, oldValue and newValue are encoded to
+// json and left null when nil.
+type historyEntry struct {
+	branchId uuid.UUID
+	sheetId  uuid.UUID
+	columnId uuid.NullUUID
+	rowId    uuid.NullUUID
+	cellId   uuid.NullUUID
+	entity   string
+	action   string
+	oldValue any
+	newValue any
+}
+
+// recordHistory appends the entry to the history, it should run in the
+// transaction of the edit so no edit goes unrecorded.
+func recordHistory(ctx context.Context, q *database.Queries, userId uuid.UUID, entry historyEntry) error {
+	_, err := createHistoryEntry(ctx, q, userId, entry)
+	return err
+}
+
+func createHistoryEntry(ctx context.Context, q *database.Queries, userId uuid.UUID, entry historyEntry) (database.History, error) {
+	oldValue, err := historyJson(entry.oldValue)
+	if err != nil {
+		return database.History{}, err
+	}
+	newValue, err := historyJson(entry.newValue)
+	if err != nil {
+		return database.History{}, err
+	}
+
+	history, err := q.CreateHistoryEntry(ctx, database.CreateHistoryEntryParams{
+		BranchID: entry.branchId,
+		SheetID:  entry.sheetId,
+		ColumnID: entry.columnId,
+		RowID:    entry.rowId,
+		CellID:   entry.cellId,
+		UserID:   uuid.NullUUID{UUID: userId, Valid: true},
+		Entity:   entry.entity,
+		Action:   entry.action,
+		OldValue: oldValue,
+		NewValue: newValue,
+	})
+	if err != nil {
+		return database.History{}, fmt.Errorf("could not record history: %w", err)
+	}
+	return history, nil
+}
+
+// loadBranchSide loads the branch for recordSheetChanges to compare with
+// after a bulk change.
+func loadBranchSide(ctx context.Context, q *database.Queries, branchId uuid.UUID) (mergeSide, error) {
+	rows, err := getBranchMergeRows(ctx, q, branchId)
+	if err != nil {
+		return mergeSide{}, fmt.Errorf("could not get branch data: %w", err)
+	}
+	return buildMergeSide(rows), nil
+}
+
+// recordSheetChanges records a HistorySheetChange for every sheet of the
+// branch that differs from before, in the transaction of the change.
+func recordSheetChanges(ctx context.Context, q *database.Queries, userId, branchId uuid.UUID, before mergeSide, action, source string) error {
+	after, err := loadBranchSide(ctx, q, branchId)
+	if err != nil {
+		return err
+	}
+	for _, diff := range diffSides(before, after) {
+		err := recordHistory(ctx, q, userId, historyEntry{
+			branchId: branchId,
+			sheetId:  diff.SheetID,
+			entity:   HistoryEntitySheet,
+			action:   action,
+			newValue: HistorySheetChange{
+				Source:  source,
+				Change:  diff.Change,
+				Name:    diff.Name,
+				OldName: diff.OldName,
+				Columns: len(diff.Columns),
+				Cells:   len(diff.Cells),
+			},
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func historyJson(value any) (sql.NullString, error) {
+	if value == nil {
+		return sql.NullString{}, nil
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return sql.NullString{}, fmt.Errorf("could not encode history value: %w", err)
+	}
+	return sql.NullString{String: string(data), Valid: true}, nil
+}
+
+func historyCellValue(value, cellType sql.NullString) HistoryCellValue {
+	return HistoryCellValue{Value: value.String, Type: cellType.String}
+}
+
+func historyColumnValue(column database.Column) HistoryColumnValue {
+	return HistoryColumnValue{
+		Name:       column.Name,
+		Type:       column.Type,
+		Required:   column.Required,
+		IsKey:      column.IsKey,
+		OrderIndex: column.OrderIndex,
+		OriginID:   originKey(column.OriginColumnID, column.ID),
+	}
+}
+
+// sheetRow returns the row at idx, rows that were never given an id get one.
+func sheetRow(ctx context.Context, q *database.Queries, sheetId uuid.UUID, idx int64) (database.SheetRow, error) {
+	row, err := q.GetSheetRowByIdx(ctx, database.GetSheetRowByIdxParams{
+		SheetID: sheetId,
+		Idx:     idx,
+	})
+	if err == nil {
+		return row, nil
+	}
+	if err != sql.ErrNoRows {
+		return database.SheetRow{}, fmt.Errorf("could not get row: %w", err)
+	}
+
+	row, err = q.CreateSheetRow(ctx, database.CreateSheetRowParams{
+		SheetID: sheetId,
+		Idx:     idx,
+	})
+	if err != nil {
+		return database.SheetRow{}, fmt.Errorf("could not create row: %w", err)
+	}
+	return row, nil
+}
+
+// historyEntryResponse also takes the rows of GetSheetHistory, which have
+// the same fields.
+func historyEntryResponse(row database.GetCellHistoryRow) HistoryEntry {
+	entry := HistoryEntry{
+		ID:        row.ID,
+		Seq:       row.Seq,
+		BranchID:  row.BranchID,
+		SheetID:   row.SheetID,
+		ColumnID:  row.ColumnID,
+		RowID:     row.RowID,
+		CellID:    row.CellID,
+		UserEmail: row.UserEmail.String,
+		Entity:    row.Entity,
+		Action:    row.Action,
+		CreatedAt: row.CreatedAt,
+	}
+	if row.OldValue.Valid {
+		entry.OldValue = json.RawMessage(row.OldValue.String)
+	}
+	if row.NewValue.Valid {
+		entry.NewValue = json.RawMessage(row.NewValue.String)
+	}
+	return entry
+}
@@ -0,0 +1,396 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/Dass33/administratum/backend/internal/database"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+const (
+	ImportModeAppend  = "append"
+	ImportModeReplace = "replace"
+)
+
+const maxImportSize = 10 << 20
+
+type ImportCellError struct {
+	Column string `json:"column"`
+	Value  string `json:"value"`
+	Error  string `json:"error"`
+}
+
+type ImportRowReport struct {
+	// Row is the line of the record in the file, the header is line 1.
+	Row    int               `json:"row"`
+	Errors []ImportCellError `json:"errors"`
+}
+
+type ImportReport struct {
+	Imported       int               `json:"imported"`
+	Skipped        int               `json:"skipped"`
+	CreatedColumns []string          `json:"created_columns"`
+	Errors         []ImportRowReport `json:"errors"`
+}
+
+// importColumn is a header of the imported file, column is nil until a
+// missing column gets created. The type header of map sheets has isType set
+// and is never stored as a column.
+type importColumn struct {
+	name   string
+	isType bool
+	column *Column
+	data   []ColumnData
+}
+
+func (cfg *apiConfig) importSheetHandler(w http.ResponseWriter, r *http.Request, userId uuid.UUID) {
+	sheetIdStr := chi.URLParam(r, "sheet_id")
+	sheetId, err := uuid.Parse(sheetIdStr)
+	if err != nil {
+		msg := fmt.Sprintf("Could not parse the sheet id from url: %s", err)
+		respondWithError(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	if !cfg.checkSheetPermission(userId, sheetId, "write", r.Context()) {
+		respondWithError(w, http.StatusForbidden, "Insufficient write permissions")
+		return
+	}
+
+	query := r.URL.Query()
+	delimiter, ok := sheetFormatDelimiter(query.Get("format"))
+	if !ok {
+		respondWithError(w, http.StatusBadRequest, "Invalid sheet format")
+		return
+	}
+
+	mode := query.Get("mode")
+	if mode == "" {
+		mode = ImportModeAppend
+	}
+	if mode != ImportModeAppend && mode != ImportModeReplace {
+		respondWithError(w, http.StatusBadRequest, "Invalid import mode")
+		return
+	}
+	skipInvalid := query.Get("skip_invalid") == "true"
+
+	reader := csv.NewReader(http.MaxBytesReader(w, r.Body, maxImportSize))
+	reader.Comma = delimiter
+	reader.FieldsPerRecord = -1
+	if delimiter == '\t' {
+		reader.LazyQuotes = true
+	}
+	records, err := reader.ReadAll()
+	if err != nil {
+		msg := fmt.Sprintf("Could not read file: %s", err)
+		respondWithError(w, http.StatusBadRequest, msg)
+		return
+	}
+	if len(records) == 0 {
+		respondWithError(w, http.StatusBadRequest, "File does not contain a header row")
+		return
+	}
+
+	sheet, err := cfg.db.GetSheet(r.Context(), sheetId)
+	if err != nil {
+		msg := fmt.Sprintf("Could not get sheet: %s", err)
+		respondWithError(w, http.StatusInternalServerError, msg)
+		return
+	}
+
+	columns, err := cfg.GetColumns(sheetId, r.Context())
+	if err != nil {
+		msg := fmt.Sprintf("Could not get columns: %s", err)
+		respondWithError(w, http.StatusInternalServerError, msg)
+		return
+	}
+
+	enums, err := cfg.getEnumsForBranch(sheet.BranchID, r.Context())
+	if err != nil {
+		msg := fmt.Sprintf("Could not get enums for branch: %s", err)
+		respondWithError(w, http.StatusInternalServerError, msg)
+		return
+	}
+
+	isMap := sheet.Type == SheetTypeMap
+	importColumns, typeIdx, err := mapImportHeader(records[0], columns, isMap)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var rowStart int64 = 0
+	if mode == ImportModeAppend {
+		rowStart = sheetRowCount(columns)
+	}
+
+	report := validateImportRows(records[1:], 2, importColumns, typeIdx, columns, isMap, getEnumVals(enums), rowStart)
+	if len(report.Errors) > 0 && !skipInvalid {
+		respondWithJSON(w, http.StatusUnprocessableEntity, report)
+		return
+	}
+
+	report.CreatedColumns, err = cfg.importSheetWithTransaction(r.Context(), userId, sheet.BranchID, sheetId, mode, importColumns)
+	if err != nil {
+		msg := fmt.Sprintf("Could not import sheet: %s", err)
+		respondWithError(w, http.StatusInternalServerError, msg)
+		return
+	}
+
+	cfg.invalidateSheetJson(sheetId, r.Context())
+	respondWithJSON(w, http.StatusOK, report)
+}
+
+// mapImportHeader matches the header names to the columns of the sheet. For
+// map sheets the optional type header is returned as typeIdx, -1 otherwise.
+func mapImportHeader(header []string, columns []Column, isMap bool) ([]importColumn, int, error) {
+	if len(header) > 0 {
+		header[0] = strings.TrimPrefix(header[0], "\ufeff")
+	}
+
+	typeIdx := -1
+	importColumns := make([]importColumn, len(header))
+	for i := range header {
+		name := strings.TrimSpace(header[i])
+		if name == "" {
+			return nil, -1, fmt.Errorf("Header of column %d is empty", i+1)
+		}
+		if slices.ContainsFunc(importColumns[:i], func(col importColumn) bool { return col.name == name }) {
+			return nil, -1, fmt.Errorf("Header %s is used more than once", name)
+		}
+		importColumns[i].name = name
+
+		colIdx := slices.IndexFunc(columns, func(col Column) bool { return col.Name == name })
+		if colIdx != -1 {
+			importColumns[i].column = &columns[colIdx]
+			continue
+		}
+		if isMap && name == mapSheetTypeHeader {
+			importColumns[i].isType = true
+			typeIdx = i
+			continue
+		}
+		if isMap {
+			return nil, -1, fmt.Errorf("Map sheets do not have a column %s", name)
+		}
+	}
+	return importColumns, typeIdx, nil
+}
+
+// validateImportRows parses every record into the column data of importColumns
+// and reports the cells that do not hold a valid value of their type or leave
+// a required column empty, firstRow is the line of the first record in the
+// file. Invalid and empty records are skipped without taking up a row index.
+func validateImportRows(records [][]string, firstRow int, importColumns []importColumn, typeIdx int, columns []Column, isMap bool, enumVals map[string][]string, rowStart int64) ImportReport {
+	report := ImportReport{
+		CreatedColumns: []string{},
+		Errors:         []ImportRowReport{},
+	}
+
+	existingTypes := make(map[string]string)
+	if isMap && len(columns) >= 2 {
+		for _, valCell := range columns[1].Data {
+			nameCell, ok := getDataAtColIdx(columns[0].Data, valCell.Idx)
+			if ok && valCell.Type.Valid {
+				existingTypes[nameCell.Value.String] = valCell.Type.String
+			}
+		}
+	}
+
+	idx := rowStart
+	for i, record := range records {
+		rowReport := ImportRowReport{Row: firstRow + i}
+		if len(record) > len(importColumns) {
+			rowReport.Errors = append(rowReport.Errors, ImportCellError{
+				Error: fmt.Sprintf("Row has %d fields but the header has %d", len(record), len(importColumns)),
+			})
+		}
+
+		cellType := sql.NullString{}
+		if isMap {
+			cellType = mapImportCellType(record, importColumns, typeIdx, columns, existingTypes)
+			if !isValidCellType(cellType.String, enumVals) {
+				rowReport.Errors = append(rowReport.Errors, ImportCellError{
+					Column: mapSheetTypeHeader,
+					Value:  cellType.String,
+					Error:  fmt.Sprintf("unsupported type: %s", cellType.String),
+				})
+			}
+		}
+
+		cells := make([]ColumnData, len(importColumns))
+		empty := true
+		for e := range importColumns {
+			if e == typeIdx || e >= len(record) || record[e] == "" {
+				continue
+			}
+			empty = false
+
+			valueType := "text"
+			if importColumns[e].column != nil {
+				valueType = importColumns[e].column.Type
+			}
+			isValueCell := isMap && len(columns) >= 2 && importColumns[e].column != nil && importColumns[e].column.ID == columns[1].ID
+			if isValueCell {
+				valueType = cellType.String
+			}
+
+			if err := validateCellValue(record[e], valueType, enumVals); err != nil {
+				rowReport.Errors = append(rowReport.Errors, ImportCellError{
+					Column: importColumns[e].name,
+					Value:  record[e],
+					Error:  err.Error(),
+				})
+				continue
+			}
+
+			cells[e] = ColumnData{
+				Idx:   idx,
+				Value: sql.NullString{String: record[e], Valid: true},
+			}
+			if isValueCell {
+				cells[e].Type = cellType
+			}
+		}
+
+		for e := range importColumns {
+			col := importColumns[e].column
+			if empty || col == nil || !col.Required || (e < len(record) && record[e] != "") {
+				continue
+			}
+			rowReport.Errors = append(rowReport.Errors, ImportCellError{
+				Column: importColumns[e].name,
+				Error:  "value is required",
+			})
+		}
+
+		if len(rowReport.Errors) > 0 {
+			report.Errors = append(report.Errors, rowReport)
+			report.Skipped++
+			continue
+		}
+		if empty {
+			report.Skipped++
+			continue
+		}
+
+		for e := range cells {
+			if cells[e].Value.Valid {
+				importColumns[e].data = append(importColumns[e].data, cells[e])
+			}
+		}
+		report.Imported++
+		idx++
+	}
+	return report
+}
+
+// mapImportCellType picks the type of a map sheet value cell from the type
+// header, the entry of the same name already in the sheet or falls back to text.
+func mapImportCellType(record []string, importColumns []importColumn, typeIdx int, columns []Column, existingTypes map[string]string) sql.NullString {
+	if typeIdx != -1 && typeIdx < len(record) && record[typeIdx] != "" {
+		return sql.NullString{String: record[typeIdx], Valid: true}
+	}
+
+	for e := range importColumns {
+		col := importColumns[e].column
+		if col == nil || col.ID != columns[0].ID || e >= len(record) {
+			continue
+		}
+		if existingType, ok := existingTypes[record[e]]; ok {
+			return sql.NullString{String: existingType, Valid: true}
+		}
+	}
+	return sql.NullString{String: "text", Valid: true}
+}
+
+func (cfg *apiConfig) importSheetWithTransaction(ctx context.Context, userId, branchId, sheetId uuid.UUID, mode string, importColumns []importColumn) ([]string, error) {
+	tx, err := cfg.rawDB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("could not begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	txQueries := cfg.db.WithTx(tx)
+
+	before, err := loadBranchSide(ctx, txQueries, branchId)
+	if err != nil {
+		return nil, err
+	}
+
+	if mode == ImportModeReplace {
+		err = txQueries.DeleteSheetData(ctx, sheetId)
+		if err != nil {
+			return nil, fmt.Errorf("could not delete sheet data: %w", err)
+		}
+		// the imported rows get new row ids instead of the ids of the
+		// rows they replace
+		err = txQueries.DeleteSheetRows(ctx, sheetId)
+		if err != nil {
+			return nil, fmt.Errorf("could not delete sheet rows: %w", err)
+		}
+	}
+
+	createdColumns, err := cfg.importColumnsInTx(ctx, tx, txQueries, sheetId, importColumns)
+	if err != nil {
+		return nil, err
+	}
+
+	err = recordSheetChanges(ctx, txQueries, userId, branchId, before, HistoryActionImport, "csv")
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, fmt.Errorf("could not commit transaction: %w", err)
+	}
+	return createdColumns, nil
+}
+
+// importColumnsInTx creates the columns that are not stored yet, either
+// without a column or with a column that has no id, and inserts the data.
+func (cfg *apiConfig) importColumnsInTx(ctx context.Context, tx *sql.Tx, txQueries *database.Queries, sheetId uuid.UUID, importColumns []importColumn) ([]string, error) {
+	createdColumns := []string{}
+	for i := range importColumns {
+		if importColumns[i].isType {
+			continue
+		}
+		if importColumns[i].column == nil {
+			importColumns[i].column = &Column{Name: importColumns[i].name, Type: "text"}
+		}
+
+		col := importColumns[i].column
+		if col.ID == uuid.Nil {
+			addColumnParams := database.AddColumnParams{
+				Name:     col.Name,
+				Type:     col.Type,
+				Required: col.Required,
+				SheetID:  sheetId,
+			}
+			newColumn, err := txQueries.AddColumn(ctx, addColumnParams)
+			if err != nil {
+				return nil, fmt.Errorf("could not add column %s: %w", col.Name, err)
+			}
+			col.ID = newColumn.ID
+			createdColumns = append(createdColumns, newColumn.Name)
+		}
+
+		err := cfg.copyColumnDataBulk(ctx, tx, importColumns[i].data, col.ID)
+		if err != nil {
+			return nil, fmt.Errorf("could not insert data for column %s: %w", col.Name, err)
+		}
+	}
+	return createdColumns, nil
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestValidateImportRows(t *testing.T) {
+	columns := []Column{
+		{ID: uuid.New(), Name: "name", Type: "text", Required: true},
+		{ID: uuid.New(), Name: "price", Type: "number"},
+		{ID: uuid.New(), Name: "rarity", Type: "rarity"},
+	}
+	importColumns := []importColumn{
+		{name: "name", column: &columns[0]},
+		{name: "price", column: &columns[1]},
+		{name: "rarity", column: &columns[2]},
+	}
+	enumVals := map[string][]string{"rarity": {"common", "rare"}}
+	records := [][]string{
+		{"sword", "10", "common"},
+		{"", "", ""},
+		{"shield", "abc", "rare"},
+		{"", "3", ""},
+		{"bow", "", "epic"},
+		{"axe", "4", "rare", "extra"},
+		{"staff", "", ""},
+	}
+
+	report := validateImportRows(records, 2, importColumns, -1, columns, false, enumVals, 5)
+
+	if report.Imported != 2 || report.Skipped != 5 {
+		t.Errorf("imported %d and skipped %d, want 2 and 5", report.Imported, report.Skipped)
+	}
+
+	wantErrors := []ImportRowReport{
+		{Row: 4, Errors: []ImportCellError{{Column: "price", Value: "abc"}}},
+		{Row: 5, Errors: []ImportCellError{{Column: "name"}}},
+		{Row: 6, Errors: []ImportCellError{{Column: "rarity", Value: "epic"}}},
+		{Row: 7, Errors: []ImportCellError{{}}},
+	}
+	if len(report.Errors) != len(wantErrors) {
+		t.Fatalf("got %d rows with errors, want %d: %+v", len(report.Errors), len(wantErrors), report.Errors)
+	}
+	for i, want := range wantErrors {
+		got := report.Errors[i]
+		if got.Row != want.Row || len(got.Errors) != len(want.Errors) {
+			t.Errorf("row report %d = %+v, want row %d with %d errors", i, got, want.Row, len(want.Errors))
+			continue
+		}
+		for e := range want.Errors {
+			if got.Errors[e].Column != want.Errors[e].Column || got.Errors[e].Value != want.Errors[e].Value {
+				t.Errorf("error %d of row %d = %+v, want %+v", e, got.Row, got.Errors[e], want.Errors[e])
+			}
+		}
+	}
+
+	// valid rows take up consecutive row indexes from rowStart
+	names := importColumns[0].data
+	if len(names) != 2 || names[0].Idx != 5 || names[0].Value.String != "sword" || names[1].Idx != 6 || names[1].Value.String != "staff" {
+		t.Errorf("name cells = %+v, want sword at 5 and staff at 6", names)
+	}
+	if prices := importColumns[1].data; len(prices) != 1 || prices[0].Idx != 5 {
+		t.Errorf("price cells = %+v, want only the price of row 5", prices)
+	}
+}
@@ -0,0 +1,390 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/Dass33/administratum/backend/internal/database"
+	"github.com/Dass33/administratum/backend/internal/xlsx"
+	"github.com/google/uuid"
+)
+
+const maxWorkbookSize = 32 << 20
+
+type WorkbookSheetReport struct {
+	Sheet string `json:"sheet"`
+	Error string `json:"error,omitempty"`
+	ImportReport
+}
+
+type WorkbookImportReport struct {
+	BranchID uuid.UUID             `json:"branch_id"`
+	Sheets   []WorkbookSheetReport `json:"sheets"`
+}
+
+// workbookSheet is a worksheet read back into the names, types and cell
+// values the sheet import works with.
+type workbookSheet struct {
+	name      string
+	sheetType string
+	header    []string
+	// types holds the types row, nil when the worksheet does not have one.
+	types    []string
+	records  [][]string
+	firstRow int
+}
+
+// importWorkbookHandler imports a workbook into the branch given by
+// branch_id, or into a new branch of table_id called name. Worksheets replace
+// the sheets of the same name, the other sheets of the branch are kept.
+func (cfg *apiConfig) importWorkbookHandler(w http.ResponseWriter, r *http.Request, userId uuid.UUID) {
+	query := r.URL.Query()
+
+	branchId := uuid.NullUUID{}
+	tableId := uuid.UUID{}
+	if query.Has("branch_id") {
+		id, err := uuid.Parse(query.Get("branch_id"))
+		if err != nil {
+			msg := fmt.Sprintf("Could not parse the branch id: %s", err)
+			respondWithError(w, http.StatusBadRequest, msg)
+			return
+		}
+		if !cfg.checkBranchPermission(userId, id, "write", r.Context()) {
+			respondWithError(w, http.StatusForbidden, "Insufficient write permissions")
+			return
+		}
+		branchId = uuid.NullUUID{UUID: id, Valid: true}
+	} else {
+		id, err := uuid.Parse(query.Get("table_id"))
+		if err != nil {
+			msg := fmt.Sprintf("Could not parse the table id: %s", err)
+			respondWithError(w, http.StatusBadRequest, msg)
+			return
+		}
+		if query.Get("name") == "" {
+			respondWithError(w, http.StatusBadRequest, "New branch needs a name")
+			return
+		}
+		if !cfg.checkTablePermission(userId, id, "write", r.Context()) {
+			respondWithError(w, http.StatusForbidden, "Insufficient write permissions")
+			return
+		}
+		tableId = id
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxWorkbookSize))
+	if err != nil {
+		msg := fmt.Sprintf("Could not read file: %s", err)
+		respondWithError(w, http.StatusBadRequest, msg)
+		return
+	}
+	workbook, err := xlsx.Read(bytes.NewReader(body), int64(len(body)))
+	if err != nil {
+		msg := fmt.Sprintf("Could not read workbook: %s", err)
+		respondWithError(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	enums := []Enum{}
+	if branchId.Valid {
+		enums, err = cfg.getEnumsForBranch(branchId.UUID, r.Context())
+		if err != nil {
+			msg := fmt.Sprintf("Could not get enums for branch: %s", err)
+			respondWithError(w, http.StatusInternalServerError, msg)
+			return
+		}
+	}
+	enumVals := getEnumVals(enums)
+
+	sheets, err := parseWorkbookSheets(workbook, enumVals)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	tx, err := cfg.rawDB.BeginTx(r.Context(), nil)
+	if err != nil {
+		msg := fmt.Sprintf("Could not begin transaction: %s", err)
+		respondWithError(w, http.StatusInternalServerError, msg)
+		return
+	}
+	defer tx.Rollback()
+
+	txQueries := cfg.db.WithTx(tx)
+
+	status := http.StatusOK
+	if !branchId.Valid {
+		createBranchParams := database.CreateBranchParams{
+			Name:        query.Get("name"),
+			IsProtected: query.Get("is_protected") == "true",
+			TableID:     tableId,
+		}
+		branch, err := txQueries.CreateBranch(r.Context(), createBranchParams)
+		if err != nil {
+			msg := fmt.Sprintf("Could not create branch: %s", err)
+			respondWithError(w, http.StatusInternalServerError, msg)
+			return
+		}
+		branchId = uuid.NullUUID{UUID: branch.ID, Valid: true}
+		status = http.StatusCreated
+	}
+
+	before, err := loadBranchSide(r.Context(), txQueries, branchId.UUID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	sheetReports, valid, err := cfg.importWorkbookInTx(r.Context(), tx, txQueries, branchId.UUID, sheets, enumVals)
+	if err != nil {
+		msg := fmt.Sprintf("Could not import workbook: %s", err)
+		respondWithError(w, http.StatusInternalServerError, msg)
+		return
+	}
+	report := WorkbookImportReport{
+		BranchID: branchId.UUID,
+		Sheets:   sheetReports,
+	}
+	if !valid {
+		respondWithJSON(w, http.StatusUnprocessableEntity, report)
+		return
+	}
+
+	err = recordSheetChanges(r.Context(), txQueries, userId, branchId.UUID, before, HistoryActionImport, "xlsx")
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		msg := fmt.Sprintf("Could not commit transaction: %s", err)
+		respondWithError(w, http.StatusInternalServerError, msg)
+		return
+	}
+
+	cfg.invalidateBranchJson(branchId.UUID)
+	respondWithJSON(w, status, report)
+}
+
+// importWorkbookInTx replaces or creates a sheet for every worksheet. All
+// worksheets are validated even after the first invalid one so the report is
+// complete, but nothing is written once one of them failed.
+func (cfg *apiConfig) importWorkbookInTx(ctx context.Context, tx *sql.Tx, txQueries *database.Queries, branchId uuid.UUID, sheets []workbookSheet, enumVals map[string][]string) ([]WorkbookSheetReport, bool, error) {
+	existingSheets, err := txQueries.GetSheetsFromBranch(ctx, branchId)
+	if err != nil {
+		return nil, false, fmt.Errorf("could not get sheets from branch: %w", err)
+	}
+
+	reports := make([]WorkbookSheetReport, 0, len(sheets))
+	valid := true
+	for _, ws := range sheets {
+		report := WorkbookSheetReport{
+			Sheet: ws.name,
+			ImportReport: ImportReport{
+				CreatedColumns: []string{},
+				Errors:         []ImportRowReport{},
+			},
+		}
+
+		sheetId := uuid.UUID{}
+		existingIdx := slices.IndexFunc(existingSheets, func(sheet database.Sheet) bool { return sheet.Name == ws.name })
+		if existingIdx != -1 {
+			if existingSheets[existingIdx].Type != ws.sheetType {
+				report.Error = fmt.Sprintf("Sheet %s is a %s sheet", ws.name, existingSheets[existingIdx].Type)
+				reports = append(reports, report)
+				valid = false
+				continue
+			}
+			sheetId = existingSheets[existingIdx].ID
+		} else if ws.sheetType == SheetTypeMap {
+			sheetId, err = cfg.createMapSheetWithTx(txQueries, ctx, ws.name, branchId)
+			if err != nil {
+				return nil, false, fmt.Errorf("could not create map sheet %s: %w", ws.name, err)
+			}
+		} else {
+			createSheetParams := database.CreateSheetParams{
+				Name:     ws.name,
+				Type:     ws.sheetType,
+				BranchID: branchId,
+			}
+			sheet, err := txQueries.CreateSheet(ctx, createSheetParams)
+			if err != nil {
+				return nil, false, fmt.Errorf("could not create sheet %s: %w", ws.name, err)
+			}
+			sheetId = sheet.ID
+		}
+
+		columns, err := cfg.GetColumnsWithTx(txQueries, sheetId, ctx)
+		if err != nil {
+			return nil, false, fmt.Errorf("could not get columns of sheet %s: %w", ws.name, err)
+		}
+
+		isMap := ws.sheetType == SheetTypeMap
+		importColumns, typeIdx, err := mapImportHeader(ws.header, columns, isMap)
+		if err == nil {
+			err = applyWorkbookTypes(importColumns, ws.types, enumVals)
+		}
+		if err != nil {
+			report.Error = err.Error()
+			reports = append(reports, report)
+			valid = false
+			continue
+		}
+
+		report.ImportReport = validateImportRows(ws.records, ws.firstRow, importColumns, typeIdx, columns, isMap, enumVals, 0)
+		if len(report.Errors) > 0 {
+			valid = false
+		}
+		if !valid {
+			reports = append(reports, report)
+			continue
+		}
+
+		err = txQueries.DeleteSheetData(ctx, sheetId)
+		if err != nil {
+			return nil, false, fmt.Errorf("could not delete data of sheet %s: %w", ws.name, err)
+		}
+		err = txQueries.DeleteSheetRows(ctx, sheetId)
+		if err != nil {
+			return nil, false, fmt.Errorf("could not delete rows of sheet %s: %w", ws.name, err)
+		}
+		report.CreatedColumns, err = cfg.importColumnsInTx(ctx, tx, txQueries, sheetId, importColumns)
+		if err != nil {
+			return nil, false, err
+		}
+		reports = append(reports, report)
+	}
+	return reports, valid, nil
+}
+
+// applyWorkbookTypes gives the columns missing from the sheet the type and
+// required flag from the types row, text when there is none.
+func applyWorkbookTypes(importColumns []importColumn, types []string, enumVals map[string][]string) error {
+	for i := range importColumns {
+		if importColumns[i].column != nil || importColumns[i].isType {
+			continue
+		}
+
+		col := &Column{Name: importColumns[i].name, Type: "text"}
+		if i < len(types) && types[i] != "" {
+			col.Type, col.Required = parseWorkbookType(types[i])
+			if !isValidCellType(col.Type, enumVals) {
+				return fmt.Errorf("Column %s has an unsupported type: %s", col.Name, col.Type)
+			}
+		}
+		importColumns[i].column = col
+	}
+	return nil
+}
+
+func parseWorkbookType(val string) (string, bool) {
+	colType, required := strings.CutSuffix(strings.TrimSpace(val), requiredTypeSuffix)
+	return strings.TrimSpace(colType), required
+}
+
+// parseWorkbookSheets reads the sheet names and types from the worksheet
+// names and the values of enum worksheets into enumVals, so the other
+// worksheets can be validated against the imported enums.
+func parseWorkbookSheets(workbook []xlsx.Sheet, enumVals map[string][]string) ([]workbookSheet, error) {
+	enumNames := make(map[string]bool, len(enumVals))
+	for name := range enumVals {
+		enumNames[name] = true
+	}
+	for i := range workbook {
+		if name, sheetType := parseWorkbookSheetName(workbook[i].Name); sheetType == SheetTypeEnums {
+			enumNames[name] = true
+		}
+	}
+
+	sheets := make([]workbookSheet, 0, len(workbook))
+	for i := range workbook {
+		if len(workbook[i].Rows) == 0 {
+			continue
+		}
+
+		name, sheetType := parseWorkbookSheetName(workbook[i].Name)
+		if slices.ContainsFunc(sheets, func(sheet workbookSheet) bool { return sheet.name == name }) {
+			return nil, fmt.Errorf("Workbook contains the sheet %s more than once", name)
+		}
+
+		rows := workbook[i].Rows
+		ws := workbookSheet{
+			name:      name,
+			sheetType: sheetType,
+			header:    workbookRecord(rows[0]),
+			firstRow:  2,
+		}
+
+		dataRows := rows[1:]
+		if len(rows) > 1 && isWorkbookTypesRow(rows[1], slices.Contains(workbook[i].HiddenRows, 1), enumNames) {
+			ws.types = workbookRecord(rows[1])
+			ws.firstRow = 3
+			dataRows = rows[2:]
+		}
+		for _, row := range dataRows {
+			ws.records = append(ws.records, workbookRecord(row))
+		}
+
+		if sheetType == SheetTypeEnums {
+			vals := []string{}
+			for _, record := range ws.records {
+				if len(record) > 0 && record[0] != "" {
+					vals = append(vals, record[0])
+				}
+			}
+			enumVals[name] = vals
+		}
+		sheets = append(sheets, ws)
+	}
+	return sheets, nil
+}
+
+// isWorkbookTypesRow reports whether the second row holds column types, it
+// is either hidden as written by the export or every value in it is a type.
+func isWorkbookTypesRow(row []xlsx.Cell, hidden bool, enumNames map[string]bool) bool {
+	if hidden {
+		return true
+	}
+
+	found := false
+	for _, cell := range row {
+		if cell.Value == "" {
+			continue
+		}
+		colType, _ := parseWorkbookType(cell.Value)
+		if cell.Kind != xlsx.KindString || (colType != "any" && !enumNames[colType] && !isValidCellType(colType, nil)) {
+			return false
+		}
+		found = true
+	}
+	return found
+}
+
+// workbookRecord turns a row into cell values as the sheet stores them, without
+// the trailing empty cells. Numbers are printed without the float noise
+// spreadsheets tend to add.
+func workbookRecord(row []xlsx.Cell) []string {
+	for len(row) > 0 && strings.TrimSpace(row[len(row)-1].Value) == "" {
+		row = row[:len(row)-1]
+	}
+
+	record := make([]string, len(row))
+	for i, cell := range row {
+		record[i] = cell.Value
+		if cell.Kind != xlsx.KindNumber {
+			continue
+		}
+		if num, err := strconv.ParseFloat(cell.Value, 64); err == nil {
+			record[i] = strconv.FormatFloat(num, 'f', -1, 64)
+		}
+	}
+	return record
+}
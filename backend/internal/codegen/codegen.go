@@ -0,0 +1,208 @@
+// Package codegen emits typed models and a loader for the JSON a branch
+// exports, so game code does not have to mirror every sheet by hand.
+//
+// The generators only depend on Model, which LoadModel builds from the
+// database and a command line tool can build from any other source.
+package codegen
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+const (
+	LangGo         = "go"
+	LangTypeScript = "ts"
+	LangCSharp     = "cs"
+)
+
+const (
+	ShapeArray  = "array"
+	ShapeObject = "object"
+)
+
+const (
+	SheetTypeMap  = "map"
+	SheetTypeList = "list"
+)
+
+type Model struct {
+	// Shape is the export layout, ShapeArray or ShapeObject.
+	Shape  string  `json:"shape"`
+	Sheets []Sheet `json:"sheets"`
+	Enums  []Enum  `json:"enums"`
+}
+
+type Sheet struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+	// KeyColumn keys the rows of a list sheet in the object shape.
+	KeyColumn string `json:"key_column,omitempty"`
+	// Columns describe a row of a list sheet, for map sheets every
+	// exported entry is one field typed by the type of its value cell.
+	Columns []Column `json:"columns"`
+}
+
+type Column struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Required bool   `json:"required"`
+}
+
+type Enum struct {
+	Name   string   `json:"name"`
+	Values []string `json:"values"`
+}
+
+type Options struct {
+	// Namespace is the Go package name or the C# namespace of the output.
+	Namespace string
+}
+
+func Generate(lang string, model Model, opts Options) (string, error) {
+	if model.Shape != ShapeArray && model.Shape != ShapeObject {
+		return "", fmt.Errorf("unsupported shape: %s", model.Shape)
+	}
+
+	switch lang {
+	case LangGo:
+		return generateGo(model, opts)
+	case LangTypeScript:
+		return generateTypeScript(model), nil
+	case LangCSharp:
+		return generateCSharp(model, opts), nil
+	}
+	return "", fmt.Errorf("unsupported language: %s", lang)
+}
+
+type valueKind int
+
+const (
+	kindAny valueKind = iota
+	kindString
+	kindNumber
+	kindBool
+	kindArray
+	kindEnum
+)
+
+func (m *Model) kindOf(valueType string) valueKind {
+	for i := range m.Enums {
+		if m.Enums[i].Name == valueType {
+			return kindEnum
+		}
+	}
+
+	switch strings.ToLower(valueType) {
+	case "text", "string":
+		return kindString
+	case "number", "int", "float":
+		return kindNumber
+	case "bool", "boolean":
+		return kindBool
+	case "array":
+		return kindArray
+	}
+	return kindAny
+}
+
+// values returns the distinct non-empty values of the enum in their order.
+func (e *Enum) values() []string {
+	seen := make(map[string]bool, len(e.Values))
+	vals := make([]string, 0, len(e.Values))
+	for _, val := range e.Values {
+		if val == "" || seen[val] {
+			continue
+		}
+		seen[val] = true
+		vals = append(vals, val)
+	}
+	return vals
+}
+
+// keyed reports whether the sheet is exported as an object keyed by its key column.
+func (m *Model) keyed(sheet *Sheet) bool {
+	return m.Shape == ShapeObject && sheet.Type != SheetTypeMap && sheet.KeyColumn != ""
+}
+
+// typeNames assigns unique type identifiers to enums and sheets.
+type typeNames struct {
+	used   map[string]bool
+	enums  map[string]string
+	sheets []string
+}
+
+func newTypeNames(model *Model, reserved ...string) *typeNames {
+	names := &typeNames{
+		used:  make(map[string]bool),
+		enums: make(map[string]string),
+	}
+	for _, name := range reserved {
+		names.used[name] = true
+	}
+	for i := range model.Enums {
+		names.enums[model.Enums[i].Name] = names.unique(pascalCase(model.Enums[i].Name))
+	}
+	for i := range model.Sheets {
+		suffix := "Row"
+		if model.Sheets[i].Type == SheetTypeMap {
+			suffix = "Sheet"
+		}
+		names.sheets = append(names.sheets, names.unique(pascalCase(model.Sheets[i].Name)+suffix))
+	}
+	return names
+}
+
+func (n *typeNames) unique(name string) string {
+	return uniqueName(n.used, name)
+}
+
+func uniqueName(used map[string]bool, name string) string {
+	candidate := name
+	for i := 2; used[candidate]; i++ {
+		candidate = name + strconv.Itoa(i)
+	}
+	used[candidate] = true
+	return candidate
+}
+
+// pascalCase turns an arbitrary sheet, column or enum value name into an
+// exported identifier, "max hp" becomes "MaxHp" and "2d" becomes "X2d".
+func pascalCase(name string) string {
+	var b strings.Builder
+	upperNext := true
+	for _, r := range name {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upperNext = true
+			continue
+		}
+		if upperNext {
+			r = unicode.ToUpper(r)
+			upperNext = false
+		}
+		b.WriteRune(r)
+	}
+
+	ident := b.String()
+	if ident == "" {
+		return "X"
+	}
+	if unicode.IsDigit([]rune(ident)[0]) {
+		return "X" + ident
+	}
+	return ident
+}
+
+func isIdentifier(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, r := range name {
+		if r != '_' && r != '$' && !unicode.IsLetter(r) && (i == 0 || !unicode.IsDigit(r)) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,89 @@
+package codegen_test
+
+import (
+	"go/parser"
+	"go/token"
+	"strings"
+	"testing"
+
+	"github.com/Dass33/administratum/backend/internal/codegen"
+)
+
+func testModel(shape string) codegen.Model {
+	return codegen.Model{
+		Shape: shape,
+		Enums: []codegen.Enum{
+			{Name: "rarity", Values: []string{"common", "rare", "rare", "", "2x"}},
+		},
+		Sheets: []codegen.Sheet{
+			{
+				Name: "settings",
+				Type: codegen.SheetTypeMap,
+				Columns: []codegen.Column{
+					{Name: "max hp", Type: "number", Required: true},
+					{Name: "debug", Type: "bool", Required: true},
+				},
+			},
+			{
+				Name:      "items",
+				Type:      codegen.SheetTypeList,
+				KeyColumn: "id",
+				Columns: []codegen.Column{
+					{Name: "id", Type: "text", Required: true},
+					{Name: "rarity", Type: "rarity"},
+					{Name: "tags", Type: "array"},
+				},
+			},
+		},
+	}
+}
+
+func TestGenerateGo(t *testing.T) {
+	for _, shape := range []string{codegen.ShapeArray, codegen.ShapeObject} {
+		code, err := codegen.Generate(codegen.LangGo, testModel(shape), codegen.Options{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := parser.ParseFile(token.NewFileSet(), "config.go", code, 0); err != nil {
+			t.Fatalf("generated go for %s shape does not parse: %s\n%s", shape, err, code)
+		}
+		for _, want := range []string{"type Rarity string", "RarityX2x", "MaxHp float64", "Rarity *Rarity", "func LoadConfig("} {
+			if !strings.Contains(code, want) {
+				t.Errorf("generated go for %s shape is missing %q", shape, want)
+			}
+		}
+		if strings.Count(code, "Rarity = \"rare\"") != 1 {
+			t.Errorf("duplicate enum values were not dropped")
+		}
+	}
+}
+
+func TestGenerateTypeScript(t *testing.T) {
+	code, err := codegen.Generate(codegen.LangTypeScript, testModel(codegen.ShapeObject), codegen.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"export enum Rarity", "\"max hp\": number;", "rarity?: Rarity;", "items: Record<string, ItemsRow>;"} {
+		if !strings.Contains(code, want) {
+			t.Errorf("generated typescript is missing %q", want)
+		}
+	}
+}
+
+func TestGenerateCSharp(t *testing.T) {
+	code, err := codegen.Generate(codegen.LangCSharp, testModel(codegen.ShapeArray), codegen.Options{Namespace: "game.config"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"namespace Game.Config", "public enum Rarity", "class RarityConverter", "public List<ItemsRow> Items", "public static Config Load(string json)"} {
+		if !strings.Contains(code, want) {
+			t.Errorf("generated c# is missing %q", want)
+		}
+	}
+}
+
+func TestGenerateRejectsUnknownLanguage(t *testing.T) {
+	if _, err := codegen.Generate("cobol", testModel(codegen.ShapeArray), codegen.Options{}); err == nil {
+		t.Fatal("expected an error for an unsupported language")
+	}
+}
@@ -0,0 +1,148 @@
+package codegen
+
+import (
+	"fmt"
+	"strings"
+)
+
+func generateCSharp(model Model, opts Options) string {
+	namespace := opts.Namespace
+	if namespace == "" {
+		namespace = "Administratum.Config"
+	}
+	parts := strings.Split(namespace, ".")
+	for i := range parts {
+		parts[i] = pascalCase(parts[i])
+	}
+
+	names := newTypeNames(&model, "Config")
+	for _, name := range names.enums {
+		names.used[name+"Converter"] = true
+	}
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "// <auto-generated>\n// Code generated by administratum. DO NOT EDIT.\n// </auto-generated>\n\n")
+	fmt.Fprintf(&b, "#nullable enable\n\n")
+	fmt.Fprintf(&b, "using System;\nusing System.Collections.Generic;\nusing System.Text.Json;\nusing System.Text.Json.Serialization;\n\n")
+	fmt.Fprintf(&b, "namespace %s\n{\n", strings.Join(parts, "."))
+
+	for i := range model.Enums {
+		writeCSharpEnum(&b, &model.Enums[i], names.enums[model.Enums[i].Name])
+	}
+
+	for i := range model.Sheets {
+		sheet := &model.Sheets[i]
+		fmt.Fprintf(&b, "    public sealed class %s\n    {\n", names.sheets[i])
+		used := map[string]bool{names.sheets[i]: true}
+		for _, col := range sheet.Columns {
+			propType := csType(&model, names, col.Type)
+			if !col.Required && sheet.Type != SheetTypeMap {
+				propType += "?"
+			}
+			fmt.Fprintf(&b, "        [JsonPropertyName(%s)]\n", tsString(col.Name))
+			fmt.Fprintf(&b, "        public %s %s { get; set; }%s\n", propType, uniqueName(used, pascalCase(col.Name)), csInitializer(propType))
+		}
+		fmt.Fprintf(&b, "    }\n\n")
+	}
+
+	props := make([]string, len(model.Sheets))
+	used := map[string]bool{"Config": true, "Load": true}
+	fmt.Fprintf(&b, "    public sealed class Config\n    {\n")
+	for i := range model.Sheets {
+		props[i] = uniqueName(used, pascalCase(model.Sheets[i].Name))
+		propType := csSheetType(&model, names, i)
+		if model.Shape == ShapeObject {
+			fmt.Fprintf(&b, "        [JsonPropertyName(%s)]\n", tsString(model.Sheets[i].Name))
+		}
+		fmt.Fprintf(&b, "        public %s %s { get; set; } = new();\n", propType, props[i])
+	}
+
+	fmt.Fprintf(&b, "\n        public static Config Load(string json)\n        {\n")
+	if model.Shape == ShapeObject {
+		fmt.Fprintf(&b, "            return JsonSerializer.Deserialize<Config>(json)\n")
+		fmt.Fprintf(&b, "                ?? throw new JsonException(\"expected an object of sheets\");\n")
+	} else {
+		fmt.Fprintf(&b, "            using var document = JsonDocument.Parse(json);\n")
+		fmt.Fprintf(&b, "            var sheets = document.RootElement;\n")
+		fmt.Fprintf(&b, "            if (sheets.ValueKind != JsonValueKind.Array || sheets.GetArrayLength() != %d)\n", len(model.Sheets))
+		fmt.Fprintf(&b, "            {\n                throw new JsonException(\"expected an array of %d sheets\");\n            }\n\n", len(model.Sheets))
+		fmt.Fprintf(&b, "            return new Config\n            {\n")
+		for i := range model.Sheets {
+			fmt.Fprintf(&b, "                %s = sheets[%d].Deserialize<%s>()!,\n", props[i], i, csSheetType(&model, names, i))
+		}
+		fmt.Fprintf(&b, "            };\n")
+	}
+	fmt.Fprintf(&b, "        }\n    }\n}\n")
+	return b.String()
+}
+
+func writeCSharpEnum(b *strings.Builder, enum *Enum, typeName string) {
+	vals := enum.values()
+	members := make([]string, len(vals))
+	used := make(map[string]bool)
+	for i, val := range vals {
+		members[i] = uniqueName(used, pascalCase(val))
+	}
+
+	fmt.Fprintf(b, "    [JsonConverter(typeof(%sConverter))]\n", typeName)
+	fmt.Fprintf(b, "    public enum %s\n    {\n", typeName)
+	for _, member := range members {
+		fmt.Fprintf(b, "        %s,\n", member)
+	}
+	fmt.Fprintf(b, "    }\n\n")
+
+	fmt.Fprintf(b, "    public sealed class %sConverter : JsonConverter<%s>\n    {\n", typeName, typeName)
+	fmt.Fprintf(b, "        public override %s Read(ref Utf8JsonReader reader, Type typeToConvert, JsonSerializerOptions options)\n        {\n", typeName)
+	fmt.Fprintf(b, "            var value = reader.GetString();\n")
+	fmt.Fprintf(b, "            return value switch\n            {\n")
+	for i, val := range vals {
+		fmt.Fprintf(b, "                %s => %s.%s,\n", tsString(val), typeName, members[i])
+	}
+	fmt.Fprintf(b, "                _ => throw new JsonException($\"invalid %s value: {value}\"),\n", typeName)
+	fmt.Fprintf(b, "            };\n        }\n\n")
+	fmt.Fprintf(b, "        public override void Write(Utf8JsonWriter writer, %s value, JsonSerializerOptions options)\n        {\n", typeName)
+	fmt.Fprintf(b, "            writer.WriteStringValue(value switch\n            {\n")
+	for i, val := range vals {
+		fmt.Fprintf(b, "                %s.%s => %s,\n", typeName, members[i], tsString(val))
+	}
+	fmt.Fprintf(b, "                _ => throw new JsonException($\"invalid %s value: {value}\"),\n", typeName)
+	fmt.Fprintf(b, "            });\n        }\n    }\n\n")
+}
+
+func csSheetType(model *Model, names *typeNames, i int) string {
+	sheet := &model.Sheets[i]
+	if model.keyed(sheet) {
+		return "Dictionary<string, " + names.sheets[i] + ">"
+	}
+	if sheet.Type != SheetTypeMap {
+		return "List<" + names.sheets[i] + ">"
+	}
+	return names.sheets[i]
+}
+
+func csType(model *Model, names *typeNames, valueType string) string {
+	switch model.kindOf(valueType) {
+	case kindString:
+		return "string"
+	case kindNumber:
+		return "double"
+	case kindBool:
+		return "bool"
+	case kindArray:
+		return "List<JsonElement>"
+	case kindEnum:
+		return names.enums[valueType]
+	}
+	return "JsonElement"
+}
+
+// csInitializer keeps non-nullable reference properties from starting as null.
+func csInitializer(propType string) string {
+	switch {
+	case propType == "string":
+		return " = \"\";"
+	case strings.HasPrefix(propType, "List<") && !strings.HasSuffix(propType, "?"):
+		return " = new();"
+	}
+	return ""
+}
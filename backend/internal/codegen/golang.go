@@ -0,0 +1,148 @@
+package codegen
+
+import (
+	"fmt"
+	"go/format"
+	"go/token"
+	"strconv"
+	"strings"
+)
+
+func generateGo(model Model, opts Options) (string, error) {
+	pkg := opts.Namespace
+	if pkg == "" {
+		pkg = "config"
+	}
+	pkg = strings.ToLower(pascalCase(pkg))
+	if token.IsKeyword(pkg) {
+		pkg += "s"
+	}
+
+	names := newTypeNames(&model, "Config", "LoadConfig")
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "// Code generated by administratum. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&b, "package %s\n\n", pkg)
+	if model.Shape == ShapeArray || len(model.Enums) > 0 {
+		fmt.Fprintf(&b, "import (\n\t\"encoding/json\"\n\t\"fmt\"\n)\n\n")
+	} else {
+		fmt.Fprintf(&b, "import \"encoding/json\"\n\n")
+	}
+
+	for i := range model.Enums {
+		writeGoEnum(&b, &model.Enums[i], names.enums[model.Enums[i].Name])
+	}
+
+	for i := range model.Sheets {
+		sheet := &model.Sheets[i]
+		fmt.Fprintf(&b, "// %s is generated from the %s sheet %s.\n", names.sheets[i], sheet.Type, strconv.Quote(sheet.Name))
+		fmt.Fprintf(&b, "type %s struct {\n", names.sheets[i])
+		used := make(map[string]bool)
+		for _, col := range sheet.Columns {
+			fieldType := goType(&model, names, col.Type)
+			tag := col.Name
+			if !col.Required && sheet.Type != SheetTypeMap {
+				if fieldType != "any" && !strings.HasPrefix(fieldType, "[]") {
+					fieldType = "*" + fieldType
+				}
+				tag += ",omitempty"
+			}
+			fmt.Fprintf(&b, "\t%s %s `json:%s`\n", uniqueName(used, pascalCase(col.Name)), fieldType, strconv.Quote(tag))
+		}
+		fmt.Fprintf(&b, "}\n\n")
+	}
+
+	fields := make([]string, len(model.Sheets))
+	used := make(map[string]bool)
+	fmt.Fprintf(&b, "type Config struct {\n")
+	for i := range model.Sheets {
+		sheet := &model.Sheets[i]
+		fields[i] = uniqueName(used, pascalCase(sheet.Name))
+		fieldType := names.sheets[i]
+		if model.keyed(sheet) {
+			fieldType = "map[string]" + fieldType
+		} else if sheet.Type != SheetTypeMap {
+			fieldType = "[]" + fieldType
+		}
+		if model.Shape == ShapeObject {
+			fmt.Fprintf(&b, "\t%s %s `json:%s`\n", fields[i], fieldType, strconv.Quote(sheet.Name))
+		} else {
+			fmt.Fprintf(&b, "\t%s %s\n", fields[i], fieldType)
+		}
+	}
+	fmt.Fprintf(&b, "}\n\n")
+
+	if model.Shape == ShapeArray {
+		fmt.Fprintf(&b, "// UnmarshalJSON decodes the positional array of sheets.\n")
+		fmt.Fprintf(&b, "func (c *Config) UnmarshalJSON(data []byte) error {\n")
+		fmt.Fprintf(&b, "\tvar sheets []json.RawMessage\n")
+		fmt.Fprintf(&b, "\tif err := json.Unmarshal(data, &sheets); err != nil {\n\t\treturn err\n\t}\n")
+		fmt.Fprintf(&b, "\tif len(sheets) != %d {\n", len(model.Sheets))
+		fmt.Fprintf(&b, "\t\treturn fmt.Errorf(\"expected %d sheets, got %%d\", len(sheets))\n\t}\n", len(model.Sheets))
+		for i := range model.Sheets {
+			fmt.Fprintf(&b, "\tif err := json.Unmarshal(sheets[%d], &c.%s); err != nil {\n", i, fields[i])
+			fmt.Fprintf(&b, "\t\treturn fmt.Errorf(\"sheet %%s: %%w\", %s, err)\n\t}\n", strconv.Quote(model.Sheets[i].Name))
+		}
+		fmt.Fprintf(&b, "\treturn nil\n}\n\n")
+	}
+
+	fmt.Fprintf(&b, "func LoadConfig(data []byte) (*Config, error) {\n")
+	fmt.Fprintf(&b, "\tconfig := &Config{}\n")
+	fmt.Fprintf(&b, "\tif err := json.Unmarshal(data, config); err != nil {\n\t\treturn nil, err\n\t}\n")
+	fmt.Fprintf(&b, "\treturn config, nil\n}\n")
+
+	src, err := format.Source([]byte(b.String()))
+	if err != nil {
+		return "", fmt.Errorf("could not format generated go code: %w", err)
+	}
+	return string(src), nil
+}
+
+func writeGoEnum(b *strings.Builder, enum *Enum, typeName string) {
+	fmt.Fprintf(b, "// %s is generated from the enum sheet %s.\n", typeName, strconv.Quote(enum.Name))
+	fmt.Fprintf(b, "type %s string\n\n", typeName)
+
+	vals := enum.values()
+	if len(vals) > 0 {
+		used := make(map[string]bool)
+		fmt.Fprintf(b, "const (\n")
+		for _, val := range vals {
+			fmt.Fprintf(b, "\t%s %s = %s\n", uniqueName(used, typeName+pascalCase(val)), typeName, strconv.Quote(val))
+		}
+		fmt.Fprintf(b, ")\n\n")
+	}
+
+	fmt.Fprintf(b, "func (e %s) Valid() bool {\n", typeName)
+	fmt.Fprintf(b, "\tswitch e {\n")
+	if len(vals) > 0 {
+		quoted := make([]string, 0, len(vals))
+		for _, val := range vals {
+			quoted = append(quoted, strconv.Quote(val))
+		}
+		fmt.Fprintf(b, "\tcase %s:\n\t\treturn true\n", strings.Join(quoted, ", "))
+	}
+	fmt.Fprintf(b, "\t}\n\treturn false\n}\n\n")
+
+	fmt.Fprintf(b, "func (e *%s) UnmarshalJSON(data []byte) error {\n", typeName)
+	fmt.Fprintf(b, "\tvar val string\n")
+	fmt.Fprintf(b, "\tif err := json.Unmarshal(data, &val); err != nil {\n\t\treturn err\n\t}\n")
+	fmt.Fprintf(b, "\tif !%s(val).Valid() {\n", typeName)
+	fmt.Fprintf(b, "\t\treturn fmt.Errorf(\"invalid %s value: %%q\", val)\n\t}\n", typeName)
+	fmt.Fprintf(b, "\t*e = %s(val)\n\treturn nil\n}\n\n", typeName)
+}
+
+func goType(model *Model, names *typeNames, valueType string) string {
+	switch model.kindOf(valueType) {
+	case kindString:
+		return "string"
+	case kindNumber:
+		return "float64"
+	case kindBool:
+		return "bool"
+	case kindArray:
+		return "[]any"
+	case kindEnum:
+		return names.enums[valueType]
+	}
+	return "any"
+}
@@ -0,0 +1,118 @@
+package codegen
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Dass33/administratum/backend/internal/database"
+	"github.com/google/uuid"
+)
+
+// sheetTypeEnums is the type of the sheets listing the values of an enum in
+// their first column.
+const sheetTypeEnums = "enums"
+
+type loadedColumn struct {
+	database.GetColumnsWithDataBySheetRow
+	cells []database.GetColumnsWithDataBySheetRow
+}
+
+// LoadModel reads the model of a branch, the sheets it exports to JSON
+// together with the values of its enum sheets.
+func LoadModel(ctx context.Context, q *database.Queries, branchId uuid.UUID, shape string) (Model, error) {
+	model := Model{Shape: shape}
+
+	sheetsDb, err := q.GetSheetsFromBranch(ctx, branchId)
+	if err != nil {
+		return model, fmt.Errorf("could not get sheets of branch: %w", err)
+	}
+
+	for _, sheetDb := range sheetsDb {
+		columns, err := loadColumns(ctx, q, sheetDb.ID)
+		if err != nil {
+			return model, fmt.Errorf("could not get columns of sheet %s: %w", sheetDb.Name, err)
+		}
+
+		if sheetDb.Type == sheetTypeEnums && len(columns) > 0 {
+			enum := Enum{Name: sheetDb.Name}
+			for _, cell := range columns[0].cells {
+				if cell.DataValue.Valid && cell.DataValue.String != "" {
+					enum.Values = append(enum.Values, cell.DataValue.String)
+				}
+			}
+			model.Enums = append(model.Enums, enum)
+		}
+
+		sheet := Sheet{Name: sheetDb.Name, Type: SheetTypeList}
+		if sheetDb.Type == SheetTypeMap {
+			if len(columns) < 2 {
+				return model, fmt.Errorf("sheet %s does not have enough columns", sheetDb.Name)
+			}
+			sheet.Type = SheetTypeMap
+			sheet.Columns = mapSheetColumns(columns[0], columns[1])
+		} else {
+			for i := range columns {
+				sheet.Columns = append(sheet.Columns, Column{
+					Name:     columns[i].ColumnName,
+					Type:     columns[i].ColumnType,
+					Required: columns[i].ColumnRequired,
+				})
+				if columns[i].ColumnIsKey {
+					sheet.KeyColumn = columns[i].ColumnName
+				}
+			}
+		}
+		model.Sheets = append(model.Sheets, sheet)
+	}
+	return model, nil
+}
+
+// loadColumns groups the cells of a sheet by their column, in the order of
+// the columns and the rows.
+func loadColumns(ctx context.Context, q *database.Queries, sheetId uuid.UUID) ([]loadedColumn, error) {
+	rows, err := q.GetColumnsWithDataBySheet(ctx, sheetId)
+	if err != nil {
+		return nil, err
+	}
+
+	var columns []loadedColumn
+	for _, row := range rows {
+		if len(columns) == 0 || columns[len(columns)-1].ColumnID != row.ColumnID {
+			columns = append(columns, loadedColumn{GetColumnsWithDataBySheetRow: row})
+		}
+		if row.DataID.Valid {
+			last := &columns[len(columns)-1]
+			last.cells = append(last.cells, row)
+		}
+	}
+	return columns, nil
+}
+
+// mapSheetColumns makes a field of every entry of a map sheet, typed by the
+// type of its value cell. Entries without a typed value and repeated names
+// are left out.
+func mapSheetColumns(names, values loadedColumn) []Column {
+	valueCells := make(map[int64]database.GetColumnsWithDataBySheetRow, len(values.cells))
+	for _, cell := range values.cells {
+		valueCells[cell.DataIdx.Int64] = cell
+	}
+
+	var columns []Column
+	seen := make(map[string]bool)
+	for _, nameCell := range names.cells {
+		if !nameCell.DataValue.Valid || seen[nameCell.DataValue.String] {
+			continue
+		}
+		valCell, ok := valueCells[nameCell.DataIdx.Int64]
+		if !ok || !valCell.DataValue.Valid || !valCell.DataType.Valid {
+			continue
+		}
+		seen[nameCell.DataValue.String] = true
+		columns = append(columns, Column{
+			Name:     nameCell.DataValue.String,
+			Type:     valCell.DataType.String,
+			Required: true,
+		})
+	}
+	return columns
+}
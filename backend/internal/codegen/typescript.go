@@ -0,0 +1,100 @@
+package codegen
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+func generateTypeScript(model Model) string {
+	names := newTypeNames(&model, "Config", "loadConfig")
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "// Code generated by administratum. DO NOT EDIT.\n\n")
+
+	for i := range model.Enums {
+		enum := &model.Enums[i]
+		fmt.Fprintf(&b, "export enum %s {\n", names.enums[enum.Name])
+		used := make(map[string]bool)
+		for _, val := range enum.values() {
+			fmt.Fprintf(&b, "    %s = %s,\n", uniqueName(used, pascalCase(val)), tsString(val))
+		}
+		fmt.Fprintf(&b, "}\n\n")
+	}
+
+	for i := range model.Sheets {
+		sheet := &model.Sheets[i]
+		fmt.Fprintf(&b, "export interface %s {\n", names.sheets[i])
+		for _, col := range sheet.Columns {
+			optional := ""
+			if !col.Required && sheet.Type != SheetTypeMap {
+				optional = "?"
+			}
+			fmt.Fprintf(&b, "    %s%s: %s;\n", tsProperty(col.Name), optional, tsType(&model, names, col.Type))
+		}
+		fmt.Fprintf(&b, "}\n\n")
+	}
+
+	fmt.Fprintf(&b, "export interface Config {\n")
+	for i := range model.Sheets {
+		fmt.Fprintf(&b, "    %s: %s;\n", tsProperty(model.Sheets[i].Name), tsSheetType(&model, names, i))
+	}
+	fmt.Fprintf(&b, "}\n\n")
+
+	fmt.Fprintf(&b, "export function loadConfig(json: string): Config {\n")
+	fmt.Fprintf(&b, "    const data = JSON.parse(json);\n")
+	if model.Shape == ShapeObject {
+		fmt.Fprintf(&b, "    if (data === null || typeof data !== \"object\" || Array.isArray(data)) {\n")
+		fmt.Fprintf(&b, "        throw new Error(\"expected an object of sheets\");\n    }\n")
+		fmt.Fprintf(&b, "    return data as Config;\n}\n")
+		return b.String()
+	}
+
+	fmt.Fprintf(&b, "    if (!Array.isArray(data) || data.length !== %d) {\n", len(model.Sheets))
+	fmt.Fprintf(&b, "        throw new Error(\"expected an array of %d sheets\");\n    }\n", len(model.Sheets))
+	fmt.Fprintf(&b, "    return {\n")
+	for i := range model.Sheets {
+		fmt.Fprintf(&b, "        %s: data[%d] as %s,\n", tsProperty(model.Sheets[i].Name), i, tsSheetType(&model, names, i))
+	}
+	fmt.Fprintf(&b, "    };\n}\n")
+	return b.String()
+}
+
+func tsSheetType(model *Model, names *typeNames, i int) string {
+	sheet := &model.Sheets[i]
+	if model.keyed(sheet) {
+		return "Record<string, " + names.sheets[i] + ">"
+	}
+	if sheet.Type != SheetTypeMap {
+		return names.sheets[i] + "[]"
+	}
+	return names.sheets[i]
+}
+
+func tsType(model *Model, names *typeNames, valueType string) string {
+	switch model.kindOf(valueType) {
+	case kindString:
+		return "string"
+	case kindNumber:
+		return "number"
+	case kindBool:
+		return "boolean"
+	case kindArray:
+		return "unknown[]"
+	case kindEnum:
+		return names.enums[valueType]
+	}
+	return "unknown"
+}
+
+func tsProperty(name string) string {
+	if isIdentifier(name) {
+		return name
+	}
+	return tsString(name)
+}
+
+func tsString(val string) string {
+	quoted, _ := json.Marshal(val)
+	return string(quoted)
+}
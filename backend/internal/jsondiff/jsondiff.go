@@ -0,0 +1,113 @@
+// Package jsondiff compares two JSON documents by content, so two exports
+// can be told apart without the noise of a text diff.
+//
+// Documents are compared as decoded by encoding/json into an any: objects
+// are map[string]any, arrays []any and numbers float64.
+package jsondiff
+
+import (
+	"sort"
+	"strconv"
+)
+
+const (
+	Added   = "added"
+	Removed = "removed"
+	Changed = "changed"
+)
+
+// Change is one value that differs between the documents. Path holds the
+// object keys and array indexes leading to it, Old is unset for added values
+// and New for removed ones.
+type Change struct {
+	Path []string `json:"path"`
+	Kind string   `json:"change"`
+	Old  any      `json:"old_value,omitempty"`
+	New  any      `json:"new_value,omitempty"`
+}
+
+// Diff lists the changes that turn old into new. Objects are compared key by
+// key in sorted order and arrays index by index, any other values that
+// differ are reported as a whole.
+func Diff(old, new any) []Change {
+	changes := []Change{}
+	diff(nil, old, new, &changes)
+	return changes
+}
+
+func diff(path []string, old, new any, changes *[]Change) {
+	switch oldValue := old.(type) {
+	case map[string]any:
+		if newValue, ok := new.(map[string]any); ok {
+			diffObjects(path, oldValue, newValue, changes)
+			return
+		}
+	case []any:
+		if newValue, ok := new.([]any); ok {
+			diffArrays(path, oldValue, newValue, changes)
+			return
+		}
+	default:
+		if isScalar(new) && old == new {
+			return
+		}
+	}
+	*changes = append(*changes, Change{Path: path, Kind: Changed, Old: old, New: new})
+}
+
+func diffObjects(path []string, old, new map[string]any, changes *[]Change) {
+	keys := make([]string, 0, len(old)+len(new))
+	for key := range old {
+		keys = append(keys, key)
+	}
+	for key := range new {
+		if _, ok := old[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		oldValue, inOld := old[key]
+		newValue, inNew := new[key]
+		keyPath := appendPath(path, key)
+		switch {
+		case !inOld:
+			*changes = append(*changes, Change{Path: keyPath, Kind: Added, New: newValue})
+		case !inNew:
+			*changes = append(*changes, Change{Path: keyPath, Kind: Removed, Old: oldValue})
+		default:
+			diff(keyPath, oldValue, newValue, changes)
+		}
+	}
+}
+
+func diffArrays(path []string, old, new []any, changes *[]Change) {
+	for i := 0; i < max(len(old), len(new)); i++ {
+		indexPath := appendPath(path, strconv.Itoa(i))
+		switch {
+		case i >= len(old):
+			*changes = append(*changes, Change{Path: indexPath, Kind: Added, New: new[i]})
+		case i >= len(new):
+			*changes = append(*changes, Change{Path: indexPath, Kind: Removed, Old: old[i]})
+		default:
+			diff(indexPath, old[i], new[i], changes)
+		}
+	}
+}
+
+// isScalar reports whether the value can be compared with ==, containers
+// of the other document would panic.
+func isScalar(value any) bool {
+	switch value.(type) {
+	case map[string]any, []any:
+		return false
+	}
+	return true
+}
+
+func appendPath(path []string, key string) []string {
+	next := make([]string, len(path), len(path)+1)
+	copy(next, path)
+	return append(next, key)
+}
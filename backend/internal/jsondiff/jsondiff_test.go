@@ -0,0 +1,55 @@
+package jsondiff_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/Dass33/administratum/backend/internal/jsondiff"
+)
+
+func decode(t *testing.T, data string) any {
+	t.Helper()
+	var value any
+	if err := json.Unmarshal([]byte(data), &value); err != nil {
+		t.Fatal(err)
+	}
+	return value
+}
+
+func TestDiffEqual(t *testing.T) {
+	doc := `{"items": {"sword": {"damage": 5, "tags": ["sharp"]}}, "config": [1, null, true]}`
+	if changes := jsondiff.Diff(decode(t, doc), decode(t, doc)); len(changes) != 0 {
+		t.Errorf("expected no changes, got %+v", changes)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	old := decode(t, `{
+		"items": {"sword": {"damage": 5, "tags": ["sharp"]}, "bow": {"damage": 3}},
+		"config": {"hp": 10, "name": "game"}
+	}`)
+	new := decode(t, `{
+		"items": {"sword": {"damage": 7, "tags": ["sharp", "heavy"]}, "axe": {"damage": 6}},
+		"config": {"hp": [10], "name": "game"}
+	}`)
+
+	want := []jsondiff.Change{
+		{Path: []string{"config", "hp"}, Kind: jsondiff.Changed, Old: 10.0, New: []any{10.0}},
+		{Path: []string{"items", "axe"}, Kind: jsondiff.Added, New: map[string]any{"damage": 6.0}},
+		{Path: []string{"items", "bow"}, Kind: jsondiff.Removed, Old: map[string]any{"damage": 3.0}},
+		{Path: []string{"items", "sword", "damage"}, Kind: jsondiff.Changed, Old: 5.0, New: 7.0},
+		{Path: []string{"items", "sword", "tags", "1"}, Kind: jsondiff.Added, New: "heavy"},
+	}
+	if changes := jsondiff.Diff(old, new); !reflect.DeepEqual(changes, want) {
+		t.Errorf("unexpected changes:\n got %+v\nwant %+v", changes, want)
+	}
+}
+
+func TestDiffRootArrays(t *testing.T) {
+	changes := jsondiff.Diff(decode(t, `[{"a": 1}, 2]`), decode(t, `[{"a": 1}]`))
+	want := []jsondiff.Change{{Path: []string{"1"}, Kind: jsondiff.Removed, Old: 2.0}}
+	if !reflect.DeepEqual(changes, want) {
+		t.Errorf("unexpected changes: %+v", changes)
+	}
+}
@@ -0,0 +1,219 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// maxPartSize limits the uncompressed size of every part read from a workbook.
+const maxPartSize = 256 << 20
+
+type xmlRelationships struct {
+	Relationships []struct {
+		ID     string `xml:"Id,attr"`
+		Type   string `xml:"Type,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+type xmlWorkbook struct {
+	Sheets []struct {
+		Name string `xml:"name,attr"`
+		RID  string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+	} `xml:"sheets>sheet"`
+}
+
+type xmlText struct {
+	T    string `xml:"t"`
+	Runs []struct {
+		T string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (t *xmlText) String() string {
+	if len(t.Runs) == 0 {
+		return t.T
+	}
+	var b strings.Builder
+	for _, run := range t.Runs {
+		b.WriteString(run.T)
+	}
+	return b.String()
+}
+
+type xmlSharedStrings struct {
+	Items []xmlText `xml:"si"`
+}
+
+type xmlWorksheet struct {
+	Rows []struct {
+		R      int  `xml:"r,attr"`
+		Hidden bool `xml:"hidden,attr"`
+		Cells  []struct {
+			R  string   `xml:"r,attr"`
+			T  string   `xml:"t,attr"`
+			V  string   `xml:"v"`
+			Is *xmlText `xml:"is"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+// Read decodes the values of every worksheet of an xlsx workbook, formulas
+// are read as their cached values and validations are not read.
+func Read(r io.ReaderAt, size int64) ([]Sheet, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("not an xlsx file: %w", err)
+	}
+	files := make(map[string]*zip.File, len(zr.File))
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+
+	workbookPath := "xl/workbook.xml"
+	rootRels := xmlRelationships{}
+	if err := decodePart(files, "_rels/.rels", &rootRels); err == nil {
+		for _, rel := range rootRels.Relationships {
+			if strings.HasSuffix(rel.Type, "/officeDocument") {
+				workbookPath = resolvePart("", rel.Target)
+			}
+		}
+	}
+
+	workbook := xmlWorkbook{}
+	if err := decodePart(files, workbookPath, &workbook); err != nil {
+		return nil, err
+	}
+
+	workbookDir := path.Dir(workbookPath)
+	relsPath := path.Join(workbookDir, "_rels", path.Base(workbookPath)+".rels")
+	workbookRels := xmlRelationships{}
+	if err := decodePart(files, relsPath, &workbookRels); err != nil {
+		return nil, err
+	}
+
+	targets := make(map[string]string, len(workbookRels.Relationships))
+	var sharedStrings []string
+	for _, rel := range workbookRels.Relationships {
+		target := resolvePart(workbookDir, rel.Target)
+		targets[rel.ID] = target
+		if strings.HasSuffix(rel.Type, "/sharedStrings") {
+			shared := xmlSharedStrings{}
+			if err := decodePart(files, target, &shared); err != nil {
+				return nil, err
+			}
+			for i := range shared.Items {
+				sharedStrings = append(sharedStrings, shared.Items[i].String())
+			}
+		}
+	}
+
+	sheets := make([]Sheet, 0, len(workbook.Sheets))
+	for _, wbSheet := range workbook.Sheets {
+		target, ok := targets[wbSheet.RID]
+		if !ok {
+			return nil, fmt.Errorf("sheet %s has no worksheet part", wbSheet.Name)
+		}
+		sheet, err := readWorksheet(files, target, sharedStrings)
+		if err != nil {
+			return nil, fmt.Errorf("sheet %s: %w", wbSheet.Name, err)
+		}
+		sheet.Name = wbSheet.Name
+		sheets = append(sheets, sheet)
+	}
+	return sheets, nil
+}
+
+func readWorksheet(files map[string]*zip.File, name string, sharedStrings []string) (Sheet, error) {
+	worksheet := xmlWorksheet{}
+	if err := decodePart(files, name, &worksheet); err != nil {
+		return Sheet{}, err
+	}
+
+	sheet := Sheet{}
+	rowIdx := -1
+	for _, xmlRow := range worksheet.Rows {
+		rowIdx++
+		if xmlRow.R > 0 {
+			rowIdx = xmlRow.R - 1
+		}
+		if rowIdx >= MaxRows {
+			return Sheet{}, fmt.Errorf("row %d is out of range", rowIdx+1)
+		}
+		for len(sheet.Rows) <= rowIdx {
+			sheet.Rows = append(sheet.Rows, nil)
+		}
+		if xmlRow.Hidden {
+			sheet.HiddenRows = append(sheet.HiddenRows, rowIdx)
+		}
+
+		row := []Cell{}
+		colIdx := -1
+		for _, xmlCell := range xmlRow.Cells {
+			colIdx++
+			if xmlCell.R != "" {
+				col, _, err := parseCellRef(xmlCell.R)
+				if err != nil {
+					return Sheet{}, err
+				}
+				colIdx = col
+			}
+
+			cell := Cell{Value: xmlCell.V, Kind: KindString}
+			switch xmlCell.T {
+			case "s":
+				idx, err := strconv.Atoi(xmlCell.V)
+				if err != nil || idx < 0 || idx >= len(sharedStrings) {
+					return Sheet{}, fmt.Errorf("invalid shared string in cell %s", xmlCell.R)
+				}
+				cell.Value = sharedStrings[idx]
+			case "inlineStr":
+				if xmlCell.Is != nil {
+					cell.Value = xmlCell.Is.String()
+				}
+			case "b":
+				cell = Bool(xmlCell.V == "1" || strings.EqualFold(xmlCell.V, "true"))
+			case "str", "e":
+			default:
+				cell.Kind = KindNumber
+			}
+
+			for len(row) <= colIdx {
+				row = append(row, Cell{})
+			}
+			row[colIdx] = cell
+		}
+		sheet.Rows[rowIdx] = row
+	}
+	return sheet, nil
+}
+
+func decodePart(files map[string]*zip.File, name string, v any) error {
+	f, ok := files[name]
+	if !ok {
+		return fmt.Errorf("missing part %s", name)
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	if err := xml.NewDecoder(io.LimitReader(rc, maxPartSize)).Decode(v); err != nil {
+		return fmt.Errorf("could not decode %s: %w", name, err)
+	}
+	return nil
+}
+
+// resolvePart turns a relationship target into a part name of the zip archive.
+func resolvePart(dir string, target string) string {
+	if strings.HasPrefix(target, "/") {
+		return strings.TrimPrefix(target, "/")
+	}
+	return path.Join(dir, target)
+}
@@ -0,0 +1,183 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+const (
+	nsMain          = "http://schemas.openxmlformats.org/spreadsheetml/2006/main"
+	nsRelationships = "http://schemas.openxmlformats.org/officeDocument/2006/relationships"
+	nsPackageRels   = "http://schemas.openxmlformats.org/package/2006/relationships"
+	xmlHeader       = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n"
+)
+
+const stylesXml = xmlHeader + `<styleSheet xmlns="` + nsMain + `">` +
+	`<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts>` +
+	`<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>` +
+	`<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>` +
+	`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>` +
+	`<cellXfs count="2"><xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/><xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/></cellXfs>` +
+	`<cellStyles count="1"><cellStyle name="Normal" xfId="0" builtinId="0"/></cellStyles>` +
+	`</styleSheet>`
+
+// boldStyle is the index of the bold cell format in stylesXml.
+const boldStyle = 1
+
+// Write encodes the sheets as an xlsx workbook. Sheet names have to be
+// unique and valid, see SanitizeSheetName.
+func Write(w io.Writer, sheets []Sheet) error {
+	if len(sheets) == 0 {
+		return fmt.Errorf("a workbook needs at least one sheet")
+	}
+	for i := range sheets {
+		if sheets[i].Name != SanitizeSheetName(sheets[i].Name) {
+			return fmt.Errorf("invalid sheet name: %s", sheets[i].Name)
+		}
+		for e := range i {
+			if strings.EqualFold(sheets[i].Name, sheets[e].Name) {
+				return fmt.Errorf("duplicate sheet name: %s", sheets[i].Name)
+			}
+		}
+	}
+
+	zw := zip.NewWriter(w)
+	files := []struct {
+		name    string
+		content string
+	}{
+		{"[Content_Types].xml", contentTypesXml(len(sheets))},
+		{"_rels/.rels", xmlHeader + `<Relationships xmlns="` + nsPackageRels + `">` +
+			`<Relationship Id="rId1" Type="` + nsRelationships + `/officeDocument" Target="xl/workbook.xml"/></Relationships>`},
+		{"xl/workbook.xml", workbookXml(sheets)},
+		{"xl/_rels/workbook.xml.rels", workbookRelsXml(len(sheets))},
+		{"xl/styles.xml", stylesXml},
+	}
+	for _, file := range files {
+		if err := writeZipFile(zw, file.name, file.content); err != nil {
+			return err
+		}
+	}
+
+	for i := range sheets {
+		name := fmt.Sprintf("xl/worksheets/sheet%d.xml", i+1)
+		if err := writeZipFile(zw, name, worksheetXml(&sheets[i])); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+func writeZipFile(zw *zip.Writer, name string, content string) error {
+	f, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(f, content)
+	return err
+}
+
+func escape(val string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(val))
+	return b.String()
+}
+
+func contentTypesXml(sheetCount int) string {
+	var b strings.Builder
+	b.WriteString(xmlHeader)
+	b.WriteString(`<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">`)
+	b.WriteString(`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>`)
+	b.WriteString(`<Default Extension="xml" ContentType="application/xml"/>`)
+	b.WriteString(`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>`)
+	b.WriteString(`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>`)
+	for i := range sheetCount {
+		fmt.Fprintf(&b, `<Override PartName="/xl/worksheets/sheet%d.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`, i+1)
+	}
+	b.WriteString(`</Types>`)
+	return b.String()
+}
+
+func workbookXml(sheets []Sheet) string {
+	var b strings.Builder
+	b.WriteString(xmlHeader)
+	fmt.Fprintf(&b, `<workbook xmlns="%s" xmlns:r="%s"><sheets>`, nsMain, nsRelationships)
+	for i := range sheets {
+		fmt.Fprintf(&b, `<sheet name="%s" sheetId="%d" r:id="rId%d"/>`, escape(sheets[i].Name), i+1, i+1)
+	}
+	b.WriteString(`</sheets></workbook>`)
+	return b.String()
+}
+
+func workbookRelsXml(sheetCount int) string {
+	var b strings.Builder
+	b.WriteString(xmlHeader)
+	fmt.Fprintf(&b, `<Relationships xmlns="%s">`, nsPackageRels)
+	for i := range sheetCount {
+		fmt.Fprintf(&b, `<Relationship Id="rId%d" Type="%s/worksheet" Target="worksheets/sheet%d.xml"/>`, i+1, nsRelationships, i+1)
+	}
+	fmt.Fprintf(&b, `<Relationship Id="rId%d" Type="%s/styles" Target="styles.xml"/>`, sheetCount+1, nsRelationships)
+	b.WriteString(`</Relationships>`)
+	return b.String()
+}
+
+func worksheetXml(sheet *Sheet) string {
+	var b strings.Builder
+	b.WriteString(xmlHeader)
+	fmt.Fprintf(&b, `<worksheet xmlns="%s" xmlns:r="%s">`, nsMain, nsRelationships)
+
+	if sheet.HeaderRows > 0 {
+		fmt.Fprintf(&b, `<sheetViews><sheetView workbookViewId="0"><pane ySplit="%d" topLeftCell="A%d" activePane="bottomLeft" state="frozen"/></sheetView></sheetViews>`,
+			sheet.HeaderRows, sheet.HeaderRows+1)
+	}
+
+	b.WriteString(`<sheetData>`)
+	for i, row := range sheet.Rows {
+		hidden := ""
+		if slices.Contains(sheet.HiddenRows, i) {
+			hidden = ` hidden="1"`
+		}
+		fmt.Fprintf(&b, `<row r="%d"%s>`, i+1, hidden)
+
+		style := ""
+		if i < sheet.HeaderRows {
+			style = fmt.Sprintf(` s="%d"`, boldStyle)
+		}
+		for e, cell := range row {
+			if cell.Value == "" {
+				continue
+			}
+			ref := CellRef(e, i)
+			switch cell.Kind {
+			case KindNumber:
+				fmt.Fprintf(&b, `<c r="%s"%s><v>%s</v></c>`, ref, style, escape(cell.Value))
+			case KindBool:
+				val := "0"
+				if strings.EqualFold(cell.Value, "true") {
+					val = "1"
+				}
+				fmt.Fprintf(&b, `<c r="%s"%s t="b"><v>%s</v></c>`, ref, style, val)
+			default:
+				fmt.Fprintf(&b, `<c r="%s"%s t="inlineStr"><is><t xml:space="preserve">%s</t></is></c>`, ref, style, escape(cell.Value))
+			}
+		}
+		b.WriteString(`</row>`)
+	}
+	b.WriteString(`</sheetData>`)
+
+	if len(sheet.Validations) > 0 {
+		fmt.Fprintf(&b, `<dataValidations count="%d">`, len(sheet.Validations))
+		for _, validation := range sheet.Validations {
+			fmt.Fprintf(&b, `<dataValidation type="list" allowBlank="1" showErrorMessage="1" sqref="%s"><formula1>%s</formula1></dataValidation>`,
+				escape(validation.Ref), escape(validation.Formula))
+		}
+		b.WriteString(`</dataValidations>`)
+	}
+
+	b.WriteString(`</worksheet>`)
+	return b.String()
+}
@@ -0,0 +1,123 @@
+// Package xlsx reads and writes the small subset of Office Open XML
+// workbooks needed to move branches in and out of spreadsheet applications:
+// plain string, number and bool cells, hidden rows and list data validations.
+package xlsx
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MaxSheetName is the longest worksheet name spreadsheet applications accept.
+const MaxSheetName = 31
+
+// MaxRows is the number of rows of a worksheet.
+const MaxRows = 1048576
+
+type CellKind int
+
+const (
+	KindString CellKind = iota
+	KindNumber
+	KindBool
+)
+
+type Cell struct {
+	// Value is the text of the cell, bools are "true" or "false".
+	Value string
+	Kind  CellKind
+}
+
+type Validation struct {
+	// Ref is the validated range, e.g. "B3:B100".
+	Ref string
+	// Formula is the source of the list, either a quoted comma separated
+	// list or a range such as 'Sheet'!$A$3:$A$9.
+	Formula string
+}
+
+type Sheet struct {
+	Name string
+	Rows [][]Cell
+	// HiddenRows are zero based indexes into Rows.
+	HiddenRows []int
+	// HeaderRows are the leading rows written in bold and frozen in place.
+	HeaderRows  int
+	Validations []Validation
+}
+
+func String(val string) Cell {
+	return Cell{Value: val, Kind: KindString}
+}
+
+func Number(val string) Cell {
+	return Cell{Value: val, Kind: KindNumber}
+}
+
+func Bool(val bool) Cell {
+	if val {
+		return Cell{Value: "true", Kind: KindBool}
+	}
+	return Cell{Value: "false", Kind: KindBool}
+}
+
+// ColumnName returns the letters of the zero based column, 0 is A and 26 is AA.
+func ColumnName(col int) string {
+	name := ""
+	for col++; col > 0; col = (col - 1) / 26 {
+		name = string(rune('A'+(col-1)%26)) + name
+	}
+	return name
+}
+
+// CellRef returns the A1 reference of the zero based column and row.
+func CellRef(col, row int) string {
+	return fmt.Sprintf("%s%d", ColumnName(col), row+1)
+}
+
+// RangeRef returns an absolute reference to the cells of one column between
+// the zero based rows, prefixed by the quoted sheet name.
+func RangeRef(sheet string, col, fromRow, toRow int) string {
+	return fmt.Sprintf("'%s'!$%s$%d:$%s$%d", strings.ReplaceAll(sheet, "'", "''"), ColumnName(col), fromRow+1, ColumnName(col), toRow+1)
+}
+
+// SanitizeSheetName replaces the characters worksheet names can not contain
+// and shortens the name to MaxSheetName.
+func SanitizeSheetName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`[]:*?/\`, r) {
+			return '_'
+		}
+		return r
+	}, name)
+	name = strings.Trim(name, "'")
+
+	runes := []rune(name)
+	if len(runes) > MaxSheetName {
+		runes = runes[:MaxSheetName]
+	}
+	if len(runes) == 0 {
+		return "Sheet"
+	}
+	return string(runes)
+}
+
+// parseCellRef returns the zero based column and row of an A1 reference.
+func parseCellRef(ref string) (int, int, error) {
+	col := 0
+	i := 0
+	for ; i < len(ref) && ref[i] >= 'A' && ref[i] <= 'Z'; i++ {
+		col = col*26 + int(ref[i]-'A') + 1
+	}
+	row := 0
+	for _, r := range ref[i:] {
+		if r < '0' || r > '9' {
+			return 0, 0, fmt.Errorf("invalid cell reference: %s", ref)
+		}
+		row = row*10 + int(r-'0')
+	}
+	if col == 0 || row == 0 {
+		return 0, 0, fmt.Errorf("invalid cell reference: %s", ref)
+	}
+	return col - 1, row - 1, nil
+}
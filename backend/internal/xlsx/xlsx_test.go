@@ -0,0 +1,83 @@
+package xlsx_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/Dass33/administratum/backend/internal/xlsx"
+)
+
+func TestColumnName(t *testing.T) {
+	cases := map[int]string{0: "A", 25: "Z", 26: "AA", 51: "AZ", 52: "BA", 701: "ZZ", 702: "AAA"}
+	for col, want := range cases {
+		if got := xlsx.ColumnName(col); got != want {
+			t.Errorf("ColumnName(%d) = %s, want %s", col, got, want)
+		}
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	sheets := []xlsx.Sheet{
+		{
+			Name: "items",
+			Rows: [][]xlsx.Cell{
+				{xlsx.String("name"), xlsx.String("hp"), xlsx.String("flying")},
+				{xlsx.String("text"), xlsx.String("number"), xlsx.String("bool")},
+				{xlsx.String("bat <&>"), xlsx.Number("1.5"), xlsx.Bool(true)},
+				{},
+				{xlsx.String(" padded "), {}, xlsx.Bool(false)},
+			},
+			HiddenRows: []int{1},
+			HeaderRows: 2,
+			Validations: []xlsx.Validation{
+				{Ref: "C3:C100", Formula: `"true,false"`},
+			},
+		},
+		{
+			Name: "rarity (enums)",
+			Rows: [][]xlsx.Cell{{xlsx.String("value")}, {xlsx.String("common")}},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := xlsx.Write(&buf, sheets); err != nil {
+		t.Fatal(err)
+	}
+
+	read, err := xlsx.Read(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(read) != 2 || read[0].Name != "items" || read[1].Name != "rarity (enums)" {
+		t.Fatalf("unexpected sheets: %+v", read)
+	}
+
+	rows := read[0].Rows
+	if len(rows) != 5 {
+		t.Fatalf("expected 5 rows, got %d", len(rows))
+	}
+	if rows[2][0].Value != "bat <&>" || rows[2][1] != xlsx.Number("1.5") || rows[2][2] != xlsx.Bool(true) {
+		t.Errorf("unexpected values in row 3: %+v", rows[2])
+	}
+	if len(rows[3]) != 0 {
+		t.Errorf("expected an empty row 4, got %+v", rows[3])
+	}
+	if rows[4][0].Value != " padded " || rows[4][1].Value != "" || rows[4][2] != xlsx.Bool(false) {
+		t.Errorf("unexpected values in row 5: %+v", rows[4])
+	}
+	if len(read[0].HiddenRows) != 1 || read[0].HiddenRows[0] != 1 {
+		t.Errorf("expected row 2 to be hidden, got %v", read[0].HiddenRows)
+	}
+}
+
+func TestWriteRejectsInvalidNames(t *testing.T) {
+	for _, names := range [][]string{{"a/b"}, {"Sheet", "sheet"}} {
+		sheets := make([]xlsx.Sheet, len(names))
+		for i := range names {
+			sheets[i].Name = names[i]
+		}
+		if err := xlsx.Write(&bytes.Buffer{}, sheets); err == nil {
+			t.Errorf("expected an error for sheet names %v", names)
+		}
+	}
+}
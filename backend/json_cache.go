@@ -0,0 +1,249 @@
+package main
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/andybalholm/brotli"
+	"github.com/google/uuid"
+)
+
+// jsonCache keeps the rendered /json documents of each branch so repeated
+// polls do not rebuild them from the database. Every write handler that
+// touches a branch has to call invalidateBranchJson for it.
+type jsonCache struct {
+	mu          sync.Mutex
+	entries     map[uuid.UUID]map[string]*jsonCacheEntry
+	generations map[uuid.UUID]uint64
+}
+
+type jsonCacheEntry struct {
+	body           []byte
+	etag           string
+	lastModified   time.Time
+	releaseVersion int64
+	immutable      bool
+
+	mu      sync.Mutex
+	encoded map[string][]byte
+}
+
+var jsonEncoders = map[string]func(io.Writer) (io.WriteCloser, error){
+	"br": func(w io.Writer) (io.WriteCloser, error) {
+		return brotli.NewWriterLevel(w, brotli.BestCompression), nil
+	},
+	"gzip": func(w io.Writer) (io.WriteCloser, error) {
+		return gzip.NewWriterLevel(w, gzip.BestCompression)
+	},
+	"deflate": func(w io.Writer) (io.WriteCloser, error) {
+		return flate.NewWriter(w, flate.BestCompression)
+	},
+}
+
+func newJsonCache() *jsonCache {
+	return &jsonCache{
+		entries:     make(map[uuid.UUID]map[string]*jsonCacheEntry),
+		generations: make(map[uuid.UUID]uint64),
+	}
+}
+
+func newJsonCacheEntry(body []byte, lastModified time.Time, releaseVersion int64) *jsonCacheEntry {
+	hash := sha256.Sum256(body)
+	return &jsonCacheEntry{
+		body:           body,
+		etag:           `W/"` + hex.EncodeToString(hash[:16]) + `"`,
+		lastModified:   lastModified.UTC().Truncate(time.Second),
+		releaseVersion: releaseVersion,
+		encoded:        make(map[string][]byte),
+	}
+}
+
+// generation has to be read before building an entry and passed to set, so an
+// entry built from data that was invalidated in the meantime is dropped.
+func (c *jsonCache) generation(branchId uuid.UUID) uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.generations[branchId]
+}
+
+func (c *jsonCache) get(branchId uuid.UUID, key string) (*jsonCacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[branchId][key]
+	return entry, ok
+}
+
+func (c *jsonCache) set(branchId uuid.UUID, key string, generation uint64, entry *jsonCacheEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.generations[branchId] != generation {
+		return
+	}
+	if c.entries[branchId] == nil {
+		c.entries[branchId] = make(map[string]*jsonCacheEntry)
+	}
+	c.entries[branchId][key] = entry
+}
+
+func (c *jsonCache) invalidate(branchId uuid.UUID) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, branchId)
+	c.generations[branchId]++
+}
+
+func (e *jsonCacheEntry) encodedBody(encoding string) ([]byte, error) {
+	if encoding == "" {
+		return e.body, nil
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if body, ok := e.encoded[encoding]; ok {
+		return body, nil
+	}
+
+	var buf bytes.Buffer
+	encoder, err := jsonEncoders[encoding](&buf)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := encoder.Write(e.body); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+
+	e.encoded[encoding] = buf.Bytes()
+	return e.encoded[encoding], nil
+}
+
+func (e *jsonCacheEntry) notModified(r *http.Request) bool {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		for _, tag := range strings.Split(inm, ",") {
+			tag = strings.TrimSpace(tag)
+			if tag == "*" || strings.TrimPrefix(tag, "W/") == strings.TrimPrefix(e.etag, "W/") {
+				return true
+			}
+		}
+		return false
+	}
+
+	if ims := r.Header.Get("If-Modified-Since"); ims != "" && !e.lastModified.IsZero() {
+		since, err := http.ParseTime(ims)
+		return err == nil && !e.lastModified.After(since)
+	}
+	return false
+}
+
+// negotiateEncoding picks the supported content coding with the highest
+// q-value from the Accept-Encoding header, empty string means identity. br
+// compresses best and wins ties.
+func negotiateEncoding(acceptEncoding string) string {
+	best := ""
+	bestQ := 0.0
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+
+		q := 1.0
+		if val, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(val, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+
+		if _, ok := jsonEncoders[name]; ok && (q > bestQ || (q == bestQ && q > 0 && name == "br")) {
+			best = name
+			bestQ = q
+		}
+	}
+	return best
+}
+
+func serveJsonEntry(w http.ResponseWriter, r *http.Request, entry *jsonCacheEntry) {
+	header := w.Header()
+	header.Set("Content-Type", "application/json")
+	header.Set("ETag", entry.etag)
+	header.Set("Vary", "Accept-Encoding")
+	if entry.immutable {
+		header.Set("Cache-Control", "public, max-age=31536000, immutable")
+	} else {
+		header.Set("Cache-Control", "no-cache")
+	}
+	if !entry.lastModified.IsZero() {
+		header.Set("Last-Modified", entry.lastModified.Format(http.TimeFormat))
+	}
+	if entry.releaseVersion > 0 {
+		header.Set("X-Release-Version", strconv.FormatInt(entry.releaseVersion, 10))
+	}
+
+	if entry.notModified(r) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	encoding := negotiateEncoding(r.Header.Get("Accept-Encoding"))
+	body, err := entry.encodedBody(encoding)
+	if err != nil {
+		encoding = ""
+		body = entry.body
+	}
+	if encoding != "" {
+		header.Set("Content-Encoding", encoding)
+	}
+	header.Set("Content-Length", strconv.Itoa(len(body)))
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(body)
+}
+
+// invalidateBranchJson bumps the modification time of the branch before it
+// drops the cached documents, so no document of the new data is cached with
+// the old time. It runs after the request committed, the bump is not tied to
+// the request context.
+func (cfg *apiConfig) invalidateBranchJson(branchId uuid.UUID) {
+	err := cfg.db.TouchBranchData(context.Background(), branchId)
+	if err != nil {
+		log.Printf("Could not bump modification time of branch %s: %s", branchId, err)
+	}
+	cfg.jsonCache.invalidate(branchId)
+}
+
+func (cfg *apiConfig) invalidateSheetJson(sheetId uuid.UUID, ctx context.Context) {
+	sheet, err := cfg.db.GetSheet(ctx, sheetId)
+	if err != nil {
+		return
+	}
+	cfg.invalidateBranchJson(sheet.BranchID)
+}
+
+func (cfg *apiConfig) invalidateColumnJson(columnId uuid.UUID, ctx context.Context) {
+	branchId, err := cfg.db.GetBranchIdFromColumn(ctx, columnId)
+	if err != nil {
+		return
+	}
+	cfg.invalidateBranchJson(branchId)
+}
+
+func (cfg *apiConfig) invalidateColumnDataJson(columnDataId uuid.UUID, ctx context.Context) {
+	branchId, err := cfg.db.GetBranchIdFromColumnData(ctx, columnDataId)
+	if err != nil {
+		return
+	}
+	cfg.invalidateBranchJson(branchId)
+}
@@ -0,0 +1,29 @@
+package main
+
+import "testing"
+
+func TestNegotiateEncoding(t *testing.T) {
+	tests := []struct {
+		acceptEncoding string
+		want           string
+	}{
+		{acceptEncoding: "", want: ""},
+		{acceptEncoding: "identity", want: ""},
+		{acceptEncoding: "gzip", want: "gzip"},
+		{acceptEncoding: "gzip, deflate, br", want: "br"},
+		{acceptEncoding: "br, gzip", want: "br"},
+		{acceptEncoding: "BR", want: "br"},
+		{acceptEncoding: "br;q=0.5, gzip;q=0.8", want: "gzip"},
+		{acceptEncoding: "gzip;q=1.0, br;q=1.0", want: "br"},
+		{acceptEncoding: "br;q=0, gzip", want: "gzip"},
+		{acceptEncoding: "br;q=0, gzip;q=0", want: ""},
+		{acceptEncoding: "gzip;q=high, br;q=0.1", want: "br"},
+		{acceptEncoding: "zstd, *;q=0", want: ""},
+	}
+
+	for _, tt := range tests {
+		if got := negotiateEncoding(tt.acceptEncoding); got != tt.want {
+			t.Errorf("negotiateEncoding(%q) = %q, want %q", tt.acceptEncoding, got, tt.want)
+		}
+	}
+}
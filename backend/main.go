@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"log"
 	"net/http"
@@ -19,10 +20,12 @@ const PlatformDev = "dev"
 const PlatformProd = "production"
 
 type apiConfig struct {
-	db       *database.Queries
-	rawDB    *sql.DB
-	platform string
-	jwt_key  string
+	db         *database.Queries
+	rawDB      *sql.DB
+	platform   string
+	jwt_key    string
+	jsonCache  *jsonCache
+	mergeLocks *mergeLocks
 }
 
 type IdName struct {
@@ -51,14 +54,18 @@ func main() {
 	}
 	dbQueries := database.New(db)
 	apiCfg := apiConfig{
-		db:       dbQueries,
-		rawDB:    db,
-		platform: os.Getenv("PLATFORM"),
-		jwt_key:  os.Getenv("JWT_KEY"),
+		db:         dbQueries,
+		rawDB:      db,
+		platform:   os.Getenv("PLATFORM"),
+		jwt_key:    os.Getenv("JWT_KEY"),
+		jsonCache:  newJsonCache(),
+		mergeLocks: newMergeLocks(),
 	}
 
 	log.Println("Connected to database!")
 
+	apiCfg.startTrashSweeper(context.Background())
+
 	router := chi.NewRouter()
 
 	router.Use(cors.Handler(cors.Options{
@@ -88,6 +95,13 @@ func main() {
 	router.Post("/create_project", apiCfg.middlewareAuth(apiCfg.createProjectHandler))
 	router.Post("/create_sheet", apiCfg.middlewareAuth(apiCfg.createSheetHandler))
 	router.Get("/json/{branch_id}", apiCfg.getJsonHandler)
+	router.Get("/json/{branch_id}/draft", apiCfg.getDraftJsonHandler)
+	router.Get("/json_schema/{branch_id}", apiCfg.getJsonSchemaHandler)
+	router.Get("/codegen/{branch_id}", apiCfg.middlewareAuth(apiCfg.getCodegenHandler))
+	router.Get("/export_sheet/{sheet_id}", apiCfg.middlewareAuth(apiCfg.exportSheetHandler))
+	router.Post("/import_sheet/{sheet_id}", apiCfg.middlewareAuth(apiCfg.importSheetHandler))
+	router.Get("/export_workbook/{branch_id}", apiCfg.middlewareAuth(apiCfg.exportWorkbookHandler))
+	router.Post("/import_workbook", apiCfg.middlewareAuth(apiCfg.importWorkbookHandler))
 	router.Put("/rename_sheet", apiCfg.middlewareAuth(apiCfg.renameSheetHandler))
 	router.Delete("/delete_sheet", apiCfg.middlewareAuth(apiCfg.deleteSheetHandler))
 	router.Put("/rename_project", apiCfg.middlewareAuth(apiCfg.renemeProjectHandler))
@@ -95,13 +109,40 @@ func main() {
 	router.Post("/add_share", apiCfg.middlewareAuth(apiCfg.addShareHandler))
 	router.Delete("/delete_row", apiCfg.middlewareAuth(apiCfg.deleteRowHandler))
 	router.Put("/change_game_url", apiCfg.middlewareAuth(apiCfg.changeGameUrlHandler))
+	router.Put("/change_export_shape", apiCfg.middlewareAuth(apiCfg.changeExportShapeHandler))
+	router.Put("/set_key_column", apiCfg.middlewareAuth(apiCfg.setKeyColumnHandler))
 	router.Post("/create_branch", apiCfg.middlewareAuth(apiCfg.createBranchHandler))
 	router.Get("/get_branch/{branch_id}", apiCfg.middlewareAuth(apiCfg.getBranchHandler))
 	router.Delete("/delete_branch", apiCfg.middlewareAuth(apiCfg.deleteBranchHandler))
 	router.Put("/update_branch", apiCfg.middlewareAuth(apiCfg.updateBranchHandler))
 	router.Post("/merge_preview", apiCfg.middlewareAuth(apiCfg.mergePreviewHandler))
 	router.Post("/merge_execute", apiCfg.middlewareAuth(apiCfg.mergeExecuteHandler))
+	router.Post("/sync_preview", apiCfg.middlewareAuth(apiCfg.syncPreviewHandler))
+	router.Post("/sync_execute", apiCfg.middlewareAuth(apiCfg.syncExecuteHandler))
+	router.Get("/merges/{table_id}", apiCfg.middlewareAuth(apiCfg.getMergesHandler))
 	router.Get("/merge_targets", apiCfg.middlewareAuth(apiCfg.getMergeTargetsHandler))
+	router.Get("/branch_diff", apiCfg.middlewareAuth(apiCfg.branchDiffHandler))
+	router.Post("/create_merge_request", apiCfg.middlewareAuth(apiCfg.createMergeRequestHandler))
+	router.Get("/merge_requests/{table_id}", apiCfg.middlewareAuth(apiCfg.getMergeRequestsHandler))
+	router.Get("/merge_request/{merge_request_id}", apiCfg.middlewareAuth(apiCfg.getMergeRequestHandler))
+	router.Put("/update_merge_request", apiCfg.middlewareAuth(apiCfg.updateMergeRequestHandler))
+	router.Post("/comment_merge_request", apiCfg.middlewareAuth(apiCfg.commentMergeRequestHandler))
+	router.Post("/review_merge_request", apiCfg.middlewareAuth(apiCfg.reviewMergeRequestHandler))
+	router.Post("/merge_merge_request", apiCfg.middlewareAuth(apiCfg.mergeMergeRequestHandler))
+	router.Get("/validate_branch/{branch_id}", apiCfg.middlewareAuth(apiCfg.validateBranchHandler))
+	router.Post("/publish_release", apiCfg.middlewareAuth(apiCfg.publishReleaseHandler))
+	router.Get("/releases/{branch_id}", apiCfg.middlewareAuth(apiCfg.getReleasesHandler))
+	router.Post("/create_tag", apiCfg.middlewareAuth(apiCfg.createTagHandler))
+	router.Get("/tags/{table_id}", apiCfg.middlewareAuth(apiCfg.getTagsHandler))
+	router.Delete("/delete_tag", apiCfg.middlewareAuth(apiCfg.deleteTagHandler))
+	router.Post("/restore_tag", apiCfg.middlewareAuth(apiCfg.restoreTagHandler))
+	router.Get("/cell_history/{cell_id}", apiCfg.middlewareAuth(apiCfg.getCellHistoryHandler))
+	router.Get("/sheet_history/{sheet_id}", apiCfg.middlewareAuth(apiCfg.getSheetHistoryHandler))
+	router.Post("/undo", apiCfg.middlewareAuth(apiCfg.undoHandler))
+	router.Post("/redo", apiCfg.middlewareAuth(apiCfg.redoHandler))
+	router.Get("/trash", apiCfg.middlewareAuth(apiCfg.getTrashHandler))
+	router.Get("/trash/{table_id}", apiCfg.middlewareAuth(apiCfg.getProjectTrashHandler))
+	router.Post("/restore_trash", apiCfg.middlewareAuth(apiCfg.restoreTrashHandler))
 
 	srv := &http.Server{
 		Addr:              ":" + port,
@@ -2,24 +2,42 @@ package main
 
 import (
 	"context"
-	"database/sql"
 	"encoding/json"
 	"fmt"
 	"net/http"
-	"time"
 
 	"github.com/Dass33/administratum/backend/internal/database"
 	"github.com/google/uuid"
 )
 
 type MergeResolution struct {
-	ConflictID   string `json:"conflict_id"`
-	ChosenSource string `json:"chosen_source"`
+	ConflictID string `json:"conflict_id"`
+	// ChosenSource is "source" or "target", or "keep" or "delete" for the
+	// delete conflicts. Cell, column name, column type and sheet name
+	// conflicts can also be resolved with "custom" and a value.
+	ChosenSource string  `json:"chosen_source"`
+	Value        *string `json:"value,omitempty"`
 }
 
 type MergeExecuteRequest struct {
-	SourceBranchID uuid.UUID         `json:"source_branch_id"`
+	SourceBranchID uuid.UUID `json:"source_branch_id"`
+	// TargetBranchID defaults to the oldest branch of the table.
+	TargetBranchID uuid.NullUUID     `json:"target_branch_id"`
 	Resolutions    []MergeResolution `json:"resolutions"`
+	// Strategies resolve the conflicts without a resolution.
+	Strategies []MergeStrategy `json:"strategies"`
+	// PreviewToken of the preview the resolutions were chosen in, it is
+	// required and the merge is rejected when the branches changed since.
+	PreviewToken string `json:"preview_token"`
+	// KeepSource keeps the source branch after the merge, so it can be merged
+	// again later. The source is always kept by a partial merge.
+	KeepSource bool `json:"keep_source"`
+	MergeSelection
+	// MergeRequestID is recorded in the merge history when the merge comes
+	// from a merge request.
+	MergeRequestID uuid.NullUUID `json:"-"`
+	// RequireValid refuses the merge while validateBranch finds errors in the source branch.
+	RequireValid bool `json:"require_valid"`
 }
 
 type MergeExecuteResponse struct {
@@ -28,6 +46,11 @@ type MergeExecuteResponse struct {
 	TargetBranchID uuid.UUID `json:"target_branch_id"`
 }
 
+type mergeValidationResponse struct {
+	Error      string           `json:"error"`
+	Validation ValidationReport `json:"validation"`
+}
+
 func (cfg *apiConfig) mergeExecuteHandler(w http.ResponseWriter, r *http.Request, userId uuid.UUID) {
 	var req MergeExecuteRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -43,560 +66,284 @@ func (cfg *apiConfig) mergeExecuteHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	targetBranch, err := cfg.db.GetOldestBranchFromTable(ctx, sourceBranch.TableID)
-	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Could not find target branch (oldest branch in table)")
-		return
-	}
-
-	if !cfg.checkBranchPermission(userId, req.SourceBranchID, "read", ctx) {
-		respondWithError(w, http.StatusForbidden, "No read permission on source branch")
-		return
-	}
-
-	if !cfg.checkBranchPermission(userId, targetBranch.ID, "write", ctx) {
-		respondWithError(w, http.StatusForbidden, "No write permission on target branch")
-		return
-	}
-
-	sourceData, err := cfg.db.GetBranchDataForMerge(ctx, req.SourceBranchID)
-	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Could not get source branch data")
-		return
-	}
-
-	targetData, err := cfg.db.GetBranchDataForMerge(ctx, targetBranch.ID)
-	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Could not get target branch data")
-		return
-	}
-
-	conflicts := cfg.detectMergeConflicts(sourceData, targetData, sourceBranch.CreatedAt)
-
-	resolutionMap := make(map[string]string)
-	for _, resolution := range req.Resolutions {
-		resolutionMap[resolution.ConflictID] = resolution.ChosenSource
-	}
-
-	if len(conflicts) != len(req.Resolutions) {
-		respondWithError(w, http.StatusBadRequest, "All conflicts must be resolved")
-		return
-	}
-
-	err = cfg.executeMerge(sourceData, targetData, conflicts, resolutionMap, sourceBranch.CreatedAt, ctx)
-	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, fmt.Sprintf("Merge failed: %v", err))
-		return
-	}
-
-	err = cfg.createNewSheets(sourceData, targetData, targetBranch.ID, ctx)
-	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to create new sheets: %v", err))
-		return
-	}
-
-	targetData, err = cfg.db.GetBranchDataForMerge(ctx, targetBranch.ID)
-	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to refresh target data: %v", err))
-		return
-	}
-
-	err = cfg.createNewColumns(sourceData, targetData, ctx)
-	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to create new columns: %v", err))
+	targetBranch, ok := cfg.getMergeTarget(w, sourceBranch, req.TargetBranchID, ctx)
+	if !ok {
 		return
 	}
 
-	err = cfg.copyDataToNewColumns(sourceData, targetBranch.ID, ctx)
-	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to copy data to new columns: %v", err))
+	if targetBranch.RequiredApprovals > 0 {
+		msg := fmt.Sprintf("Target branch requires %d approvals, open a merge request instead", targetBranch.RequiredApprovals)
+		respondWithError(w, http.StatusForbidden, msg)
 		return
 	}
 
-	err = cfg.handleDeletions(sourceData, targetData, ctx)
-	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to handle deletions: %v", err))
+	response, ok := cfg.mergeBranches(w, userId, sourceBranch, targetBranch, req, nil, ctx)
+	if !ok {
 		return
 	}
-
-	cfg.db.DeleteBranch(ctx, req.SourceBranchID)
-
-	response := MergeExecuteResponse{
-		Success:        true,
-		Message:        "Merge completed successfully and source branch deleted",
-		TargetBranchID: targetBranch.ID,
-	}
-
 	respondWithJSON(w, http.StatusOK, response)
 }
 
-func (cfg *apiConfig) executeMerge(
-	sourceData, targetData []database.GetBranchDataForMergeRow,
-	conflicts []MergeConflict,
-	resolutions map[string]string,
-	branchCreatedAt time.Time,
+// afterMergeFunc runs in the transaction of the merge once the plan was
+// applied, with the conflicts the resolutions were applied to.
+type afterMergeFunc func(ctx context.Context, q *database.Queries, conflicts []MergeConflict) error
+
+// mergeBranches merges the source branch into the target and deletes the
+// source unless it is kept or the user may not write to it. A kept source
+// is rebased onto the target and the merge is recorded in the merge
+// history. It writes the error response itself and returns false when the
+// merge did not happen.
+func (cfg *apiConfig) mergeBranches(
+	w http.ResponseWriter,
+	userId uuid.UUID,
+	sourceBranch, targetBranch database.Branch,
+	req MergeExecuteRequest,
+	afterMerge afterMergeFunc,
 	ctx context.Context,
-) error {
-	err := cfg.processConflictResolutions(sourceData, targetData, conflicts, resolutions, ctx)
-	if err != nil {
-		return fmt.Errorf("failed to process conflict resolutions: %v", err)
-	}
-
-	err = cfg.updateNonConflictingData(sourceData, targetData, conflicts, branchCreatedAt, ctx)
-	if err != nil {
-		return fmt.Errorf("failed to update non-conflicting data: %v", err)
+) (MergeExecuteResponse, bool) {
+	if !cfg.checkBranchPermission(userId, sourceBranch.ID, "read", ctx) {
+		respondWithError(w, http.StatusForbidden, "No read permission on source branch")
+		return MergeExecuteResponse{}, false
 	}
 
-	err = cfg.createNewCellData(sourceData, targetData, branchCreatedAt, ctx)
-	if err != nil {
-		return fmt.Errorf("failed to create new cell data: %v", err)
+	if !cfg.checkBranchPermission(userId, targetBranch.ID, "merge", ctx) {
+		respondWithError(w, http.StatusForbidden, "No write permission on target branch")
+		return MergeExecuteResponse{}, false
 	}
 
-	return nil
-}
-
-func (cfg *apiConfig) processConflictResolutions(
-	sourceData, targetData []database.GetBranchDataForMergeRow,
-	conflicts []MergeConflict,
-	resolutions map[string]string,
-	ctx context.Context,
-) error {
-	for _, conflict := range conflicts {
-		resolution, exists := resolutions[conflict.ID]
-		if !exists {
-			return fmt.Errorf("no resolution provided for conflict %s", conflict.ID)
-		}
-
-		if resolution != "source" && resolution != "target" {
-			return fmt.Errorf("invalid resolution %s for conflict %s", resolution, conflict.ID)
+	if req.RequireValid {
+		report, err := cfg.validateBranch(sourceBranch.ID, ctx)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, fmt.Sprintf("Could not validate source branch: %v", err))
+			return MergeExecuteResponse{}, false
 		}
-
-		if resolution == "source" {
-			switch conflict.Type {
-			case "cell_data":
-				err := cfg.resolveCellDataConflict(sourceData, targetData, conflict, ctx)
-				if err != nil {
-					return err
-				}
-			case "column_property":
-				err := cfg.resolveColumnPropertyConflict(conflict, ctx)
-				if err != nil {
-					return err
-				}
-			case "sheet_property":
-				err := cfg.resolveSheetPropertyConflict(conflict, ctx)
-				if err != nil {
-					return err
-				}
-			}
+		if !report.Valid {
+			respondWithJSON(w, http.StatusUnprocessableEntity, mergeValidationResponse{
+				Error:      "Source branch contains validation errors",
+				Validation: report,
+			})
+			return MergeExecuteResponse{}, false
 		}
 	}
-	return nil
-}
 
-func (cfg *apiConfig) resolveCellDataConflict(
-	sourceData, targetData []database.GetBranchDataForMergeRow,
-	conflict MergeConflict,
-	ctx context.Context,
-) error {
-	conflictKey := conflict.ID[5:] // Remove "cell-" prefix
-
-	var sourceRow database.GetBranchDataForMergeRow
-	var sourceExists bool
-	for _, row := range sourceData {
-		if row.ColumnDataID.Valid {
-			sheetKey := row.SourceSheetID.String
-			columnKey := row.SourceColumnID.String
-			key := fmt.Sprintf("%s-%s-%d", sheetKey, columnKey, row.ColumnDataIdx.Int64)
-			if key == conflictKey {
-				sourceRow = row
-				sourceExists = true
-				break
-			}
-		}
+	partial := req.MergeSelection.partial()
+	deleteSource := !req.KeepSource && !partial && cfg.checkBranchPermission(userId, sourceBranch.ID, "write", ctx)
+	message := "Merge completed successfully and source branch deleted"
+	if partial {
+		message = "Merge of the selected changes completed, the rest stays on the source branch"
+	} else if req.KeepSource {
+		message = "Merge completed successfully, the source branch was kept"
+	} else if !deleteSource {
+		message = "Merge completed successfully, the protected source branch was kept"
 	}
 
-	for _, targetRow := range targetData {
-		if targetRow.ColumnID.Valid && targetRow.ColumnDataID.Valid {
-			sheetKey := targetRow.SourceSheetID.String
-			columnKey := targetRow.SourceColumnID.String
-			key := fmt.Sprintf("%s-%s-%d", sheetKey, columnKey, targetRow.ColumnDataIdx.Int64)
-
-			if key == conflictKey {
-				var valueToUse sql.NullString
-				if sourceExists {
-					valueToUse = sourceRow.ColumnDataValue
-				} else {
-					valueToUse = sql.NullString{String: conflict.SourceValue, Valid: true}
-				}
-
-				updateParams := database.UpdateColumnDataParams{
-					ID:    targetRow.ColumnDataID.UUID,
-					Value: valueToUse,
-				}
-				err := cfg.db.UpdateColumnData(ctx, updateParams)
-				if err != nil {
-					return fmt.Errorf("failed to update cell data: %v", err)
-				}
-				break
+	finish := func(ctx context.Context, q *database.Queries, plan mergePlan, resolutions []MergeResolution) error {
+		if deleteSource {
+			_, err := q.TrashBranchWithPermissionCheck(ctx, database.TrashBranchWithPermissionCheckParams{
+				UserID: userId,
+				ID:     sourceBranch.ID,
+			})
+			if err != nil {
+				return fmt.Errorf("Could not delete source branch: %v", err)
+			}
+		} else if partial {
+			err := storeBaseSnapshotInTx(ctx, q, newBranchSnapshot(plan.mergedBase()), targetBranch.ID, sourceBranch.ID)
+			if err != nil {
+				return fmt.Errorf("Could not rebase source branch: %v", err)
+			}
+		} else {
+			err := cfg.rebaseSnapshotInTx(ctx, q, targetBranch.ID, sourceBranch.ID)
+			if err != nil {
+				return fmt.Errorf("Could not rebase source branch: %v", err)
 			}
 		}
-	}
-	return nil
-}
 
-func (cfg *apiConfig) resolveColumnPropertyConflict(conflict MergeConflict, ctx context.Context) error {
-	if conflict.Property == "name" {
-		err := cfg.db.UpdateColumn(ctx, database.UpdateColumnParams{
-			ID:   conflict.ColumnID,
-			Name: conflict.SourceValue,
-		})
+		err := recordMerge(ctx, q, userId, sourceBranch, targetBranch, req, !deleteSource, resolutions, plan.summary)
 		if err != nil {
-			return fmt.Errorf("failed to update column name: %v", err)
+			return err
 		}
-	}
-	return nil
-}
 
-func (cfg *apiConfig) resolveSheetPropertyConflict(conflict MergeConflict, ctx context.Context) error {
-	if conflict.Property == "name" {
-		err := cfg.db.RenameSheet(ctx, database.RenameSheetParams{
-			ID:   conflict.SheetID,
-			Name: conflict.SourceValue,
-		})
-		if err != nil {
-			return fmt.Errorf("failed to update sheet name: %v", err)
+		if afterMerge != nil {
+			return afterMerge(ctx, q, plan.conflictList())
 		}
+		return nil
 	}
-	return nil
-}
 
-func (cfg *apiConfig) updateNonConflictingData(
-	sourceData, targetData []database.GetBranchDataForMergeRow,
-	conflicts []MergeConflict,
-	branchCreatedAt time.Time,
-	ctx context.Context,
-) error {
-	for _, sourceRow := range sourceData {
-		if sourceRow.ColumnDataID.Valid && sourceRow.ColumnDataCreatedAt.Valid &&
-			sourceRow.ColumnDataUpdatedAt.Valid &&
-			sourceRow.ColumnDataUpdatedAt.Time.After(branchCreatedAt) {
-
-			wasConflict := false
-			sourceSheetKey := sourceRow.SourceSheetID.String
-			sourceColumnKey := sourceRow.SourceColumnID.String
-			sourceKey := fmt.Sprintf("%s-%s-%d", sourceSheetKey, sourceColumnKey, sourceRow.ColumnDataIdx.Int64)
-			expectedConflictID := fmt.Sprintf("cell-%s", sourceKey)
-
-			for _, conflict := range conflicts {
-				if conflict.Type == "cell_data" && conflict.ID == expectedConflictID {
-					wasConflict = true
-					break
-				}
-			}
+	if !cfg.runMerge(w, userId, sourceBranch, targetBranch, req, finish, ctx) {
+		return MergeExecuteResponse{}, false
+	}
 
-			if !wasConflict {
-				for _, targetRow := range targetData {
-					if targetRow.ColumnID.Valid && targetRow.ColumnDataID.Valid &&
-						targetRow.SheetID.String() == sourceSheetKey &&
-						targetRow.ColumnID.UUID.String() == sourceColumnKey &&
-						targetRow.ColumnDataIdx.Int64 == sourceRow.ColumnDataIdx.Int64 {
-
-						updateParams := database.UpdateColumnDataParams{
-							ID:    targetRow.ColumnDataID.UUID,
-							Value: sourceRow.ColumnDataValue,
-						}
-						err := cfg.db.UpdateColumnData(ctx, updateParams)
-						if err != nil {
-							return fmt.Errorf("failed to update non-conflicting cell data: %v", err)
-						}
-						break
-					}
-				}
-			}
-		}
+	response := MergeExecuteResponse{
+		Success:        true,
+		Message:        message,
+		TargetBranchID: targetBranch.ID,
 	}
-	return nil
+	return response, true
 }
 
-func (cfg *apiConfig) createNewCellData(
-	sourceData, targetData []database.GetBranchDataForMergeRow,
-	branchCreatedAt time.Time,
+// runMerge applies the merge plan of the two branches and then finish in one
+// transaction while no other merge of the table runs. It writes the error
+// response itself and returns false when nothing was merged.
+func (cfg *apiConfig) runMerge(
+	w http.ResponseWriter,
+	userId uuid.UUID,
+	sourceBranch, targetBranch database.Branch,
+	req MergeExecuteRequest,
+	finish func(ctx context.Context, q *database.Queries, plan mergePlan, resolutions []MergeResolution) error,
 	ctx context.Context,
-) error {
-	for _, sourceRow := range sourceData {
-		if sourceRow.ColumnDataID.Valid && sourceRow.ColumnDataCreatedAt.Valid {
-
-			var sourceSheetKey, sourceColumnKey string
-			if sourceRow.SourceSheetID.Valid {
-				sourceSheetKey = sourceRow.SourceSheetID.String
-			} else {
-				sourceSheetKey = sourceRow.SheetID.String()
-			}
-			if sourceRow.SourceColumnID.Valid {
-				sourceColumnKey = sourceRow.SourceColumnID.String
-			} else {
-				sourceColumnKey = sourceRow.ColumnID.UUID.String()
-			}
-
-			found := false
-			var targetColumnID uuid.UUID
-			for _, targetRow := range targetData {
-				if targetRow.ColumnID.Valid {
-					var targetSheetKey, targetColumnKey string
-					targetSheetKey = targetRow.SheetID.String()
-					targetColumnKey = targetRow.ColumnID.UUID.String()
-
-					if targetSheetKey == sourceSheetKey && targetColumnKey == sourceColumnKey {
-						targetColumnID = targetRow.ColumnID.UUID
-
-						if targetRow.ColumnDataID.Valid && targetRow.ColumnDataIdx.Int64 == sourceRow.ColumnDataIdx.Int64 {
-							found = true
-							break
-						}
-					}
-				}
-			}
-
-			if !found && targetColumnID != uuid.Nil {
-				shouldMerge := false
-
-				if sourceRow.ColumnDataCreatedAt.Time.After(branchCreatedAt) {
-					shouldMerge = true
-				}
-
-				if sourceRow.ColumnDataUpdatedAt.Valid &&
-					sourceRow.ColumnDataUpdatedAt.Time.After(branchCreatedAt) {
-					shouldMerge = true
-				}
-
-				if shouldMerge {
-					maxIdx := int64(-1)
-					for _, targetRow := range targetData {
-						if targetRow.ColumnID.Valid && targetRow.ColumnDataID.Valid &&
-							targetRow.ColumnID.UUID == targetColumnID &&
-							targetRow.ColumnDataIdx.Int64 > maxIdx {
-							maxIdx = targetRow.ColumnDataIdx.Int64
-						}
-					}
-					nextIdx := maxIdx + 1
-
-					params := database.CreateColumnDataParams{
-						Idx:      nextIdx,
-						Value:    sourceRow.ColumnDataValue,
-						Type:     sql.NullString{Valid: false},
-						ColumnID: targetColumnID,
-					}
-					_, err := cfg.db.CreateColumnData(ctx, params)
-					if err != nil {
-						return fmt.Errorf("failed to create new column data: %v", err)
-					}
-				}
-			}
-		}
+) bool {
+	if req.PreviewToken == "" {
+		respondWithError(w, http.StatusBadRequest, "Preview the merge first, the preview token is required")
+		return false
 	}
-	return nil
-}
 
-func (cfg *apiConfig) createNewSheets(sourceData, targetData []database.GetBranchDataForMergeRow, targetBranchID uuid.UUID, ctx context.Context) error {
-	sourceSheets := make(map[uuid.UUID]database.GetBranchDataForMergeRow)
-	for _, row := range sourceData {
-		if !row.SourceSheetID.Valid {
-			sourceSheets[row.SheetID] = row
-		}
+	if !cfg.mergeLocks.tryLock(targetBranch.TableID) {
+		respondWithError(w, http.StatusConflict, "Another merge into this table is in progress")
+		return false
 	}
+	defer cfg.mergeLocks.unlock(targetBranch.TableID)
 
-	targetSheets := make(map[string]bool)
-	for _, row := range targetData {
-		targetSheets[row.SheetID.String()] = true
+	tx, err := cfg.rawDB.BeginTx(ctx, nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, fmt.Sprintf("Could not begin transaction: %v", err))
+		return false
 	}
+	defer tx.Rollback()
+	txQueries := cfg.db.WithTx(tx)
 
-	for _, sourceSheet := range sourceSheets {
-
-		if targetSheets[sourceSheet.SheetID.String()] {
-			continue
-		}
-		createSheetParams := database.CreateSheetParams{
-			BranchID:      targetBranchID,
-			Name:          sourceSheet.SheetName,
-			Type:          sourceSheet.SheetType,
-			SourceSheetID: sql.NullString{String: sourceSheet.SheetID.String(), Valid: true},
-		}
-
-		_, err := cfg.db.CreateSheet(ctx, createSheetParams)
-		if err != nil {
-			return fmt.Errorf("failed to create sheet %s: %v", sourceSheet.SheetName, err)
-		}
+	plan, err := cfg.getMergePlanWithTx(txQueries, sourceBranch, targetBranch, req.MergeSelection, ctx)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return false
 	}
 
-	return nil
-}
-
-func (cfg *apiConfig) createNewColumns(sourceData, targetData []database.GetBranchDataForMergeRow, ctx context.Context) error {
-	sourceColumns := make(map[uuid.UUID]database.GetBranchDataForMergeRow)
-	for _, row := range sourceData {
-		if row.ColumnID.Valid && !row.SourceColumnID.Valid {
-			sourceColumns[row.ColumnID.UUID] = row
-		}
+	if req.PreviewToken != plan.token {
+		respondWithError(w, http.StatusConflict, "Branches changed since the merge preview, preview the merge again")
+		return false
 	}
 
-	targetColumns := make(map[string]bool)
-	for _, row := range targetData {
-		if row.ColumnID.Valid {
-			targetColumns[row.ColumnID.UUID.String()] = true
-		}
-	}
-
-	targetSheetMap := make(map[string]uuid.UUID) // source sheet ID -> target sheet ID
-	for _, row := range targetData {
-		targetSheetMap[row.SheetID.String()] = row.SheetID
+	resolutions, sides, err := resolveConflicts(&plan, req.Resolutions, req.Strategies)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return false
 	}
 
-	for _, sourceColumn := range sourceColumns {
-		if targetColumns[sourceColumn.ColumnID.UUID.String()] {
-			continue
-		}
-
-		var targetSheetID uuid.UUID
-		var found bool
-
-		if sourceColumn.SourceSheetID.Valid {
-			targetSheetID, found = targetSheetMap[sourceColumn.SourceSheetID.String]
-		} else {
-			targetSheetID, found = targetSheetMap[sourceColumn.SheetID.String()]
-		}
-
-		if found {
-			addColumnParams := database.AddColumnParams{
-				Name:           sourceColumn.ColumnName.String,
-				Type:           sourceColumn.ColumnType.String,
-				Required:       sourceColumn.ColumnRequired.Bool,
-				SheetID:        targetSheetID,
-				SourceColumnID: sql.NullString{String: sourceColumn.ColumnID.UUID.String(), Valid: true},
-			}
-			_, err := cfg.db.AddColumn(ctx, addColumnParams)
-			if err != nil {
-				return fmt.Errorf("failed to create column %s: %v", sourceColumn.ColumnName.String, err)
-			}
-		}
+	err = applyMergePlan(txQueries, userId, plan, sides, ctx)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, fmt.Sprintf("Merge failed: %v", err))
+		return false
 	}
 
-	return nil
-}
-
-func (cfg *apiConfig) copyDataToNewColumns(sourceData []database.GetBranchDataForMergeRow, targetBranchID uuid.UUID, ctx context.Context) error {
-	targetData, err := cfg.db.GetBranchDataForMerge(ctx, targetBranchID)
+	err = recordSheetChanges(ctx, txQueries, userId, targetBranch.ID, plan.target, HistoryActionMerge, sourceBranch.Name)
 	if err != nil {
-		return fmt.Errorf("could not get updated target branch data: %v", err)
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return false
 	}
 
-	newTargetColumns := make(map[string]uuid.UUID) // source column ID -> new target column ID
-	for _, row := range targetData {
-		if row.ColumnID.Valid && row.SourceColumnID.Valid {
-			newTargetColumns[row.SourceColumnID.String] = row.ColumnID.UUID
-		}
+	err = finish(ctx, txQueries, plan, resolutions)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return false
 	}
 
-	for _, sourceRow := range sourceData {
-		if sourceRow.ColumnDataID.Valid && sourceRow.ColumnID.Valid {
-			if targetColumnID, exists := newTargetColumns[sourceRow.ColumnID.UUID.String()]; exists {
-				hasData := false
-				for _, targetRow := range targetData {
-					if targetRow.ColumnID.Valid && targetRow.ColumnDataID.Valid &&
-						targetRow.ColumnID.UUID == targetColumnID &&
-						targetRow.ColumnDataIdx.Int64 == sourceRow.ColumnDataIdx.Int64 {
-						hasData = true
-						break
-					}
-				}
-
-				if !hasData {
-					params := database.CreateColumnDataParams{
-						Idx:      sourceRow.ColumnDataIdx.Int64,
-						Value:    sourceRow.ColumnDataValue,
-						Type:     sql.NullString{Valid: false},
-						ColumnID: targetColumnID,
-					}
-					_, err := cfg.db.CreateColumnData(ctx, params)
-					if err != nil {
-						return fmt.Errorf("failed to copy column data: %v", err)
-					}
-				}
-			}
-		}
+	err = tx.Commit()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, fmt.Sprintf("Could not commit merge: %v", err))
+		return false
 	}
-	return nil
+	cfg.invalidateBranchJson(targetBranch.ID)
+	cfg.invalidateBranchJson(sourceBranch.ID)
+	return true
 }
 
-func (cfg *apiConfig) handleDeletions(sourceData, targetData []database.GetBranchDataForMergeRow, ctx context.Context) error {
-	err := cfg.handleColumnDeletions(sourceData, targetData, ctx)
+func recordMerge(
+	ctx context.Context,
+	q *database.Queries,
+	userId uuid.UUID,
+	sourceBranch, targetBranch database.Branch,
+	req MergeExecuteRequest,
+	sourceKept bool,
+	resolutions []MergeResolution,
+	summary MergeSummary,
+) error {
+	resolutionsJson, err := json.Marshal(resolutions)
 	if err != nil {
-		return fmt.Errorf("failed to handle column deletions: %v", err)
+		return fmt.Errorf("Could not encode merge resolutions: %s", err)
 	}
-
-	err = cfg.handleSheetDeletions(sourceData, targetData, ctx)
+	summaryJson, err := json.Marshal(summary)
+	if err != nil {
+		return fmt.Errorf("Could not encode merge summary: %s", err)
+	}
+
+	_, err = q.CreateMerge(ctx, database.CreateMergeParams{
+		TableID:          targetBranch.TableID,
+		SourceBranchID:   uuid.NullUUID{UUID: sourceBranch.ID, Valid: sourceKept},
+		SourceBranchName: sourceBranch.Name,
+		TargetBranchID:   uuid.NullUUID{UUID: targetBranch.ID, Valid: true},
+		TargetBranchName: targetBranch.Name,
+		UserID:           uuid.NullUUID{UUID: userId, Valid: true},
+		MergeRequestID:   req.MergeRequestID,
+		SourceKept:       sourceKept,
+		Resolutions:      string(resolutionsJson),
+		Summary:          string(summaryJson),
+	})
 	if err != nil {
-		return fmt.Errorf("failed to handle sheet deletions: %v", err)
+		return fmt.Errorf("Could not record merge: %s", err)
 	}
-
 	return nil
 }
 
-func (cfg *apiConfig) handleColumnDeletions(sourceData, targetData []database.GetBranchDataForMergeRow, ctx context.Context) error {
-	referencedTargetColumns := make(map[string]bool)
-	for _, row := range sourceData {
-		if row.ColumnID.Valid && row.SourceColumnID.Valid {
-			referencedTargetColumns[row.SourceColumnID.String] = true
+// conflictSide returns the side a resolution of the conflict takes, or an
+// empty string when it is not a valid resolution. Delete conflicts are
+// resolved by keeping or deleting the item, which takes the side that kept
+// or deleted it.
+func conflictSide(conflict MergeConflict, choice string) string {
+	switch conflict.Property {
+	case DeletedInSource:
+		switch choice {
+		case ResolutionDelete:
+			return "source"
+		case ResolutionKeep:
+			return "target"
 		}
-	}
-
-	processedColumns := make(map[uuid.UUID]bool)
-	for _, targetRow := range targetData {
-		if targetRow.ColumnID.Valid && !processedColumns[targetRow.ColumnID.UUID] {
-			processedColumns[targetRow.ColumnID.UUID] = true
-
-			targetColumnId := targetRow.ColumnID.UUID.String()
-
-			if !referencedTargetColumns[targetColumnId] {
-				deleteParams := database.DeleteColumnParams{
-					Name:    targetRow.ColumnName.String,
-					SheetID: targetRow.SheetID,
-				}
-				err := cfg.db.DeleteColumn(ctx, deleteParams)
-				if err != nil {
-					return fmt.Errorf("failed to delete column %s: %v", targetRow.ColumnName.String, err)
-				}
-			}
+		return ""
+	case DeletedInTarget:
+		switch choice {
+		case ResolutionKeep:
+			return "source"
+		case ResolutionDelete:
+			return "target"
 		}
+		return ""
 	}
-
-	return nil
+	if choice == "source" || choice == "target" {
+		return choice
+	}
+	return ""
 }
 
-func (cfg *apiConfig) handleSheetDeletions(sourceData, targetData []database.GetBranchDataForMergeRow, ctx context.Context) error {
-	referencedTargetSheets := make(map[string]bool)
-	for _, row := range sourceData {
-		if row.SourceSheetID.Valid {
-			referencedTargetSheets[row.SourceSheetID.String] = true
+// applyMergePlan runs the deletions of the plan, the source side of the
+// conflicts resolved with it and then the remaining changes.
+func applyMergePlan(q *database.Queries, userId uuid.UUID, plan mergePlan, resolutions map[string]string, ctx context.Context) error {
+	for _, op := range plan.deletions {
+		if err := op(ctx, q, userId); err != nil {
+			return err
 		}
 	}
 
-	processedSheets := make(map[uuid.UUID]bool)
-	for _, targetRow := range targetData {
-		if processedSheets[targetRow.SheetID] {
+	for _, conflict := range plan.conflicts {
+		if resolutions[conflict.ID] != "source" {
 			continue
 		}
-		processedSheets[targetRow.SheetID] = true
-
-		targetSheetId := targetRow.SheetID.String()
-
-		if !referencedTargetSheets[targetSheetId] {
-			err := cfg.db.DeleteSheet(ctx, targetRow.SheetID)
-			if err != nil {
-				return fmt.Errorf("failed to delete sheet %s: %v", targetRow.SheetName, err)
-			}
+		if err := plan.resolutions[conflict.ID](ctx, q, userId); err != nil {
+			return fmt.Errorf("failed to resolve conflict %s: %v", conflict.ID, err)
 		}
 	}
 
+	for _, op := range plan.changes {
+		if err := op(ctx, q, userId); err != nil {
+			return err
+		}
+	}
 	return nil
 }
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"cmp"
+	"maps"
+	"slices"
+
+	"github.com/google/uuid"
+)
+
+// MergeSelection limits a merge to some sheets and columns, the changes of
+// everything else stay pending on the source. Ids of either branch are
+// accepted, so sheets and columns deleted in the source can be selected by
+// their id in the target. Columns are only selected in sheets both branches
+// have, new sheets have to be selected as a whole. An empty selection merges
+// everything.
+type MergeSelection struct {
+	SheetIDs  []uuid.UUID `json:"sheet_ids"`
+	ColumnIDs []uuid.UUID `json:"column_ids"`
+}
+
+func (selection MergeSelection) partial() bool {
+	return len(selection.SheetIDs) > 0 || len(selection.ColumnIDs) > 0
+}
+
+// mergeInclude is a selection resolved to the keys of the merge sides.
+type mergeInclude struct {
+	partial bool
+	sheets  map[uuid.UUID]bool
+	// columns maps the included column keys to the key of their sheet.
+	columns map[uuid.UUID]uuid.UUID
+}
+
+func (selection MergeSelection) include(source, target mergeSide) mergeInclude {
+	include := mergeInclude{
+		partial: selection.partial(),
+		sheets:  make(map[uuid.UUID]bool),
+		columns: make(map[uuid.UUID]uuid.UUID),
+	}
+	if !include.partial {
+		return include
+	}
+
+	sheetIds := make(map[uuid.UUID]bool, len(selection.SheetIDs))
+	for _, id := range selection.SheetIDs {
+		sheetIds[id] = true
+	}
+	columnIds := make(map[uuid.UUID]bool, len(selection.ColumnIDs))
+	for _, id := range selection.ColumnIDs {
+		columnIds[id] = true
+	}
+
+	for _, side := range []mergeSide{source, target} {
+		for _, key := range side.sheetKeys {
+			sheet := side.sheets[key]
+			if sheetIds[sheet.id] {
+				include.sheets[key] = true
+			}
+			_, inSource := source.sheets[key]
+			_, inTarget := target.sheets[key]
+			if !inSource || !inTarget {
+				continue
+			}
+			for _, columnKey := range sheet.columnKeys {
+				if columnIds[sheet.columns[columnKey].id] {
+					include.columns[columnKey] = key
+				}
+			}
+		}
+	}
+	return include
+}
+
+func (include mergeInclude) sheet(key uuid.UUID) bool {
+	return !include.partial || include.sheets[key]
+}
+
+func (include mergeInclude) column(sheetKey, columnKey uuid.UUID) bool {
+	if include.sheet(sheetKey) {
+		return true
+	}
+	_, ok := include.columns[columnKey]
+	return ok
+}
+
+// mergedBase is the base of the source after a partial merge. The included
+// sheets and columns are taken as the source has them, the rest keeps the
+// old base so its changes are merged later.
+func (plan mergePlan) mergedBase() mergeSide {
+	sheets := maps.Clone(plan.base.sheets)
+	for key := range plan.include.sheets {
+		if sheet, ok := plan.source.sheets[key]; ok {
+			sheets[key] = sheet
+		} else {
+			delete(sheets, key)
+		}
+	}
+
+	cloned := make(map[uuid.UUID]bool)
+	for columnKey, sheetKey := range plan.include.columns {
+		baseSheet, ok := sheets[sheetKey]
+		if !ok || plan.include.sheets[sheetKey] {
+			continue
+		}
+		if !cloned[sheetKey] {
+			sheet := *baseSheet
+			sheet.columns = maps.Clone(baseSheet.columns)
+			sheet.columnKeys = slices.Clone(baseSheet.columnKeys)
+			baseSheet = &sheet
+			sheets[sheetKey] = baseSheet
+			cloned[sheetKey] = true
+		}
+
+		baseSheet.columnKeys = slices.DeleteFunc(baseSheet.columnKeys, func(key uuid.UUID) bool {
+			return key == columnKey
+		})
+		delete(baseSheet.columns, columnKey)
+		if column, ok := plan.source.sheets[sheetKey].columns[columnKey]; ok {
+			baseSheet.columns[columnKey] = column
+			baseSheet.columnKeys = append(baseSheet.columnKeys, columnKey)
+		}
+	}
+
+	for sheetKey := range cloned {
+		sheet := sheets[sheetKey]
+		slices.SortStableFunc(sheet.columnKeys, func(a, b uuid.UUID) int {
+			return cmp.Compare(sheet.columns[a].orderIndex, sheet.columns[b].orderIndex)
+		})
+	}
+
+	merged := mergeSide{sheets: sheets}
+	seen := make(map[uuid.UUID]bool, len(sheets))
+	for _, keys := range [][]uuid.UUID{plan.base.sheetKeys, plan.source.sheetKeys} {
+		for _, key := range keys {
+			if _, ok := sheets[key]; ok && !seen[key] {
+				seen[key] = true
+				merged.sheetKeys = append(merged.sheetKeys, key)
+			}
+		}
+	}
+	return merged
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestMergedBase(t *testing.T) {
+	otherSheet := func(id, value string) sheetSpec {
+		return sheetSpec{
+			key:  "other",
+			id:   id,
+			name: "other",
+			rows: []string{"o1"},
+			columns: []columnSpec{
+				{name: "name", cells: []string{"o1", "x"}},
+				{name: "value", cells: []string{"o1", value}},
+			},
+		}
+	}
+	newSheet := sheetSpec{key: "new", id: "source new", name: "new"}
+
+	base := testBase(baseItems, otherSheet("base other", "1"))
+	source := buildTestSide(
+		itemsSheet("source", [3]string{"r1", "a", "10"}, [3]string{"r2", "b", "2"}),
+		otherSheet("source other", "2"),
+		newSheet,
+	)
+	target := buildTestSide(
+		itemsSheet("target", [3]string{"r1", "a", "1"}, [3]string{"r2", "b", "2"}),
+		otherSheet("target other", "1"),
+	)
+
+	value := func(side mergeSide, sheetKey, column, row string) string {
+		sheet, ok := side.sheets[testId(sheetKey)]
+		if !ok {
+			return "<no sheet>"
+		}
+		col, ok := sheet.columns[testColumnKey(sheetKey, column)]
+		if !ok {
+			return "<no column>"
+		}
+		return col.cells[testRowKey(row)].cell.value
+	}
+
+	cases := []struct {
+		name      string
+		selection MergeSelection
+		// want lists the sheet, column, row and value of cells of the
+		// merged base.
+		want     [][4]string
+		wantKeys []string
+	}{
+		{
+			name:      "whole sheet",
+			selection: MergeSelection{SheetIDs: []uuid.UUID{testId("source")}},
+			want: [][4]string{
+				{"items", "value", "r1", "10"},
+				{"other", "value", "o1", "1"},
+			},
+			wantKeys: []string{"items", "other"},
+		},
+		{
+			name:      "sheet selected by its target id",
+			selection: MergeSelection{SheetIDs: []uuid.UUID{testId("target")}},
+			want: [][4]string{
+				{"items", "value", "r1", "10"},
+				{"other", "value", "o1", "1"},
+			},
+			wantKeys: []string{"items", "other"},
+		},
+		{
+			name:      "single column",
+			selection: MergeSelection{ColumnIDs: []uuid.UUID{testId("source other:value")}},
+			want: [][4]string{
+				{"items", "value", "r1", "1"},
+				{"other", "name", "o1", "x"},
+				{"other", "value", "o1", "2"},
+			},
+			wantKeys: []string{"items", "other"},
+		},
+		{
+			name:      "new sheet",
+			selection: MergeSelection{SheetIDs: []uuid.UUID{testId("source new")}},
+			want: [][4]string{
+				{"items", "value", "r1", "1"},
+				{"other", "value", "o1", "1"},
+			},
+			wantKeys: []string{"items", "other", "new"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			plan := planMerge(base, source, target, tc.selection.include(source, target), testId("target branch"))
+			merged := plan.mergedBase()
+
+			for _, cell := range tc.want {
+				if got := value(merged, cell[0], cell[1], cell[2]); got != cell[3] {
+					t.Errorf("%s.%s of %s = %q, want %q", cell[0], cell[1], cell[2], got, cell[3])
+				}
+			}
+			keys := make([]uuid.UUID, len(tc.wantKeys))
+			for i, key := range tc.wantKeys {
+				keys[i] = testId(key)
+			}
+			if !slices.Equal(merged.sheetKeys, keys) {
+				t.Errorf("sheet keys = %v, want %v", merged.sheetKeys, keys)
+			}
+			if got := value(plan.base.mergeSide, "items", "value", "r1"); got != "1" {
+				t.Errorf("merged base changed the old base to %q", got)
+			}
+		})
+	}
+
+	t.Run("sheet deleted in source", func(t *testing.T) {
+		source := buildTestSide(itemsSheet("source", [3]string{"r1", "a", "1"}, [3]string{"r2", "b", "2"}))
+		selection := MergeSelection{SheetIDs: []uuid.UUID{testId("target other")}}
+		plan := planMerge(base, source, target, selection.include(source, target), testId("target branch"))
+		merged := plan.mergedBase()
+		if _, ok := merged.sheets[testId("other")]; ok {
+			t.Errorf("sheet deleted in the source is still in the merged base")
+		}
+		if _, ok := plan.base.sheets[testId("other")]; !ok {
+			t.Errorf("merged base changed the old base")
+		}
+	})
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// mergeLocks allows one merge per table at a time, so two merges can not
+// interleave their writes into the same branches.
+type mergeLocks struct {
+	mu     sync.Mutex
+	tables map[uuid.UUID]bool
+}
+
+func newMergeLocks() *mergeLocks {
+	return &mergeLocks{tables: make(map[uuid.UUID]bool)}
+}
+
+// tryLock returns false when a merge of the table is already running.
+func (l *mergeLocks) tryLock(tableId uuid.UUID) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.tables[tableId] {
+		return false
+	}
+	l.tables[tableId] = true
+	return true
+}
+
+func (l *mergeLocks) unlock(tableId uuid.UUID) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.tables, tableId)
+}
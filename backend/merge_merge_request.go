@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/Dass33/administratum/backend/internal/database"
+	"github.com/google/uuid"
+)
+
+type mergeMergeRequestParams struct {
+	MergeRequestId string            `json:"merge_request_id"`
+	Resolutions    []MergeResolution `json:"resolutions"`
+	Strategies     []MergeStrategy   `json:"strategies"`
+	PreviewToken   string            `json:"preview_token"`
+	KeepSource     bool              `json:"keep_source"`
+	RequireValid   bool              `json:"require_valid"`
+}
+
+func (cfg *apiConfig) mergeMergeRequestHandler(w http.ResponseWriter, r *http.Request, userId uuid.UUID) {
+	decoder := json.NewDecoder(r.Body)
+	params := mergeMergeRequestParams{}
+	err := decoder.Decode(&params)
+	if err != nil {
+		msg := fmt.Sprintf("Error decoding parameters: %s", err)
+		respondWithError(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	mergeRequestId, err := uuid.Parse(params.MergeRequestId)
+	if err != nil {
+		msg := fmt.Sprintf("Could not parse the merge request id: %s", err)
+		respondWithError(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	ctx := r.Context()
+
+	mergeRequestDb, err := cfg.db.GetMergeRequest(ctx, mergeRequestId)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Merge request not found")
+		return
+	}
+
+	if mergeRequestDb.Status != MergeRequestOpen {
+		respondWithError(w, http.StatusConflict, "Only open merge requests can be merged")
+		return
+	}
+
+	if !mergeRequestDb.SourceBranchID.Valid || !mergeRequestDb.TargetBranchID.Valid {
+		respondWithError(w, http.StatusConflict, "Branch of the merge request no longer exists")
+		return
+	}
+
+	sourceBranch, err := cfg.db.GetBranch(ctx, mergeRequestDb.SourceBranchID.UUID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Source branch not found")
+		return
+	}
+
+	targetBranch, err := cfg.db.GetBranch(ctx, mergeRequestDb.TargetBranchID.UUID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Target branch not found")
+		return
+	}
+
+	reviews, err := cfg.db.GetMergeRequestReviews(ctx, mergeRequestId)
+	if err != nil {
+		msg := fmt.Sprintf("Could not get merge request reviews: %s", err)
+		respondWithError(w, http.StatusInternalServerError, msg)
+		return
+	}
+
+	sourceToken, err := getSourceToken(ctx, cfg.db, sourceBranch.ID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	approvals, changesRequested := countReviews(reviews, mergeRequestDb.AuthorID, sourceToken)
+	if changesRequested > 0 {
+		respondWithError(w, http.StatusForbidden, "Reviewers requested changes to the merge request")
+		return
+	}
+	if approvals < targetBranch.RequiredApprovals {
+		msg := fmt.Sprintf("Merge request has %d of %d required approvals", approvals, targetBranch.RequiredApprovals)
+		respondWithError(w, http.StatusForbidden, msg)
+		return
+	}
+
+	mergeReq := MergeExecuteRequest{
+		SourceBranchID: sourceBranch.ID,
+		Resolutions:    params.Resolutions,
+		Strategies:     params.Strategies,
+		PreviewToken:   params.PreviewToken,
+		KeepSource:     params.KeepSource,
+		MergeRequestID: uuid.NullUUID{UUID: mergeRequestId, Valid: true},
+		RequireValid:   params.RequireValid,
+	}
+	setMerged := func(ctx context.Context, q *database.Queries, conflicts []MergeConflict) error {
+		conflictsJson, err := json.Marshal(conflicts)
+		if err != nil {
+			return fmt.Errorf("Could not encode conflicts: %s", err)
+		}
+		err = q.SetMergeRequestMerged(ctx, database.SetMergeRequestMergedParams{
+			Conflicts: string(conflictsJson),
+			MergedBy:  uuid.NullUUID{UUID: userId, Valid: true},
+			ID:        mergeRequestId,
+		})
+		if err != nil {
+			return fmt.Errorf("Could not update the merge request: %s", err)
+		}
+		return nil
+	}
+
+	response, ok := cfg.mergeBranches(w, userId, sourceBranch, targetBranch, mergeReq, setMerged, ctx)
+	if !ok {
+		return
+	}
+	respondWithJSON(w, http.StatusOK, response)
+}
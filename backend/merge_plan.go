@@ -0,0 +1,1165 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Dass33/administratum/backend/internal/database"
+	"github.com/google/uuid"
+)
+
+// mergeCell holds the parts of a cell the merge compares.
+type mergeCell struct {
+	value    string
+	cellType string
+}
+
+type mergeCellData struct {
+	id        uuid.UUID
+	cell      mergeCell
+	createdAt time.Time
+	updatedAt time.Time
+}
+
+// mergeRow is a row of a sheet, its times are the oldest and newest time of
+// its cells.
+type mergeRow struct {
+	id        uuid.UUID
+	idx       int64
+	createdAt time.Time
+	updatedAt time.Time
+}
+
+type mergeColumn struct {
+	id         uuid.UUID
+	name       string
+	colType    string
+	required   bool
+	isKey      bool
+	orderIndex int64
+	createdAt  time.Time
+	updatedAt  time.Time
+	// cells are keyed by the key of their row.
+	cells map[uuid.UUID]mergeCellData
+}
+
+type mergeSheet struct {
+	id        uuid.UUID
+	name      string
+	sheetType string
+	createdAt time.Time
+	updatedAt time.Time
+	columns   map[uuid.UUID]*mergeColumn
+	// columnKeys holds the column keys ordered by the order index.
+	columnKeys []uuid.UUID
+	rows       map[uuid.UUID]*mergeRow
+	// rowKeys holds the row keys ordered by idx.
+	rowKeys []uuid.UUID
+}
+
+// mergeSide is one branch of a merge. Sheets, columns and rows are keyed by
+// their origin, the id of the item the branch copies were made from.
+type mergeSide struct {
+	sheets    map[uuid.UUID]*mergeSheet
+	sheetKeys []uuid.UUID
+}
+
+// mergeBase is the state both sides are compared against. Values that can
+// not be told from the stored data are listed in unknown by conflict id and
+// conflict whenever the sides differ.
+type mergeBase struct {
+	mergeSide
+	unknown map[string]bool
+}
+
+func (base mergeBase) known(conflictId string) bool {
+	return !base.unknown[conflictId]
+}
+
+type mergeOp func(ctx context.Context, q *database.Queries, userId uuid.UUID) error
+
+type mergePlan struct {
+	conflicts []MergeConflict
+	// deletions run first, so renamed and new items can take over the
+	// names of deleted ones.
+	deletions []mergeOp
+	// resolutions apply the source side of a conflict when it is chosen,
+	// they run before the changes.
+	resolutions map[string]mergeOp
+	// customValues build the resolution of the conflicts that can be
+	// resolved with a value of their own.
+	customValues map[string]func(value string) mergeOp
+	changes      []mergeOp
+	token        string
+	summary      MergeSummary
+	// base, source and include are kept to update the base of the source
+	// after a partial merge.
+	base    mergeBase
+	source  mergeSide
+	include mergeInclude
+	// target is compared with the merged target to record the changed
+	// sheets in the history.
+	target mergeSide
+}
+
+// conflictList returns the conflicts of the plan, never nil so they encode
+// as an empty list.
+func (plan mergePlan) conflictList() []MergeConflict {
+	if plan.conflicts == nil {
+		return []MergeConflict{}
+	}
+	return plan.conflicts
+}
+
+// Delete conflicts tell which side deleted the item in their property and
+// are resolved by keeping or deleting it.
+const (
+	DeletedInSource  = "deleted_in_source"
+	DeletedInTarget  = "deleted_in_target"
+	ResolutionKeep   = "keep"
+	ResolutionDelete = "delete"
+)
+
+type mergeOutcome int
+
+const (
+	keepTarget mergeOutcome = iota
+	takeSource
+	conflicting
+)
+
+// threeWay decides a value changed on either side of the merge. Changes of
+// the source win over an untouched target and differing changes on both
+// sides conflict.
+func threeWay[T comparable](base T, baseKnown bool, source, target T) mergeOutcome {
+	switch {
+	case source == target:
+		return keepTarget
+	case !baseKnown:
+		return conflicting
+	case source == base:
+		return keepTarget
+	case target == base:
+		return takeSource
+	}
+	return conflicting
+}
+
+func sheetConflictId(sheetKey uuid.UUID, property string) string {
+	return fmt.Sprintf("sheet-%s-%s", sheetKey, property)
+}
+
+func columnConflictId(columnKey uuid.UUID, property string) string {
+	return fmt.Sprintf("column-%s-%s", columnKey, property)
+}
+
+func cellConflictId(sheetKey, columnKey, rowKey uuid.UUID) string {
+	return fmt.Sprintf("cell-%s-%s-%s", sheetKey, columnKey, rowKey)
+}
+
+func rowConflictId(sheetKey, rowKey uuid.UUID) string {
+	return fmt.Sprintf("row-%s-%s", sheetKey, rowKey)
+}
+
+// getBranchMergeRows loads the data of a branch for a merge after giving ids
+// to the rows that do not have one yet. Previews call it outside of a
+// transaction, rows numbered by a concurrent request keep their id.
+func getBranchMergeRows(ctx context.Context, q *database.Queries, branchId uuid.UUID) ([]database.GetBranchDataForMergeRow, error) {
+	err := q.EnsureSheetRows(ctx, branchId)
+	if err != nil {
+		return nil, fmt.Errorf("could not create row ids: %w", err)
+	}
+	return q.GetBranchDataForMerge(ctx, branchId)
+}
+
+// buildMergeSide keys the sheets and columns of a branch by their origin, so
+// the copies of an item in every branch share the key.
+func buildMergeSide(rows []database.GetBranchDataForMergeRow) mergeSide {
+	side := mergeSide{sheets: make(map[uuid.UUID]*mergeSheet)}
+	for _, row := range rows {
+		sheetKey := originKey(row.OriginSheetID, row.SheetID)
+
+		sheet, ok := side.sheets[sheetKey]
+		if !ok {
+			sheet = &mergeSheet{
+				id:        row.SheetID,
+				name:      row.SheetName,
+				sheetType: row.SheetType,
+				createdAt: row.SheetCreatedAt,
+				updatedAt: row.SheetUpdatedAt,
+				columns:   make(map[uuid.UUID]*mergeColumn),
+				rows:      make(map[uuid.UUID]*mergeRow),
+			}
+			side.sheets[sheetKey] = sheet
+			side.sheetKeys = append(side.sheetKeys, sheetKey)
+		}
+		if !row.ColumnID.Valid {
+			continue
+		}
+
+		columnKey := originKey(row.OriginColumnID, row.ColumnID.UUID)
+
+		column, ok := sheet.columns[columnKey]
+		if !ok {
+			column = &mergeColumn{
+				id:         row.ColumnID.UUID,
+				name:       row.ColumnName.String,
+				colType:    row.ColumnType.String,
+				required:   row.ColumnRequired.Bool,
+				isKey:      row.ColumnIsKey.Bool,
+				orderIndex: row.ColumnOrderIndex.Int64,
+				createdAt:  row.ColumnCreatedAt.Time,
+				updatedAt:  row.ColumnUpdatedAt.Time,
+				cells:      make(map[uuid.UUID]mergeCellData),
+			}
+			sheet.columns[columnKey] = column
+			sheet.columnKeys = append(sheet.columnKeys, columnKey)
+		}
+
+		if !row.ColumnDataID.Valid {
+			continue
+		}
+		cell := mergeCellData{
+			id: row.ColumnDataID.UUID,
+			cell: mergeCell{
+				value:    row.ColumnDataValue.String,
+				cellType: row.ColumnDataType.String,
+			},
+			createdAt: row.ColumnDataCreatedAt.Time,
+			updatedAt: row.ColumnDataUpdatedAt.Time,
+		}
+
+		rowId := row.RowID.UUID
+		if !row.RowID.Valid {
+			rowId = unnumberedRowId(row.SheetID, row.ColumnDataIdx.Int64)
+		}
+		rowKey := originKey(row.OriginRowID, rowId)
+		sheetRow, ok := sheet.rows[rowKey]
+		if !ok {
+			sheetRow = &mergeRow{
+				id:        rowId,
+				idx:       row.ColumnDataIdx.Int64,
+				createdAt: cell.createdAt,
+				updatedAt: cell.updatedAt,
+			}
+			sheet.rows[rowKey] = sheetRow
+			sheet.rowKeys = append(sheet.rowKeys, rowKey)
+		}
+		if cell.createdAt.Before(sheetRow.createdAt) {
+			sheetRow.createdAt = cell.createdAt
+		}
+		if cell.updatedAt.After(sheetRow.updatedAt) {
+			sheetRow.updatedAt = cell.updatedAt
+		}
+		column.cells[rowKey] = cell
+	}
+
+	for _, sheet := range side.sheets {
+		sortRowKeys(sheet)
+	}
+	return side
+}
+
+// unnumberedRowId stands in for the id of a row that was not given one yet,
+// only read-only comparisons see such rows, merges give them ids first.
+func unnumberedRowId(sheetId uuid.UUID, idx int64) uuid.UUID {
+	return uuid.NewSHA1(sheetId, []byte(strconv.FormatInt(idx, 10)))
+}
+
+func sortRowKeys(sheet *mergeSheet) {
+	slices.SortFunc(sheet.rowKeys, func(a, b uuid.UUID) int {
+		return int(sheet.rows[a].idx - sheet.rows[b].idx)
+	})
+}
+
+func originKey(origin uuid.NullUUID, id uuid.UUID) uuid.UUID {
+	if origin.Valid {
+		return origin.UUID
+	}
+	return id
+}
+
+// legacyMergeBase guesses the base of branches created before base snapshots
+// were stored. A value counts as unchanged on the side that did not touch it
+// after the fork, values both sides touched are unknown.
+func legacyMergeBase(source, target mergeSide, forkedAt time.Time) mergeBase {
+	base := mergeBase{
+		mergeSide: mergeSide{sheets: make(map[uuid.UUID]*mergeSheet)},
+		unknown:   make(map[string]bool),
+	}
+
+	for _, key := range target.sheetKeys {
+		targetSheet := target.sheets[key]
+		if targetSheet.createdAt.After(forkedAt) {
+			continue
+		}
+		sourceSheet := source.sheets[key]
+
+		baseSheet := &mergeSheet{
+			id:        key,
+			name:      targetSheet.name,
+			sheetType: targetSheet.sheetType,
+			columns:   make(map[uuid.UUID]*mergeColumn),
+			rows:      make(map[uuid.UUID]*mergeRow),
+		}
+		if targetSheet.updatedAt.After(forkedAt) {
+			if sourceSheet != nil && !sourceSheet.updatedAt.After(forkedAt) {
+				baseSheet.name = sourceSheet.name
+			} else {
+				base.unknown[sheetConflictId(key, "name")] = true
+			}
+		}
+
+		for _, rowKey := range targetSheet.rowKeys {
+			if !targetSheet.rows[rowKey].createdAt.After(forkedAt) {
+				baseSheet.rows[rowKey] = targetSheet.rows[rowKey]
+				baseSheet.rowKeys = append(baseSheet.rowKeys, rowKey)
+			}
+		}
+		// Copied rows of the source missing from the target were deleted by
+		// the target.
+		deletedRows := make(map[uuid.UUID]bool)
+		if sourceSheet != nil {
+			for _, rowKey := range sourceSheet.rowKeys {
+				sourceRow := sourceSheet.rows[rowKey]
+				if _, ok := targetSheet.rows[rowKey]; !ok && sourceRow.id != rowKey {
+					baseSheet.rows[rowKey] = sourceRow
+					baseSheet.rowKeys = append(baseSheet.rowKeys, rowKey)
+					deletedRows[rowKey] = true
+				}
+			}
+		}
+
+		targetMoved, sourceMoved := false, false
+		for _, columnKey := range targetSheet.columnKeys {
+			targetColumn := targetSheet.columns[columnKey]
+			if targetColumn.createdAt.After(forkedAt) {
+				continue
+			}
+			var sourceColumn *mergeColumn
+			if sourceSheet != nil {
+				sourceColumn = sourceSheet.columns[columnKey]
+			}
+
+			baseColumn := *targetColumn
+			baseColumn.id = columnKey
+			baseColumn.cells = make(map[uuid.UUID]mergeCellData)
+			if targetColumn.updatedAt.After(forkedAt) {
+				targetMoved = true
+				if sourceColumn != nil && !sourceColumn.updatedAt.After(forkedAt) {
+					baseColumn.name = sourceColumn.name
+					baseColumn.colType = sourceColumn.colType
+					baseColumn.required = sourceColumn.required
+					baseColumn.isKey = sourceColumn.isKey
+				} else {
+					for _, property := range columnProperties {
+						base.unknown[columnConflictId(columnKey, property.name)] = true
+					}
+				}
+			}
+			if sourceColumn != nil && sourceColumn.updatedAt.After(forkedAt) {
+				sourceMoved = true
+			}
+
+			for rowKey, targetCell := range targetColumn.cells {
+				if targetCell.createdAt.After(forkedAt) {
+					continue
+				}
+				baseCell := targetCell
+				if targetCell.updatedAt.After(forkedAt) {
+					sourceCell, ok := mergeColumnCell(sourceColumn, rowKey)
+					if ok && !sourceCell.updatedAt.After(forkedAt) {
+						baseCell = sourceCell
+					} else {
+						base.unknown[cellConflictId(key, columnKey, rowKey)] = true
+					}
+				}
+				baseColumn.cells[rowKey] = baseCell
+			}
+			for rowKey := range deletedRows {
+				sourceCell, ok := mergeColumnCell(sourceColumn, rowKey)
+				if !ok {
+					continue
+				}
+				if sourceCell.updatedAt.After(forkedAt) {
+					base.unknown[cellConflictId(key, columnKey, rowKey)] = true
+				}
+				baseColumn.cells[rowKey] = sourceCell
+			}
+
+			baseSheet.columns[columnKey] = &baseColumn
+			baseSheet.columnKeys = append(baseSheet.columnKeys, columnKey)
+		}
+
+		if targetMoved && sourceSheet != nil {
+			if sourceMoved {
+				base.unknown[sheetConflictId(key, "column_order")] = true
+			} else {
+				baseSheet.columnKeys = orderedKeys(sourceSheet.columnKeys, baseSheet.columns)
+			}
+		}
+
+		if sourceSheet != nil {
+			for _, columnKey := range sourceSheet.columnKeys {
+				if _, ok := targetSheet.columns[columnKey]; !ok && sourceSheet.columns[columnKey].id != columnKey {
+					baseSheet.columns[columnKey] = sourceSheet.columns[columnKey]
+					baseSheet.columnKeys = append(baseSheet.columnKeys, columnKey)
+				}
+			}
+		}
+
+		sortRowKeys(baseSheet)
+		base.sheets[key] = baseSheet
+		base.sheetKeys = append(base.sheetKeys, key)
+	}
+
+	// Copies in the source missing from the target existed when the source
+	// was created, so the target deleted them.
+	for _, key := range source.sheetKeys {
+		if _, ok := target.sheets[key]; !ok && source.sheets[key].id != key {
+			base.sheets[key] = source.sheets[key]
+			base.sheetKeys = append(base.sheetKeys, key)
+		}
+	}
+	return base
+}
+
+func mergeColumnCell(column *mergeColumn, rowKey uuid.UUID) (mergeCellData, bool) {
+	if column == nil {
+		return mergeCellData{}, false
+	}
+	cell, ok := column.cells[rowKey]
+	return cell, ok
+}
+
+// orderedKeys returns the keys of columns in the order of keys, keys missing
+// from columns are dropped and columns missing from keys are left out.
+func orderedKeys(keys []uuid.UUID, columns map[uuid.UUID]*mergeColumn) []uuid.UUID {
+	ordered := make([]uuid.UUID, 0, len(columns))
+	for _, key := range keys {
+		if _, ok := columns[key]; ok {
+			ordered = append(ordered, key)
+		}
+	}
+	return ordered
+}
+
+// columnUpdate collects the column properties taken from the source, the
+// column is written once after the resolutions are applied.
+type columnUpdate struct {
+	id       uuid.UUID
+	name     string
+	colType  string
+	required bool
+	changed  bool
+}
+
+type columnProperty struct {
+	name string
+	get  func(column *mergeColumn) string
+	set  func(update *columnUpdate, source *mergeColumn)
+	// setValue sets a custom value, it is nil for the properties that can
+	// not be resolved with one.
+	setValue func(update *columnUpdate, value string)
+}
+
+var columnProperties = []columnProperty{
+	{
+		name:     "name",
+		get:      func(column *mergeColumn) string { return column.name },
+		set:      func(update *columnUpdate, source *mergeColumn) { update.name = source.name },
+		setValue: func(update *columnUpdate, value string) { update.name = value },
+	},
+	{
+		name:     "type",
+		get:      func(column *mergeColumn) string { return column.colType },
+		set:      func(update *columnUpdate, source *mergeColumn) { update.colType = source.colType },
+		setValue: func(update *columnUpdate, value string) { update.colType = value },
+	},
+	{
+		name: "required",
+		get:  func(column *mergeColumn) string { return strconv.FormatBool(column.required) },
+		set:  func(update *columnUpdate, source *mergeColumn) { update.required = source.required },
+	},
+}
+
+// planMerge compares the source and the target with their common base and
+// lists the changes to bring the source changes into the target together
+// with the conflicts of changes made on both sides. Only the changes of the
+// included sheets and columns are planned.
+func planMerge(base mergeBase, source, target mergeSide, include mergeInclude, targetBranchId uuid.UUID) mergePlan {
+	plan := mergePlan{
+		resolutions:  make(map[string]mergeOp),
+		customValues: make(map[string]func(value string) mergeOp),
+		base:         base,
+		source:       source,
+		include:      include,
+		target:       target,
+	}
+
+	for _, key := range source.sheetKeys {
+		sourceSheet := source.sheets[key]
+		if targetSheet, ok := target.sheets[key]; ok {
+			plan.mergeSheet(base, key, sourceSheet, targetSheet)
+		} else if !include.sheet(key) {
+			continue
+		} else if baseSheet, inBase := base.sheets[key]; !inBase {
+			plan.changes = append(plan.changes, createSheetOp(key, sourceSheet, targetBranchId))
+			plan.summary.SheetsCreated++
+		} else if base.sheetChanged(key, baseSheet, sourceSheet) {
+			plan.addConflict(MergeConflict{
+				ID:              sheetConflictId(key, "delete"),
+				Type:            "sheet_delete",
+				SheetID:         sourceSheet.id,
+				SheetName:       sourceSheet.name,
+				Property:        DeletedInTarget,
+				BaseValue:       baseSheet.name,
+				SourceValue:     sourceSheet.name,
+				SourceUpdatedAt: sourceSheet.updatedAt,
+			}, createSheetOp(key, sourceSheet, targetBranchId))
+		}
+	}
+
+	for _, key := range target.sheetKeys {
+		if _, ok := source.sheets[key]; ok || !include.sheet(key) {
+			continue
+		}
+		baseSheet, ok := base.sheets[key]
+		if !ok {
+			continue
+		}
+		targetSheet := target.sheets[key]
+		if !base.sheetChanged(key, baseSheet, targetSheet) {
+			plan.deletions = append(plan.deletions, deleteSheetOp(targetSheet))
+			plan.summary.SheetsDeleted++
+			continue
+		}
+		plan.addConflict(MergeConflict{
+			ID:              sheetConflictId(key, "delete"),
+			Type:            "sheet_delete",
+			SheetID:         targetSheet.id,
+			SheetName:       targetSheet.name,
+			Property:        DeletedInSource,
+			BaseValue:       baseSheet.name,
+			TargetValue:     targetSheet.name,
+			TargetUpdatedAt: targetSheet.updatedAt,
+		}, deleteSheetOp(targetSheet))
+	}
+	return plan
+}
+
+func (plan *mergePlan) addConflict(conflict MergeConflict, resolution mergeOp) {
+	plan.conflicts = append(plan.conflicts, conflict)
+	plan.summary.Conflicts++
+	plan.resolutions[conflict.ID] = resolution
+}
+
+func (plan *mergePlan) mergeSheet(base mergeBase, key uuid.UUID, source, target *mergeSheet) {
+	baseSheet, inBase := base.sheets[key]
+	wholeSheet := plan.include.sheet(key)
+
+	baseName := ""
+	if inBase {
+		baseName = baseSheet.name
+	}
+	conflictId := sheetConflictId(key, "name")
+	outcome := threeWay(baseName, inBase && base.known(conflictId), source.name, target.name)
+	if !wholeSheet {
+		outcome = keepTarget
+	}
+	switch outcome {
+	case takeSource:
+		plan.changes = append(plan.changes, renameSheetOp(target.id, source.name))
+		plan.summary.SheetsUpdated++
+	case conflicting:
+		plan.addConflict(MergeConflict{
+			ID:              conflictId,
+			Type:            "sheet_property",
+			SheetID:         target.id,
+			SheetName:       source.name,
+			Property:        "name",
+			BaseValue:       baseName,
+			SourceValue:     source.name,
+			TargetValue:     target.name,
+			SourceUpdatedAt: source.updatedAt,
+			TargetUpdatedAt: target.updatedAt,
+		}, renameSheetOp(target.id, source.name))
+		plan.customValues[conflictId] = func(value string) mergeOp {
+			return renameSheetOp(target.id, value)
+		}
+	}
+
+	if inBase && wholeSheet {
+		plan.mergeColumnOrder(base, key, baseSheet, source, target)
+	}
+
+	createdColumns := make(map[uuid.UUID]*uuid.UUID)
+	for _, columnKey := range source.columnKeys {
+		if !plan.include.column(key, columnKey) {
+			continue
+		}
+		sourceColumn := source.columns[columnKey]
+		var baseColumn *mergeColumn
+		if inBase {
+			baseColumn = baseSheet.columns[columnKey]
+		}
+		if targetColumn, ok := target.columns[columnKey]; ok {
+			plan.mergeColumn(base, key, columnKey, baseColumn, sourceColumn, targetColumn, source, target)
+		} else if baseColumn == nil {
+			createdId := new(uuid.UUID)
+			createdColumns[columnKey] = createdId
+			plan.changes = append(plan.changes, createColumnOp(target.id, columnKey, sourceColumn, createdId))
+			plan.summary.ColumnsCreated++
+		} else if base.columnChanged(key, columnKey, baseColumn, sourceColumn) {
+			// The column is restored when the conflict is resolved by
+			// keeping it, its cells are then written like those of a
+			// created column.
+			createdId := new(uuid.UUID)
+			createdColumns[columnKey] = createdId
+			plan.addConflict(MergeConflict{
+				ID:              columnConflictId(columnKey, "delete"),
+				Type:            "column_delete",
+				SheetID:         target.id,
+				SheetName:       source.name,
+				ColumnID:        sourceColumn.id,
+				ColumnName:      sourceColumn.name,
+				Property:        DeletedInTarget,
+				BaseValue:       baseColumn.name,
+				SourceValue:     sourceColumn.name,
+				SourceUpdatedAt: sourceColumn.updatedAt,
+			}, createColumnOp(target.id, columnKey, sourceColumn, createdId))
+		}
+	}
+
+	for _, columnKey := range target.columnKeys {
+		if _, ok := source.columns[columnKey]; ok || !inBase || !plan.include.column(key, columnKey) {
+			continue
+		}
+		baseColumn, ok := baseSheet.columns[columnKey]
+		if !ok {
+			continue
+		}
+		targetColumn := target.columns[columnKey]
+		deleteOp := deleteColumnOp(targetColumn.id, targetColumn.name)
+		if !base.columnChanged(key, columnKey, baseColumn, targetColumn) {
+			plan.deletions = append(plan.deletions, deleteOp)
+			plan.summary.ColumnsDeleted++
+			continue
+		}
+		plan.addConflict(MergeConflict{
+			ID:              columnConflictId(columnKey, "delete"),
+			Type:            "column_delete",
+			SheetID:         target.id,
+			SheetName:       source.name,
+			ColumnID:        targetColumn.id,
+			ColumnName:      targetColumn.name,
+			Property:        DeletedInSource,
+			BaseValue:       baseColumn.name,
+			TargetValue:     targetColumn.name,
+			TargetUpdatedAt: targetColumn.updatedAt,
+		}, deleteOp)
+	}
+
+	plan.mergeCreatedColumnCells(baseSheet, source, target, createdColumns)
+	if wholeSheet {
+		plan.mergeKeyColumn(baseSheet, inBase, key, source, target, createdColumns)
+		plan.mergeRows(base, key, baseSheet, source, target, createdColumns)
+	}
+}
+
+// mergeColumnOrder compares the order of the columns found on all three
+// sides and moves the target columns into the source order when only the
+// source reordered them.
+func (plan *mergePlan) mergeColumnOrder(base mergeBase, key uuid.UUID, baseSheet, source, target *mergeSheet) {
+	common := make(map[uuid.UUID]*mergeColumn)
+	for columnKey, column := range baseSheet.columns {
+		_, inSource := source.columns[columnKey]
+		_, inTarget := target.columns[columnKey]
+		if inSource && inTarget {
+			common[columnKey] = column
+		}
+	}
+
+	baseOrder := orderedKeys(baseSheet.columnKeys, common)
+	sourceOrder := orderedKeys(source.columnKeys, common)
+	targetOrder := orderedKeys(target.columnKeys, common)
+
+	conflictId := sheetConflictId(key, "column_order")
+	outcome := threeWay(joinKeys(baseOrder), base.known(conflictId), joinKeys(sourceOrder), joinKeys(targetOrder))
+	if outcome == keepTarget {
+		return
+	}
+
+	indexes := make([]int64, len(targetOrder))
+	for i, columnKey := range targetOrder {
+		indexes[i] = target.columns[columnKey].orderIndex
+	}
+	op := func(ctx context.Context, q *database.Queries, userId uuid.UUID) error {
+		for i, columnKey := range sourceOrder {
+			err := q.SetColumnOrderIndex(ctx, database.SetColumnOrderIndexParams{
+				OrderIndex: indexes[i],
+				ID:         target.columns[columnKey].id,
+			})
+			if err != nil {
+				return fmt.Errorf("failed to reorder columns of sheet %s: %v", target.name, err)
+			}
+		}
+		return nil
+	}
+
+	if outcome == takeSource {
+		plan.changes = append(plan.changes, op)
+		plan.summary.SheetsUpdated++
+		return
+	}
+	plan.addConflict(MergeConflict{
+		ID:              conflictId,
+		Type:            "sheet_property",
+		SheetID:         target.id,
+		SheetName:       source.name,
+		Property:        "column_order",
+		BaseValue:       columnNames(baseOrder, target),
+		SourceValue:     columnNames(sourceOrder, source),
+		TargetValue:     columnNames(targetOrder, target),
+		SourceUpdatedAt: source.updatedAt,
+		TargetUpdatedAt: target.updatedAt,
+	}, op)
+}
+
+func joinKeys(keys []uuid.UUID) string {
+	parts := make([]string, len(keys))
+	for i := range keys {
+		parts[i] = keys[i].String()
+	}
+	return strings.Join(parts, ",")
+}
+
+func columnNames(keys []uuid.UUID, sheet *mergeSheet) string {
+	names := make([]string, len(keys))
+	for i := range keys {
+		names[i] = sheet.columns[keys[i]].name
+	}
+	return strings.Join(names, ", ")
+}
+
+// mergeKeyColumn merges the key column as a property of the sheet, as a
+// sheet only has one of them.
+func (plan *mergePlan) mergeKeyColumn(baseSheet *mergeSheet, inBase bool, key uuid.UUID, source, target *mergeSheet, createdColumns map[uuid.UUID]*uuid.UUID) {
+	baseKey := uuid.Nil
+	if inBase {
+		baseKey = keyColumn(baseSheet)
+	}
+	sourceKey := keyColumn(source)
+	targetKey := keyColumn(target)
+
+	outcome := threeWay(baseKey, inBase, sourceKey, targetKey)
+	if outcome == keepTarget {
+		return
+	}
+
+	op := func(ctx context.Context, q *database.Queries, userId uuid.UUID) error {
+		columnId := uuid.Nil
+		if column, ok := target.columns[sourceKey]; ok {
+			columnId = column.id
+		} else if createdId, ok := createdColumns[sourceKey]; ok {
+			columnId = *createdId
+		}
+		err := q.SetKeyColumn(ctx, database.SetKeyColumnParams{
+			SheetID: target.id,
+			ID:      columnId,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to set key column of sheet %s: %v", target.name, err)
+		}
+		return nil
+	}
+
+	if outcome == takeSource {
+		plan.changes = append(plan.changes, op)
+		plan.summary.SheetsUpdated++
+		return
+	}
+	plan.addConflict(MergeConflict{
+		ID:              sheetConflictId(key, "key_column"),
+		Type:            "sheet_property",
+		SheetID:         target.id,
+		SheetName:       source.name,
+		Property:        "key_column",
+		BaseValue:       keyColumnName(baseSheet, baseKey),
+		SourceValue:     keyColumnName(source, sourceKey),
+		TargetValue:     keyColumnName(target, targetKey),
+		SourceUpdatedAt: source.updatedAt,
+		TargetUpdatedAt: target.updatedAt,
+	}, op)
+}
+
+func keyColumn(sheet *mergeSheet) uuid.UUID {
+	for _, columnKey := range sheet.columnKeys {
+		if sheet.columns[columnKey].isKey {
+			return columnKey
+		}
+	}
+	return uuid.Nil
+}
+
+func keyColumnName(sheet *mergeSheet, columnKey uuid.UUID) string {
+	if sheet == nil || columnKey == uuid.Nil {
+		return ""
+	}
+	return sheet.columns[columnKey].name
+}
+
+// mergeColumn merges the properties of a column found on both sides and its
+// cells in the rows found on both sides, the other rows are left to
+// mergeRows.
+func (plan *mergePlan) mergeColumn(base mergeBase, sheetKey, key uuid.UUID, baseColumn, source, target *mergeColumn, sourceSheet, targetSheet *mergeSheet) {
+	sheetId := targetSheet.id
+	sheetName := sourceSheet.name
+	update := &columnUpdate{
+		id:       target.id,
+		name:     target.name,
+		colType:  target.colType,
+		required: target.required,
+	}
+	hasUpdate, taken := false, false
+
+	for _, property := range columnProperties {
+		baseValue := ""
+		if baseColumn != nil {
+			baseValue = property.get(baseColumn)
+		}
+		conflictId := columnConflictId(key, property.name)
+		baseKnown := baseColumn != nil && base.known(conflictId)
+
+		set := property.set
+		resolution := func(ctx context.Context, q *database.Queries, userId uuid.UUID) error {
+			set(update, source)
+			update.changed = true
+			return nil
+		}
+
+		switch threeWay(baseValue, baseKnown, property.get(source), property.get(target)) {
+		case takeSource:
+			set(update, source)
+			update.changed = true
+			hasUpdate = true
+			taken = true
+		case conflicting:
+			hasUpdate = true
+			plan.addConflict(MergeConflict{
+				ID:              conflictId,
+				Type:            "column_property",
+				SheetID:         sheetId,
+				SheetName:       sheetName,
+				ColumnID:        target.id,
+				ColumnName:      source.name,
+				Property:        property.name,
+				BaseValue:       baseValue,
+				SourceValue:     property.get(source),
+				TargetValue:     property.get(target),
+				SourceUpdatedAt: source.updatedAt,
+				TargetUpdatedAt: target.updatedAt,
+			}, resolution)
+			if setValue := property.setValue; setValue != nil {
+				plan.customValues[conflictId] = func(value string) mergeOp {
+					return func(ctx context.Context, q *database.Queries, userId uuid.UUID) error {
+						setValue(update, value)
+						update.changed = true
+						return nil
+					}
+				}
+			}
+		}
+	}
+
+	if taken {
+		plan.summary.ColumnsUpdated++
+	}
+	if hasUpdate {
+		plan.changes = append(plan.changes, func(ctx context.Context, q *database.Queries, userId uuid.UUID) error {
+			if !update.changed {
+				return nil
+			}
+			err := q.UpdateColumn(ctx, database.UpdateColumnParams{
+				Name:     update.name,
+				Type:     update.colType,
+				Required: update.required,
+				ID:       update.id,
+			})
+			if err != nil {
+				return fmt.Errorf("failed to update column %s: %v", update.name, err)
+			}
+			return nil
+		})
+	}
+
+	columnId := target.id
+	for _, rowKey := range sourceSheet.rowKeys {
+		targetRow, ok := targetSheet.rows[rowKey]
+		if !ok {
+			continue
+		}
+		var baseCell mergeCell
+		if baseColumn != nil {
+			baseCell = baseColumn.cells[rowKey].cell
+		}
+		sourceCell := source.cells[rowKey]
+		targetCell := target.cells[rowKey]
+
+		conflictId := cellConflictId(sheetKey, key, rowKey)
+		baseKnown := baseColumn != nil && base.known(conflictId)
+		outcome := threeWay(baseCell, baseKnown, sourceCell.cell, targetCell.cell)
+		if outcome == keepTarget {
+			continue
+		}
+
+		rowId := targetRow.id
+		op := setCellOp(&columnId, &rowId, targetCell, sourceCell.cell)
+		if outcome == takeSource {
+			plan.changes = append(plan.changes, op)
+			plan.summary.CellsUpdated++
+			continue
+		}
+		rowIndex := targetRow.idx
+		plan.addConflict(MergeConflict{
+			ID:              conflictId,
+			Type:            "cell_data",
+			SheetID:         sheetId,
+			SheetName:       sheetName,
+			ColumnID:        target.id,
+			ColumnName:      source.name,
+			RowID:           targetRow.id,
+			RowIndex:        &rowIndex,
+			BaseValue:       baseCell.value,
+			SourceValue:     sourceCell.cell.value,
+			TargetValue:     targetCell.cell.value,
+			SourceUpdatedAt: sourceCell.updatedAt,
+			TargetUpdatedAt: targetCell.updatedAt,
+		}, op)
+		cellType := targetCell.cell.cellType
+		if cellType == "" {
+			cellType = sourceCell.cell.cellType
+		}
+		plan.customValues[conflictId] = func(value string) mergeOp {
+			return setCellOp(&columnId, &rowId, targetCell, mergeCell{value: value, cellType: cellType})
+		}
+	}
+}
+
+// cellRowKeys returns the keys of the rows any of the columns has a cell in.
+func cellRowKeys(columns ...*mergeColumn) []uuid.UUID {
+	seen := make(map[uuid.UUID]bool)
+	var rowKeys []uuid.UUID
+	for _, column := range columns {
+		if column == nil {
+			continue
+		}
+		for rowKey := range column.cells {
+			if !seen[rowKey] {
+				seen[rowKey] = true
+				rowKeys = append(rowKeys, rowKey)
+			}
+		}
+	}
+	return rowKeys
+}
+
+// sheetChanged reports whether a side changed the sheet since the base, a
+// sheet deleted on the other side conflicts when it did.
+func (base mergeBase) sheetChanged(key uuid.UUID, baseSheet, side *mergeSheet) bool {
+	if !base.known(sheetConflictId(key, "name")) || baseSheet.name != side.name {
+		return true
+	}
+	if len(baseSheet.columns) != len(side.columns) {
+		return true
+	}
+	for columnKey, baseColumn := range baseSheet.columns {
+		sideColumn, ok := side.columns[columnKey]
+		if !ok || base.columnChanged(key, columnKey, baseColumn, sideColumn) {
+			return true
+		}
+	}
+	return false
+}
+
+func (base mergeBase) columnChanged(sheetKey, key uuid.UUID, baseColumn, side *mergeColumn) bool {
+	for _, property := range columnProperties {
+		if !base.known(columnConflictId(key, property.name)) || property.get(baseColumn) != property.get(side) {
+			return true
+		}
+	}
+	for _, rowKey := range cellRowKeys(baseColumn, side) {
+		if !base.known(cellConflictId(sheetKey, key, rowKey)) || baseColumn.cells[rowKey].cell != side.cells[rowKey].cell {
+			return true
+		}
+	}
+	return false
+}
+
+func nullCellType(cellType string) sql.NullString {
+	return sql.NullString{String: cellType, Valid: cellType != ""}
+}
+
+// setCellOp writes a cell of the target. The column and row are passed by
+// reference as they may only be created while the merge runs, the idx of the
+// row is read when the cell is created as deleted rows shift it. Nothing is
+// written into a column the merge did not create after all.
+func setCellOp(columnId, rowId *uuid.UUID, target mergeCellData, cell mergeCell) mergeOp {
+	return func(ctx context.Context, q *database.Queries, userId uuid.UUID) error {
+		value := sql.NullString{String: cell.value, Valid: true}
+		if target.id == uuid.Nil && *columnId == uuid.Nil {
+			return nil
+		}
+		if target.id != uuid.Nil {
+			err := q.UpdateColumnData(ctx, database.UpdateColumnDataParams{
+				Value: value,
+				Type:  nullCellType(cell.cellType),
+				ID:    target.id,
+			})
+			if err != nil {
+				return fmt.Errorf("failed to update cell data: %v", err)
+			}
+			return nil
+		}
+
+		row, err := q.GetSheetRow(ctx, *rowId)
+		if err != nil {
+			return fmt.Errorf("failed to get row of cell: %v", err)
+		}
+		_, err = q.CreateColumnData(ctx, database.CreateColumnDataParams{
+			Idx:      row.Idx,
+			Value:    value,
+			Type:     nullCellType(cell.cellType),
+			ColumnID: *columnId,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create cell data: %v", err)
+		}
+		return nil
+	}
+}
+
+func renameSheetOp(sheetId uuid.UUID, name string) mergeOp {
+	return func(ctx context.Context, q *database.Queries, userId uuid.UUID) error {
+		err := q.RenameSheet(ctx, database.RenameSheetParams{
+			ID:   sheetId,
+			Name: name,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to update sheet name: %v", err)
+		}
+		return nil
+	}
+}
+
+// createSheetOp copies a sheet of the source into the target, the copy keeps
+// the origin of the source sheet, its columns and rows so later merges match
+// them up.
+func createSheetOp(key uuid.UUID, source *mergeSheet, targetBranchId uuid.UUID) mergeOp {
+	return func(ctx context.Context, q *database.Queries, userId uuid.UUID) error {
+		sheet, err := q.CreateSheet(ctx, database.CreateSheetParams{
+			BranchID:      targetBranchId,
+			Name:          source.name,
+			Type:          source.sheetType,
+			SourceSheetID: sql.NullString{String: source.id.String(), Valid: true},
+			OriginSheetID: uuid.NullUUID{UUID: key, Valid: true},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create sheet %s: %v", source.name, err)
+		}
+
+		idxs := make(map[uuid.UUID]int64, len(source.rowKeys))
+		for i, rowKey := range source.rowKeys {
+			_, err = q.CreateSheetRow(ctx, database.CreateSheetRowParams{
+				SheetID:     sheet.ID,
+				Idx:         int64(i),
+				OriginRowID: uuid.NullUUID{UUID: rowKey, Valid: true},
+			})
+			if err != nil {
+				return fmt.Errorf("failed to create row of sheet %s: %v", source.name, err)
+			}
+			idxs[rowKey] = int64(i)
+		}
+
+		for _, columnKey := range source.columnKeys {
+			column := source.columns[columnKey]
+			columnId, err := createColumn(ctx, q, sheet.ID, columnKey, column, column.isKey)
+			if err != nil {
+				return err
+			}
+			for _, rowKey := range source.rowKeys {
+				cell, ok := column.cells[rowKey]
+				if !ok {
+					continue
+				}
+				_, err = q.CreateColumnData(ctx, database.CreateColumnDataParams{
+					Idx:      idxs[rowKey],
+					Value:    sql.NullString{String: cell.cell.value, Valid: true},
+					Type:     nullCellType(cell.cell.cellType),
+					ColumnID: columnId,
+				})
+				if err != nil {
+					return fmt.Errorf("failed to copy column data: %v", err)
+				}
+			}
+		}
+		return nil
+	}
+}
+
+// createColumnOp creates a column of the source without its cells, they are
+// written by mergeRows.
+func createColumnOp(sheetId, key uuid.UUID, source *mergeColumn, createdId *uuid.UUID) mergeOp {
+	return func(ctx context.Context, q *database.Queries, userId uuid.UUID) error {
+		columnId, err := createColumn(ctx, q, sheetId, key, source, false)
+		*createdId = columnId
+		return err
+	}
+}
+
+func createColumn(ctx context.Context, q *database.Queries, sheetId, key uuid.UUID, source *mergeColumn, isKey bool) (uuid.UUID, error) {
+	column, err := q.AddColumn(ctx, database.AddColumnParams{
+		Name:           source.name,
+		Type:           source.colType,
+		Required:       source.required,
+		SheetID:        sheetId,
+		SourceColumnID: sql.NullString{String: source.id.String(), Valid: true},
+		IsKey:          isKey,
+		OriginColumnID: uuid.NullUUID{UUID: key, Valid: true},
+	})
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to create column %s: %v", source.name, err)
+	}
+	return column.ID, nil
+}
+
+func deleteSheetOp(target *mergeSheet) mergeOp {
+	return func(ctx context.Context, q *database.Queries, userId uuid.UUID) error {
+		err := q.TrashSheet(ctx, database.TrashSheetParams{
+			DeletedBy: uuid.NullUUID{UUID: userId, Valid: true},
+			ID:        target.id,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to delete sheet %s: %v", target.name, err)
+		}
+		return nil
+	}
+}
+
+func deleteColumnOp(columnId uuid.UUID, name string) mergeOp {
+	return func(ctx context.Context, q *database.Queries, userId uuid.UUID) error {
+		err := q.TrashColumn(ctx, database.TrashColumnParams{
+			DeletedBy: uuid.NullUUID{UUID: userId, Valid: true},
+			ID:        columnId,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to delete column %s: %v", name, err)
+		}
+		return nil
+	}
+}
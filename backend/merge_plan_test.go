@@ -0,0 +1,237 @@
+package main
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func testId(name string) uuid.UUID {
+	return uuid.NewSHA1(uuid.NameSpaceOID, []byte(name))
+}
+
+// sheetSpec describes a sheet of one side of a merge. The key of the sheet,
+// its columns and rows is the same on every side, the id of the sheet is not.
+type sheetSpec struct {
+	key     string
+	id      string
+	name    string
+	rows    []string
+	columns []columnSpec
+}
+
+type columnSpec struct {
+	name  string
+	isKey bool
+	// cells are pairs of a row and its value.
+	cells []string
+}
+
+func testColumnKey(sheetKey, name string) uuid.UUID {
+	return testId("column:" + sheetKey + ":" + name)
+}
+
+func testRowKey(row string) uuid.UUID {
+	return testId("row:" + row)
+}
+
+func buildTestSide(specs ...sheetSpec) mergeSide {
+	side := mergeSide{sheets: make(map[uuid.UUID]*mergeSheet)}
+	for _, spec := range specs {
+		sheet := &mergeSheet{
+			id:      testId(spec.id),
+			name:    spec.name,
+			columns: make(map[uuid.UUID]*mergeColumn),
+			rows:    make(map[uuid.UUID]*mergeRow),
+		}
+		for i, row := range spec.rows {
+			rowKey := testRowKey(row)
+			sheet.rows[rowKey] = &mergeRow{id: rowKey, idx: int64(i)}
+			sheet.rowKeys = append(sheet.rowKeys, rowKey)
+		}
+		for i, columnSpec := range spec.columns {
+			columnKey := testColumnKey(spec.key, columnSpec.name)
+			column := &mergeColumn{
+				id:         testId(spec.id + ":" + columnSpec.name),
+				name:       columnSpec.name,
+				colType:    "text",
+				isKey:      columnSpec.isKey,
+				orderIndex: int64(i),
+				cells:      make(map[uuid.UUID]mergeCellData),
+			}
+			for c := 0; c+1 < len(columnSpec.cells); c += 2 {
+				rowKey := testRowKey(columnSpec.cells[c])
+				column.cells[rowKey] = mergeCellData{
+					id:   testId(spec.id + ":" + columnSpec.name + ":" + columnSpec.cells[c]),
+					cell: mergeCell{value: columnSpec.cells[c+1]},
+				}
+			}
+			sheet.columns[columnKey] = column
+			sheet.columnKeys = append(sheet.columnKeys, columnKey)
+		}
+		key := testId(spec.key)
+		side.sheets[key] = sheet
+		side.sheetKeys = append(side.sheetKeys, key)
+	}
+	return side
+}
+
+// itemsSheet is a sheet keyed by its name column, every row is given as its
+// row, name and value.
+func itemsSheet(id string, rows ...[3]string) sheetSpec {
+	spec := sheetSpec{key: "items", id: id, name: "items"}
+	name := columnSpec{name: "name", isKey: true}
+	value := columnSpec{name: "value"}
+	for _, row := range rows {
+		spec.rows = append(spec.rows, row[0])
+		name.cells = append(name.cells, row[0], row[1])
+		value.cells = append(value.cells, row[0], row[2])
+	}
+	spec.columns = []columnSpec{name, value}
+	return spec
+}
+
+func withoutColumn(spec sheetSpec, name string) sheetSpec {
+	spec.columns = slices.DeleteFunc(slices.Clone(spec.columns), func(column columnSpec) bool {
+		return column.name == name
+	})
+	return spec
+}
+
+func keyedBy(spec sheetSpec, name string) sheetSpec {
+	columns := slices.Clone(spec.columns)
+	for i := range columns {
+		columns[i].isKey = columns[i].name == name
+	}
+	spec.columns = columns
+	return spec
+}
+
+func renamedSheet(spec sheetSpec, name string) sheetSpec {
+	spec.name = name
+	return spec
+}
+
+func testBase(specs ...sheetSpec) mergeBase {
+	return mergeBase{mergeSide: buildTestSide(specs...), unknown: make(map[string]bool)}
+}
+
+func conflictKinds(conflicts []MergeConflict) []string {
+	kinds := make([]string, 0, len(conflicts))
+	for _, conflict := range conflicts {
+		kind := conflict.Type
+		if conflict.Property != "" {
+			kind += " " + conflict.Property
+		}
+		kinds = append(kinds, kind)
+	}
+	return kinds
+}
+
+var baseItems = itemsSheet("base", [3]string{"r1", "a", "1"}, [3]string{"r2", "b", "2"})
+
+func TestThreeWay(t *testing.T) {
+	cases := []struct {
+		name                 string
+		base, source, target string
+		baseKnown            bool
+		want                 mergeOutcome
+	}{
+		{"unchanged", "a", "a", "a", true, keepTarget},
+		{"changed in source", "a", "b", "a", true, takeSource},
+		{"changed in target", "a", "a", "b", true, keepTarget},
+		{"same change on both sides", "a", "b", "b", true, keepTarget},
+		{"different changes", "a", "b", "c", true, conflicting},
+		{"source reverted to base", "a", "a", "c", true, keepTarget},
+		{"target reverted to base", "a", "b", "a", true, takeSource},
+		{"unknown base with equal sides", "a", "b", "b", false, keepTarget},
+		{"unknown base with different sides", "a", "a", "b", false, conflicting},
+	}
+	for _, tc := range cases {
+		got := threeWay(tc.base, tc.baseKnown, tc.source, tc.target)
+		if got != tc.want {
+			t.Errorf("%s: threeWay(%q, %v, %q, %q) = %d, want %d", tc.name, tc.base, tc.baseKnown, tc.source, tc.target, got, tc.want)
+		}
+	}
+}
+
+func TestPlanMerge(t *testing.T) {
+	cases := []struct {
+		name          string
+		base          []sheetSpec
+		source        []sheetSpec
+		target        []sheetSpec
+		wantConflicts []string
+		wantSummary   MergeSummary
+	}{
+		{
+			name:        "cell changed in source",
+			base:        []sheetSpec{baseItems},
+			source:      []sheetSpec{itemsSheet("source", [3]string{"r1", "a", "10"}, [3]string{"r2", "b", "2"})},
+			target:      []sheetSpec{itemsSheet("target", [3]string{"r1", "a", "1"}, [3]string{"r2", "b", "2"})},
+			wantSummary: MergeSummary{CellsUpdated: 1},
+		},
+		{
+			name:   "source reverted to base",
+			base:   []sheetSpec{baseItems},
+			source: []sheetSpec{itemsSheet("source", [3]string{"r1", "a", "1"}, [3]string{"r2", "b", "2"})},
+			target: []sheetSpec{itemsSheet("target", [3]string{"r1", "a", "5"}, [3]string{"r2", "b", "2"})},
+		},
+		{
+			name:          "cell changed on both sides",
+			base:          []sheetSpec{baseItems},
+			source:        []sheetSpec{itemsSheet("source", [3]string{"r1", "a", "10"}, [3]string{"r2", "b", "2"})},
+			target:        []sheetSpec{itemsSheet("target", [3]string{"r1", "a", "20"}, [3]string{"r2", "b", "2"})},
+			wantConflicts: []string{"cell_data"},
+			wantSummary:   MergeSummary{Conflicts: 1},
+		},
+		{
+			name:        "source deletes an unchanged column",
+			base:        []sheetSpec{baseItems},
+			source:      []sheetSpec{withoutColumn(itemsSheet("source", [3]string{"r1", "a", "1"}, [3]string{"r2", "b", "2"}), "value")},
+			target:      []sheetSpec{itemsSheet("target", [3]string{"r1", "a", "1"}, [3]string{"r2", "b", "2"})},
+			wantSummary: MergeSummary{ColumnsDeleted: 1},
+		},
+		{
+			name:          "sheet renamed on both sides",
+			base:          []sheetSpec{baseItems},
+			source:        []sheetSpec{renamedSheet(itemsSheet("source", [3]string{"r1", "a", "1"}, [3]string{"r2", "b", "2"}), "things")},
+			target:        []sheetSpec{renamedSheet(itemsSheet("target", [3]string{"r1", "a", "1"}, [3]string{"r2", "b", "2"}), "goods")},
+			wantConflicts: []string{"sheet_property name"},
+			wantSummary:   MergeSummary{Conflicts: 1},
+		},
+		{
+			name:          "target deletes a sheet the source modified",
+			base:          []sheetSpec{baseItems},
+			source:        []sheetSpec{itemsSheet("source", [3]string{"r1", "a", "10"}, [3]string{"r2", "b", "2"})},
+			wantConflicts: []string{"sheet_delete " + DeletedInTarget},
+			wantSummary:   MergeSummary{Conflicts: 1},
+		},
+		{
+			name:        "source deletes an unchanged sheet",
+			base:        []sheetSpec{baseItems},
+			target:      []sheetSpec{itemsSheet("target", [3]string{"r1", "a", "1"}, [3]string{"r2", "b", "2"})},
+			wantSummary: MergeSummary{SheetsDeleted: 1},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			plan := planMerge(testBase(tc.base...), buildTestSide(tc.source...), buildTestSide(tc.target...), mergeInclude{}, testId("target branch"))
+
+			got := conflictKinds(plan.conflicts)
+			if !slices.Equal(got, tc.wantConflicts) {
+				t.Errorf("conflicts = %v, want %v", got, tc.wantConflicts)
+			}
+			if plan.summary != tc.wantSummary {
+				t.Errorf("summary = %+v, want %+v", plan.summary, tc.wantSummary)
+			}
+			for _, conflict := range plan.conflicts {
+				if plan.resolutions[conflict.ID] == nil {
+					t.Errorf("conflict %s has no resolution", conflict.ID)
+				}
+			}
+		})
+	}
+}
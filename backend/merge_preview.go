@@ -2,6 +2,8 @@ package main
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -13,17 +15,24 @@ import (
 
 type MergePreviewRequest struct {
 	SourceBranchID uuid.UUID `json:"source_branch_id"`
+	// TargetBranchID defaults to the oldest branch of the table.
+	TargetBranchID uuid.NullUUID `json:"target_branch_id"`
+	MergeSelection
 }
 
 type MergeConflict struct {
-	ID              string    `json:"id"`
-	Type            string    `json:"type"`
-	SheetID         uuid.UUID `json:"sheet_id"`
-	SheetName       string    `json:"sheet_name"`
-	ColumnID        uuid.UUID `json:"column_id,omitempty"`
-	ColumnName      string    `json:"column_name,omitempty"`
-	RowIndex        *int64    `json:"row_index,omitempty"`
+	ID         string    `json:"id"`
+	Type       string    `json:"type"`
+	SheetID    uuid.UUID `json:"sheet_id"`
+	SheetName  string    `json:"sheet_name"`
+	ColumnID   uuid.UUID `json:"column_id,omitempty"`
+	ColumnName string    `json:"column_name,omitempty"`
+	RowID      uuid.UUID `json:"row_id,omitempty"`
+	RowIndex   *int64    `json:"row_index,omitempty"`
+	// Property is the changed property, or for the sheet_delete,
+	// column_delete and row_delete conflicts the side that deleted the item.
 	Property        string    `json:"property,omitempty"`
+	BaseValue       string    `json:"base_value"`
 	SourceValue     string    `json:"source_value"`
 	TargetValue     string    `json:"target_value"`
 	SourceUpdatedAt time.Time `json:"source_updated_at"`
@@ -32,6 +41,9 @@ type MergeConflict struct {
 
 type MergePreviewResponse struct {
 	Conflicts []MergeConflict `json:"conflicts"`
+	// PreviewToken is passed to the merge to reject it when either branch
+	// changed since the preview.
+	PreviewToken string `json:"preview_token"`
 }
 
 func (cfg *apiConfig) mergePreviewHandler(w http.ResponseWriter, r *http.Request, userId uuid.UUID) {
@@ -49,9 +61,8 @@ func (cfg *apiConfig) mergePreviewHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	targetBranch, err := cfg.db.GetOldestBranchFromTable(ctx, sourceBranch.TableID)
-	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Could not find target branch (oldest branch in table)")
+	targetBranch, ok := cfg.getMergeTarget(w, sourceBranch, req.TargetBranchID, ctx)
+	if !ok {
 		return
 	}
 
@@ -60,206 +71,112 @@ func (cfg *apiConfig) mergePreviewHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	if !cfg.checkBranchPermission(userId, targetBranch.ID, "write", ctx) {
+	if !cfg.checkBranchPermission(userId, targetBranch.ID, "merge", ctx) {
 		respondWithError(w, http.StatusForbidden, "No write permission on target branch")
 		return
 	}
 
-	sourceData, err := cfg.db.GetBranchDataForMerge(ctx, req.SourceBranchID)
-	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Could not get source branch data")
-		return
-	}
-
-	targetData, err := cfg.db.GetBranchDataForMerge(ctx, targetBranch.ID)
+	plan, err := cfg.getMergePlan(sourceBranch, targetBranch, req.MergeSelection, ctx)
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Could not get target branch data")
+		respondWithError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	conflicts := cfg.detectMergeConflicts(sourceData, targetData, sourceBranch.CreatedAt)
-
-	if conflicts == nil {
-		conflicts = []MergeConflict{}
-	}
-
 	response := MergePreviewResponse{
-		Conflicts: conflicts,
+		Conflicts:    plan.conflictList(),
+		PreviewToken: plan.token,
 	}
 
 	respondWithJSON(w, http.StatusOK, response)
 }
 
-type mergeData struct {
-	sheets   map[uuid.UUID]database.GetBranchDataForMergeRow
-	columns  map[uuid.UUID]database.GetBranchDataForMergeRow
-	cellData map[string]database.GetBranchDataForMergeRow
-}
-
-func buildMergeData(data []database.GetBranchDataForMergeRow) mergeData {
-	sheets := make(map[uuid.UUID]database.GetBranchDataForMergeRow)
-	columns := make(map[uuid.UUID]database.GetBranchDataForMergeRow)
-	cellData := make(map[string]database.GetBranchDataForMergeRow)
-
-	for _, row := range data {
-		sheets[row.SheetID] = row
-		if row.ColumnID.Valid {
-			columns[row.ColumnID.UUID] = row
-			if row.ColumnDataID.Valid {
-				var sheetKey, columnKey string
-
-				if row.SourceSheetID.Valid {
-					sheetKey = row.SourceSheetID.String
-				} else {
-					sheetKey = row.SheetID.String()
-				}
-
-				if row.SourceColumnID.Valid {
-					columnKey = row.SourceColumnID.String
-				} else {
-					columnKey = row.ColumnID.UUID.String()
-				}
-				key := fmt.Sprintf("%s-%s-%d", sheetKey, columnKey, row.ColumnDataIdx.Int64)
-				cellData[key] = row
-			}
+// getMergeTarget loads the branch the source is merged into, the oldest
+// branch of the table when no target is given. It writes the error response
+// itself and returns false when there is no valid target.
+func (cfg *apiConfig) getMergeTarget(w http.ResponseWriter, sourceBranch database.Branch, targetBranchId uuid.NullUUID, ctx context.Context) (database.Branch, bool) {
+	var targetBranch database.Branch
+	var err error
+	if targetBranchId.Valid {
+		targetBranch, err = cfg.db.GetBranch(ctx, targetBranchId.UUID)
+		if err != nil || targetBranch.TableID != sourceBranch.TableID {
+			respondWithError(w, http.StatusNotFound, "Target branch not found")
+			return database.Branch{}, false
+		}
+	} else {
+		targetBranch, err = cfg.db.GetOldestBranchFromTable(ctx, sourceBranch.TableID)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Could not find target branch (oldest branch in table)")
+			return database.Branch{}, false
 		}
 	}
 
-	return mergeData{
-		sheets:   sheets,
-		columns:  columns,
-		cellData: cellData,
+	if targetBranch.ID == sourceBranch.ID {
+		respondWithError(w, http.StatusBadRequest, "Branch can not be merged into itself")
+		return database.Branch{}, false
 	}
+	return targetBranch, true
 }
 
-func detectSheetConflicts(sourceData, targetData mergeData, branchCreatedAt time.Time) []MergeConflict {
-	var conflicts []MergeConflict
-
-	for sheetId, sourceSheet := range sourceData.sheets {
-		targetSheet, exists := targetData.sheets[sheetId]
-		if !exists {
-			continue
-		}
-
-		if sourceSheet.SheetUpdatedAt.After(branchCreatedAt) &&
-			targetSheet.SheetUpdatedAt.After(branchCreatedAt) {
-			conflicts = append(conflicts, MergeConflict{
-				ID:              fmt.Sprintf("sheet-%s", sheetId.String()),
-				Type:            "sheet_property",
-				SheetID:         sheetId,
-				SheetName:       sourceSheet.SheetName,
-				Property:        "name",
-				SourceValue:     sourceSheet.SheetName,
-				TargetValue:     targetSheet.SheetName,
-				SourceUpdatedAt: sourceSheet.SheetUpdatedAt,
-				TargetUpdatedAt: targetSheet.SheetUpdatedAt,
-			})
-		}
-	}
-
-	return conflicts
+// getMergePlan compares both branches with their common base, limited to
+// the selected sheets and columns.
+func (cfg *apiConfig) getMergePlan(sourceBranch, targetBranch database.Branch, selection MergeSelection, ctx context.Context) (mergePlan, error) {
+	return cfg.getMergePlanWithTx(cfg.db, sourceBranch, targetBranch, selection, ctx)
 }
 
-func detectColumnConflicts(sourceData, targetData mergeData, branchCreatedAt time.Time) []MergeConflict {
-	var conflicts []MergeConflict
-
-	for columnId, sourceColumn := range sourceData.columns {
-		targetColumn, exists := targetData.columns[columnId]
-		if !exists {
-			continue
-		}
-
-		if sourceColumn.ColumnUpdatedAt.Valid && targetColumn.ColumnUpdatedAt.Valid &&
-			sourceColumn.ColumnUpdatedAt.Time.After(branchCreatedAt) &&
-			targetColumn.ColumnUpdatedAt.Time.After(branchCreatedAt) {
+func (cfg *apiConfig) getMergePlanWithTx(txQueries *database.Queries, sourceBranch, targetBranch database.Branch, selection MergeSelection, ctx context.Context) (mergePlan, error) {
+	sourceData, err := getBranchMergeRows(ctx, txQueries, sourceBranch.ID)
+	if err != nil {
+		return mergePlan{}, fmt.Errorf("Could not get source branch data: %s", err)
+	}
 
-			if sourceColumn.ColumnName.String != targetColumn.ColumnName.String {
-				conflicts = append(conflicts, MergeConflict{
-					ID:              fmt.Sprintf("column-%s-name", columnId.String()),
-					Type:            "column_property",
-					SheetID:         sourceColumn.SheetID,
-					SheetName:       sourceColumn.SheetName,
-					ColumnID:        columnId,
-					ColumnName:      sourceColumn.ColumnName.String,
-					Property:        "name",
-					SourceValue:     sourceColumn.ColumnName.String,
-					TargetValue:     targetColumn.ColumnName.String,
-					SourceUpdatedAt: sourceColumn.ColumnUpdatedAt.Time,
-					TargetUpdatedAt: targetColumn.ColumnUpdatedAt.Time,
-				})
-			}
+	targetData, err := getBranchMergeRows(ctx, txQueries, targetBranch.ID)
+	if err != nil {
+		return mergePlan{}, fmt.Errorf("Could not get target branch data: %s", err)
+	}
 
-			if sourceColumn.ColumnType.String != targetColumn.ColumnType.String {
-				conflicts = append(conflicts, MergeConflict{
-					ID:              fmt.Sprintf("column-%s-type", columnId.String()),
-					Type:            "column_property",
-					SheetID:         sourceColumn.SheetID,
-					SheetName:       sourceColumn.SheetName,
-					ColumnID:        columnId,
-					ColumnName:      sourceColumn.ColumnName.String,
-					Property:        "type",
-					SourceValue:     sourceColumn.ColumnType.String,
-					TargetValue:     targetColumn.ColumnType.String,
-					SourceUpdatedAt: sourceColumn.ColumnUpdatedAt.Time,
-					TargetUpdatedAt: targetColumn.ColumnUpdatedAt.Time,
-				})
-			}
-		}
+	source := buildMergeSide(sourceData)
+	target := buildMergeSide(targetData)
+	base, err := cfg.getMergeBase(txQueries, sourceBranch, targetBranch, source, target, ctx)
+	if err != nil {
+		return mergePlan{}, err
 	}
 
-	return conflicts
+	plan := planMerge(base, source, target, selection.include(source, target), targetBranch.ID)
+	plan.token = mergeToken(sourceData, targetData)
+	return plan, nil
 }
 
-func detectCellDataConflicts(sourceData, targetData mergeData, branchCreatedAt time.Time) []MergeConflict {
-	var conflicts []MergeConflict
-
-	for key, sourceCell := range sourceData.cellData {
-		if targetCell, exists := targetData.cellData[key]; exists {
-			if sourceCell.ColumnDataUpdatedAt.Valid && targetCell.ColumnDataUpdatedAt.Valid &&
-				sourceCell.ColumnDataUpdatedAt.Time.After(branchCreatedAt) &&
-				targetCell.ColumnDataUpdatedAt.Time.After(branchCreatedAt) {
-
-				sourceValue := ""
-				if sourceCell.ColumnDataValue.Valid {
-					sourceValue = sourceCell.ColumnDataValue.String
-				}
+// mergeToken hashes the data of both branches, it changes with every write
+// to either of them.
+func mergeToken(sourceData, targetData []database.GetBranchDataForMergeRow) string {
+	return hashBranchData(sourceData, targetData)
+}
 
-				targetValue := ""
-				if targetCell.ColumnDataValue.Valid {
-					targetValue = targetCell.ColumnDataValue.String
-				}
+// getSourceToken hashes the data of the source branch of a merge request,
+// reviews keep it to tell whether they were given on the current data.
+func getSourceToken(ctx context.Context, q *database.Queries, sourceBranchId uuid.UUID) (string, error) {
+	sourceData, err := getBranchMergeRows(ctx, q, sourceBranchId)
+	if err != nil {
+		return "", fmt.Errorf("Could not get source branch data: %s", err)
+	}
+	return hashBranchData(sourceData), nil
+}
 
-				if sourceValue != targetValue {
-					rowIndex := sourceCell.ColumnDataIdx.Int64
-					conflicts = append(conflicts, MergeConflict{
-						ID:              fmt.Sprintf("cell-%s", key),
-						Type:            "cell_data",
-						SheetID:         sourceCell.SheetID,
-						SheetName:       sourceCell.SheetName,
-						ColumnID:        sourceCell.ColumnID.UUID,
-						ColumnName:      sourceCell.ColumnName.String,
-						RowIndex:        &rowIndex,
-						SourceValue:     sourceValue,
-						TargetValue:     targetValue,
-						SourceUpdatedAt: sourceCell.ColumnDataUpdatedAt.Time,
-						TargetUpdatedAt: targetCell.ColumnDataUpdatedAt.Time,
-					})
-				}
-			}
+func hashBranchData(branches ...[]database.GetBranchDataForMergeRow) string {
+	hash := sha256.New()
+	for _, rows := range branches {
+		for _, row := range rows {
+			fmt.Fprintf(hash, "%v\n", row)
 		}
+		hash.Write([]byte{0})
 	}
-
-	return conflicts
+	return hex.EncodeToString(hash.Sum(nil))
 }
 
-func (cfg *apiConfig) detectMergeConflicts(sourceData, targetData []database.GetBranchDataForMergeRow, branchCreatedAt time.Time) []MergeConflict {
-	sourceMergeData := buildMergeData(sourceData)
-	targetMergeData := buildMergeData(targetData)
-
-	var conflicts []MergeConflict
-	conflicts = append(conflicts, detectSheetConflicts(sourceMergeData, targetMergeData, branchCreatedAt)...)
-	conflicts = append(conflicts, detectColumnConflicts(sourceMergeData, targetMergeData, branchCreatedAt)...)
-	conflicts = append(conflicts, detectCellDataConflicts(sourceMergeData, targetMergeData, branchCreatedAt)...)
-
-	return conflicts
+func (cfg *apiConfig) getMergeConflicts(sourceBranch, targetBranch database.Branch, ctx context.Context) ([]MergeConflict, error) {
+	plan, err := cfg.getMergePlan(sourceBranch, targetBranch, MergeSelection{}, ctx)
+	if err != nil {
+		return nil, err
+	}
+	return plan.conflictList(), nil
 }
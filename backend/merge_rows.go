@@ -0,0 +1,301 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/Dass33/administratum/backend/internal/database"
+	"github.com/google/uuid"
+)
+
+// rowCell is a cell of a row inserted into the target, the column is passed
+// by reference as it may be created by the same merge. Cells of a column
+// that is not created, as its delete conflict was resolved by deleting it,
+// are skipped.
+type rowCell struct {
+	columnId *uuid.UUID
+	cell     mergeCell
+}
+
+// mergeCreatedColumnCells writes the cells of the columns the merge creates
+// in the rows found on both sides.
+func (plan *mergePlan) mergeCreatedColumnCells(baseSheet, source, target *mergeSheet, createdColumns map[uuid.UUID]*uuid.UUID) {
+	if len(createdColumns) == 0 {
+		return
+	}
+	for _, rowKey := range source.rowKeys {
+		targetRow, ok := target.rows[rowKey]
+		if !ok {
+			continue
+		}
+		rowId := targetRow.id
+		for _, columnKey := range source.columnKeys {
+			createdId, ok := createdColumns[columnKey]
+			if !ok {
+				continue
+			}
+			if cell, ok := source.columns[columnKey].cells[rowKey]; ok {
+				plan.changes = append(plan.changes, setCellOp(createdId, &rowId, mergeCellData{}, cell.cell))
+				// Cells of columns restored by a conflict are not
+				// counted, they are only written when it is resolved so.
+				if baseSheet == nil || baseSheet.columns[columnKey] == nil {
+					plan.summary.CellsUpdated++
+				}
+			}
+		}
+	}
+}
+
+// mergeRows brings over the rows the source inserted or deleted. Cells of
+// rows found on both sides are merged by mergeColumn and
+// mergeCreatedColumnCells.
+func (plan *mergePlan) mergeRows(base mergeBase, sheetKey uuid.UUID, baseSheet, source, target *mergeSheet, createdColumns map[uuid.UUID]*uuid.UUID) {
+	inBase := func(rowKey uuid.UUID) bool {
+		if baseSheet == nil {
+			return false
+		}
+		_, ok := baseSheet.rows[rowKey]
+		return ok
+	}
+	targetInserted := make(map[string]uuid.UUID)
+	if keyColumn(source) == keyColumn(target) {
+		targetInserted = insertedRowsByKey(target, inBase)
+	}
+
+	for _, rowKey := range source.rowKeys {
+		sourceRow := source.rows[rowKey]
+		if _, ok := target.rows[rowKey]; ok {
+			continue
+		}
+
+		cells := rowCells(source, target, rowKey, createdColumns)
+		if !inBase(rowKey) {
+			if targetKey, ok := targetInserted[rowKeyValue(source, rowKey)]; ok {
+				plan.addRowInsertConflict(sheetKey, rowKey, targetKey, source, target, createdColumns)
+				continue
+			}
+			plan.changes = append(plan.changes, insertRowOp(target.id, rowKey, cells))
+			plan.summary.RowsInserted++
+			continue
+		}
+
+		if !base.rowChanged(sheetKey, baseSheet, source, rowKey) {
+			continue
+		}
+		rowIndex := sourceRow.idx
+		plan.addConflict(MergeConflict{
+			ID:              rowConflictId(sheetKey, rowKey),
+			Type:            "row_delete",
+			SheetID:         target.id,
+			SheetName:       source.name,
+			RowID:           sourceRow.id,
+			RowIndex:        &rowIndex,
+			Property:        DeletedInTarget,
+			BaseValue:       rowValues(baseSheet, rowKey),
+			SourceValue:     rowValues(source, rowKey),
+			SourceUpdatedAt: sourceRow.updatedAt,
+		}, insertRowOp(target.id, rowKey, cells))
+	}
+
+	for _, rowKey := range target.rowKeys {
+		if _, ok := source.rows[rowKey]; ok || !inBase(rowKey) {
+			continue
+		}
+		targetRow := target.rows[rowKey]
+		if !base.rowChanged(sheetKey, baseSheet, target, rowKey) {
+			plan.deletions = append(plan.deletions, deleteRowOp(target.id, targetRow.id))
+			plan.summary.RowsDeleted++
+			continue
+		}
+		rowIndex := targetRow.idx
+		plan.addConflict(MergeConflict{
+			ID:              rowConflictId(sheetKey, rowKey),
+			Type:            "row_delete",
+			SheetID:         target.id,
+			SheetName:       source.name,
+			RowID:           targetRow.id,
+			RowIndex:        &rowIndex,
+			Property:        DeletedInSource,
+			BaseValue:       rowValues(baseSheet, rowKey),
+			TargetValue:     rowValues(target, rowKey),
+			TargetUpdatedAt: targetRow.updatedAt,
+		}, deleteRowOp(target.id, targetRow.id))
+	}
+}
+
+// addRowInsertConflict reports a row both sides inserted with the same key,
+// taking the source writes its values into the row of the target.
+func (plan *mergePlan) addRowInsertConflict(sheetKey, rowKey, targetKey uuid.UUID, source, target *mergeSheet, createdColumns map[uuid.UUID]*uuid.UUID) {
+	sourceRow := source.rows[rowKey]
+	targetRow := target.rows[targetKey]
+	rowId := targetRow.id
+
+	var ops []mergeOp
+	for _, columnKey := range source.columnKeys {
+		sourceCell, ok := source.columns[columnKey].cells[rowKey]
+		if !ok {
+			continue
+		}
+		if targetColumn, ok := target.columns[columnKey]; ok {
+			targetCell := targetColumn.cells[targetKey]
+			if targetCell.cell == sourceCell.cell {
+				continue
+			}
+			columnId := targetColumn.id
+			ops = append(ops, setCellOp(&columnId, &rowId, targetCell, sourceCell.cell))
+		} else if createdId, ok := createdColumns[columnKey]; ok {
+			ops = append(ops, setCellOp(createdId, &rowId, mergeCellData{}, sourceCell.cell))
+		}
+	}
+
+	rowIndex := targetRow.idx
+	plan.addConflict(MergeConflict{
+		ID:              rowConflictId(sheetKey, rowKey),
+		Type:            "row_insert",
+		SheetID:         target.id,
+		SheetName:       source.name,
+		ColumnName:      target.columns[keyColumn(target)].name,
+		RowID:           targetRow.id,
+		RowIndex:        &rowIndex,
+		SourceValue:     rowValues(source, rowKey),
+		TargetValue:     rowValues(target, targetKey),
+		SourceUpdatedAt: sourceRow.updatedAt,
+		TargetUpdatedAt: targetRow.updatedAt,
+	}, func(ctx context.Context, q *database.Queries, userId uuid.UUID) error {
+		for _, op := range ops {
+			if err := op(ctx, q, userId); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// insertedRowsByKey maps the value in the key column of the rows the side
+// inserted since the base to their row key. Sheets without a key column
+// have no rows that could clash.
+func insertedRowsByKey(sheet *mergeSheet, inBase func(uuid.UUID) bool) map[string]uuid.UUID {
+	rows := make(map[string]uuid.UUID)
+	if keyColumn(sheet) == uuid.Nil {
+		return rows
+	}
+	for _, rowKey := range sheet.rowKeys {
+		if value := rowKeyValue(sheet, rowKey); value != "" && !inBase(rowKey) {
+			rows[value] = rowKey
+		}
+	}
+	return rows
+}
+
+func rowKeyValue(sheet *mergeSheet, rowKey uuid.UUID) string {
+	columnKey := keyColumn(sheet)
+	if columnKey == uuid.Nil {
+		return ""
+	}
+	return sheet.columns[columnKey].cells[rowKey].cell.value
+}
+
+// rowCells lists the cells of a source row in the columns of the target,
+// cells of columns the target deleted are dropped.
+func rowCells(source, target *mergeSheet, rowKey uuid.UUID, createdColumns map[uuid.UUID]*uuid.UUID) []rowCell {
+	var cells []rowCell
+	for _, columnKey := range source.columnKeys {
+		cell, ok := source.columns[columnKey].cells[rowKey]
+		if !ok {
+			continue
+		}
+		if targetColumn, ok := target.columns[columnKey]; ok {
+			columnId := targetColumn.id
+			cells = append(cells, rowCell{columnId: &columnId, cell: cell.cell})
+		} else if createdId, ok := createdColumns[columnKey]; ok {
+			cells = append(cells, rowCell{columnId: createdId, cell: cell.cell})
+		}
+	}
+	return cells
+}
+
+func rowValues(sheet *mergeSheet, rowKey uuid.UUID) string {
+	if sheet == nil {
+		return ""
+	}
+	values := make([]string, 0, len(sheet.columnKeys))
+	for _, columnKey := range sheet.columnKeys {
+		values = append(values, sheet.columns[columnKey].cells[rowKey].cell.value)
+	}
+	return strings.Join(values, ", ")
+}
+
+// rowChanged reports whether the side changed a row since the base, a row
+// deleted on the other side is only deleted when it did not.
+func (base mergeBase) rowChanged(sheetKey uuid.UUID, baseSheet, side *mergeSheet, rowKey uuid.UUID) bool {
+	for columnKey, sideColumn := range side.columns {
+		baseColumn, ok := baseSheet.columns[columnKey]
+		if !ok {
+			if sideColumn.cells[rowKey].cell.value != "" {
+				return true
+			}
+			continue
+		}
+		if !base.known(cellConflictId(sheetKey, columnKey, rowKey)) ||
+			baseColumn.cells[rowKey].cell != sideColumn.cells[rowKey].cell {
+			return true
+		}
+	}
+	return false
+}
+
+// insertRowOp appends a row to the sheet of the target, it keeps the key of
+// the source row as its origin.
+func insertRowOp(sheetId, rowKey uuid.UUID, cells []rowCell) mergeOp {
+	return func(ctx context.Context, q *database.Queries, userId uuid.UUID) error {
+		idx, err := q.GetNextRowIdx(ctx, sheetId)
+		if err != nil {
+			return fmt.Errorf("failed to get next row: %v", err)
+		}
+		_, err = q.CreateSheetRow(ctx, database.CreateSheetRowParams{
+			SheetID:     sheetId,
+			Idx:         idx,
+			OriginRowID: uuid.NullUUID{UUID: rowKey, Valid: true},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create row: %v", err)
+		}
+
+		for _, cell := range cells {
+			if *cell.columnId == uuid.Nil {
+				continue
+			}
+			_, err = q.CreateColumnData(ctx, database.CreateColumnDataParams{
+				Idx:      idx,
+				Value:    sql.NullString{String: cell.cell.value, Valid: true},
+				Type:     nullCellType(cell.cell.cellType),
+				ColumnID: *cell.columnId,
+			})
+			if err != nil {
+				return fmt.Errorf("failed to create cell data: %v", err)
+			}
+		}
+		return nil
+	}
+}
+
+// deleteRowOp deletes a row of the target by its id, its idx is read when the
+// merge runs as earlier deletions shift it.
+func deleteRowOp(sheetId, rowId uuid.UUID) mergeOp {
+	return func(ctx context.Context, q *database.Queries, userId uuid.UUID) error {
+		row, err := q.GetSheetRow(ctx, rowId)
+		if err != nil {
+			return fmt.Errorf("failed to get row: %v", err)
+		}
+		err = q.DeleteRow(ctx, database.DeleteRowParams{
+			SheetID: sheetId,
+			Idx:     row.Idx,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to delete row %d: %v", row.Idx, err)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestMergeRows(t *testing.T) {
+	cases := []struct {
+		name          string
+		source        sheetSpec
+		target        sheetSpec
+		wantConflicts []string
+		wantSummary   MergeSummary
+	}{
+		{
+			name:        "row inserted in source",
+			source:      itemsSheet("source", [3]string{"r1", "a", "1"}, [3]string{"r2", "b", "2"}, [3]string{"r3", "c", "3"}),
+			target:      itemsSheet("target", [3]string{"r1", "a", "1"}, [3]string{"r2", "b", "2"}),
+			wantSummary: MergeSummary{RowsInserted: 1},
+		},
+		{
+			name:          "row inserted on both sides with the same key",
+			source:        itemsSheet("source", [3]string{"r1", "a", "1"}, [3]string{"r2", "b", "2"}, [3]string{"r3", "c", "3"}),
+			target:        itemsSheet("target", [3]string{"r1", "a", "1"}, [3]string{"r2", "b", "2"}, [3]string{"r4", "c", "4"}),
+			wantConflicts: []string{"row_insert"},
+			wantSummary:   MergeSummary{Conflicts: 1},
+		},
+		{
+			name:        "empty keys never clash",
+			source:      itemsSheet("source", [3]string{"r1", "a", "1"}, [3]string{"r2", "b", "2"}, [3]string{"r3", "", "3"}),
+			target:      itemsSheet("target", [3]string{"r1", "a", "1"}, [3]string{"r2", "b", "2"}, [3]string{"r4", "", "4"}),
+			wantSummary: MergeSummary{RowsInserted: 1},
+		},
+		{
+			name:        "sides keyed by different columns",
+			source:      itemsSheet("source", [3]string{"r1", "a", "1"}, [3]string{"r2", "b", "2"}, [3]string{"r3", "c", "3"}),
+			target:      keyedBy(itemsSheet("target", [3]string{"r1", "a", "1"}, [3]string{"r2", "b", "2"}, [3]string{"r4", "c", "3"}), "value"),
+			wantSummary: MergeSummary{RowsInserted: 1},
+		},
+		{
+			name:        "unchanged row deleted in source",
+			source:      itemsSheet("source", [3]string{"r1", "a", "1"}),
+			target:      itemsSheet("target", [3]string{"r1", "a", "1"}, [3]string{"r2", "b", "2"}),
+			wantSummary: MergeSummary{RowsDeleted: 1},
+		},
+		{
+			name:          "row deleted in source and modified in target",
+			source:        itemsSheet("source", [3]string{"r1", "a", "1"}),
+			target:        itemsSheet("target", [3]string{"r1", "a", "1"}, [3]string{"r2", "b", "3"}),
+			wantConflicts: []string{"row_delete " + DeletedInSource},
+			wantSummary:   MergeSummary{Conflicts: 1},
+		},
+		{
+			name:          "row deleted in target and modified in source",
+			source:        itemsSheet("source", [3]string{"r1", "a", "1"}, [3]string{"r2", "b", "3"}),
+			target:        itemsSheet("target", [3]string{"r1", "a", "1"}),
+			wantConflicts: []string{"row_delete " + DeletedInTarget},
+			wantSummary:   MergeSummary{Conflicts: 1},
+		},
+		{
+			name:   "unchanged row deleted in target",
+			source: itemsSheet("source", [3]string{"r1", "a", "1"}, [3]string{"r2", "b", "2"}),
+			target: itemsSheet("target", [3]string{"r1", "a", "1"}),
+		},
+	}
+
+	sheetKey := testId("items")
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			base := testBase(baseItems)
+			source := buildTestSide(tc.source).sheets[sheetKey]
+			target := buildTestSide(tc.target).sheets[sheetKey]
+			plan := mergePlan{
+				resolutions:  make(map[string]mergeOp),
+				customValues: make(map[string]func(value string) mergeOp),
+			}
+			plan.mergeRows(base, sheetKey, base.sheets[sheetKey], source, target, map[uuid.UUID]*uuid.UUID{})
+
+			got := conflictKinds(plan.conflicts)
+			if !slices.Equal(got, tc.wantConflicts) {
+				t.Errorf("conflicts = %v, want %v", got, tc.wantConflicts)
+			}
+			if plan.summary != tc.wantSummary {
+				t.Errorf("summary = %+v, want %+v", plan.summary, tc.wantSummary)
+			}
+		})
+	}
+}
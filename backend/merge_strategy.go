@@ -0,0 +1,150 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+const (
+	ResolutionCustom = "custom"
+
+	PreferSource = "source"
+	PreferTarget = "target"
+	PreferNewest = "newest"
+)
+
+// MergeStrategy resolves the conflicts the resolutions leave open, only
+// those of one sheet when SheetID is set. SheetID can be the id of the sheet
+// on either branch. Strategies of a sheet go before the ones for all sheets.
+type MergeStrategy struct {
+	SheetID uuid.NullUUID `json:"sheet_id"`
+	// Prefer is "source", "target" or "newest".
+	Prefer string `json:"prefer"`
+}
+
+// resolveConflicts picks the resolution of every conflict of the plan from
+// the resolutions and strategies of the request. Custom values replace the
+// source side of their conflict in the plan. It returns the resolutions to
+// record and the side every conflict takes, or an error when a conflict is
+// left unresolved.
+func resolveConflicts(plan *mergePlan, requested []MergeResolution, strategies []MergeStrategy) ([]MergeResolution, map[string]string, error) {
+	for _, strategy := range strategies {
+		switch strategy.Prefer {
+		case PreferSource, PreferTarget, PreferNewest:
+		default:
+			return nil, nil, fmt.Errorf("Unknown merge strategy %q", strategy.Prefer)
+		}
+	}
+
+	requestedMap := make(map[string]MergeResolution, len(requested))
+	for _, resolution := range requested {
+		requestedMap[resolution.ConflictID] = resolution
+	}
+
+	sheetKeys := mergeSheetKeys(plan.source, plan.target)
+	resolutions := make([]MergeResolution, 0, len(plan.conflicts))
+	sides := make(map[string]string, len(plan.conflicts))
+	for _, conflict := range plan.conflicts {
+		resolution, ok := requestedMap[conflict.ID]
+		if !ok || resolution.ChosenSource == "" {
+			resolution = MergeResolution{
+				ConflictID:   conflict.ID,
+				ChosenSource: strategyChoice(conflict, strategies, sheetKeys),
+			}
+		}
+
+		if resolution.ChosenSource == ResolutionCustom {
+			customValue, ok := plan.customValues[conflict.ID]
+			if !ok {
+				return nil, nil, fmt.Errorf("Conflict %s can not be resolved with a custom value", conflict.ID)
+			}
+			if resolution.Value == nil {
+				return nil, nil, fmt.Errorf("Custom resolution of conflict %s is missing its value", conflict.ID)
+			}
+			if *resolution.Value == "" && conflict.Type != "cell_data" {
+				return nil, nil, fmt.Errorf("Custom value of conflict %s can not be empty", conflict.ID)
+			}
+			plan.resolutions[conflict.ID] = customValue(*resolution.Value)
+			plan.summary.ConflictsCustom++
+			sides[conflict.ID] = "source"
+			resolutions = append(resolutions, resolution)
+			continue
+		}
+
+		side := conflictSide(conflict, resolution.ChosenSource)
+		switch side {
+		case "source":
+			plan.summary.ConflictsSource++
+		case "target":
+			plan.summary.ConflictsTarget++
+		default:
+			return nil, nil, errors.New("All conflicts must be resolved")
+		}
+		sides[conflict.ID] = side
+		resolutions = append(resolutions, MergeResolution{ConflictID: conflict.ID, ChosenSource: resolution.ChosenSource})
+	}
+	return resolutions, sides, nil
+}
+
+// mergeSheetKeys maps the sheet ids of the sides to their sheet key. A sheet
+// has a different id on every branch, strategies and conflicts can name
+// either of them.
+func mergeSheetKeys(sides ...mergeSide) map[uuid.UUID]uuid.UUID {
+	keys := make(map[uuid.UUID]uuid.UUID)
+	for _, side := range sides {
+		for _, key := range side.sheetKeys {
+			keys[side.sheets[key].id] = key
+		}
+	}
+	return keys
+}
+
+func sheetKey(sheetKeys map[uuid.UUID]uuid.UUID, sheetId uuid.UUID) uuid.UUID {
+	if key, ok := sheetKeys[sheetId]; ok {
+		return key
+	}
+	return sheetId
+}
+
+// strategyChoice returns the resolution the strategies choose for the
+// conflict, or an empty string when none of them applies. Sheets are matched
+// by their key in sheetKeys.
+func strategyChoice(conflict MergeConflict, strategies []MergeStrategy, sheetKeys map[uuid.UUID]uuid.UUID) string {
+	conflictSheet := sheetKey(sheetKeys, conflict.SheetID)
+	prefer := ""
+	for _, strategy := range strategies {
+		if strategy.SheetID.Valid && sheetKey(sheetKeys, strategy.SheetID.UUID) == conflictSheet {
+			prefer = strategy.Prefer
+			break
+		}
+		if !strategy.SheetID.Valid && prefer == "" {
+			prefer = strategy.Prefer
+		}
+	}
+
+	if prefer == PreferNewest {
+		// Only the side that changed a deleted item has a time to compare,
+		// so the newest change is kept.
+		if conflict.Property == DeletedInSource || conflict.Property == DeletedInTarget {
+			return ResolutionKeep
+		}
+		if conflict.SourceUpdatedAt.After(conflict.TargetUpdatedAt) {
+			return "source"
+		}
+		return "target"
+	}
+
+	if prefer == "" || (conflict.Property != DeletedInSource && conflict.Property != DeletedInTarget) {
+		return prefer
+	}
+	deletedBy := PreferSource
+	if conflict.Property == DeletedInTarget {
+		deletedBy = PreferTarget
+	}
+	if prefer == deletedBy {
+		return ResolutionDelete
+	}
+	return ResolutionKeep
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	itemsCellConflict = cellConflictId(testId("items"), testColumnKey("items", "value"), testRowKey("r1"))
+	itemsRowConflict  = rowConflictId(testId("items"), testRowKey("r2"))
+)
+
+func TestResolveConflicts(t *testing.T) {
+	custom := "15"
+	cellPlan := func() mergePlan {
+		return planMerge(
+			testBase(baseItems),
+			buildTestSide(itemsSheet("source", [3]string{"r1", "a", "10"}, [3]string{"r2", "b", "2"})),
+			buildTestSide(itemsSheet("target", [3]string{"r1", "a", "20"}, [3]string{"r2", "b", "2"})),
+			mergeInclude{}, testId("target branch"),
+		)
+	}
+	rowPlan := func() mergePlan {
+		return planMerge(
+			testBase(baseItems),
+			buildTestSide(itemsSheet("source", [3]string{"r1", "a", "1"})),
+			buildTestSide(itemsSheet("target", [3]string{"r1", "a", "1"}, [3]string{"r2", "b", "3"})),
+			mergeInclude{}, testId("target branch"),
+		)
+	}
+
+	cases := []struct {
+		name        string
+		plan        func() mergePlan
+		resolutions []MergeResolution
+		strategies  []MergeStrategy
+		wantSides   map[string]string
+		wantSummary MergeSummary
+		wantErr     bool
+	}{
+		{
+			name:        "source chosen",
+			plan:        cellPlan,
+			resolutions: []MergeResolution{{ConflictID: itemsCellConflict, ChosenSource: "source"}},
+			wantSides:   map[string]string{itemsCellConflict: "source"},
+			wantSummary: MergeSummary{Conflicts: 1, ConflictsSource: 1},
+		},
+		{
+			name:        "target chosen",
+			plan:        cellPlan,
+			resolutions: []MergeResolution{{ConflictID: itemsCellConflict, ChosenSource: "target"}},
+			wantSides:   map[string]string{itemsCellConflict: "target"},
+			wantSummary: MergeSummary{Conflicts: 1, ConflictsTarget: 1},
+		},
+		{
+			name:        "custom value",
+			plan:        cellPlan,
+			resolutions: []MergeResolution{{ConflictID: itemsCellConflict, ChosenSource: ResolutionCustom, Value: &custom}},
+			wantSides:   map[string]string{itemsCellConflict: "source"},
+			wantSummary: MergeSummary{Conflicts: 1, ConflictsCustom: 1},
+		},
+		{
+			name:        "custom value missing",
+			plan:        cellPlan,
+			resolutions: []MergeResolution{{ConflictID: itemsCellConflict, ChosenSource: ResolutionCustom}},
+			wantErr:     true,
+		},
+		{
+			name:        "resolution goes before the strategy",
+			plan:        cellPlan,
+			resolutions: []MergeResolution{{ConflictID: itemsCellConflict, ChosenSource: "source"}},
+			strategies:  []MergeStrategy{{Prefer: PreferTarget}},
+			wantSides:   map[string]string{itemsCellConflict: "source"},
+			wantSummary: MergeSummary{Conflicts: 1, ConflictsSource: 1},
+		},
+		{
+			name:        "strategy for all sheets",
+			plan:        cellPlan,
+			strategies:  []MergeStrategy{{Prefer: PreferTarget}},
+			wantSides:   map[string]string{itemsCellConflict: "target"},
+			wantSummary: MergeSummary{Conflicts: 1, ConflictsTarget: 1},
+		},
+		{
+			name:    "unresolved conflict",
+			plan:    cellPlan,
+			wantErr: true,
+		},
+		{
+			name:       "unknown strategy",
+			plan:       cellPlan,
+			strategies: []MergeStrategy{{Prefer: "oldest"}},
+			wantErr:    true,
+		},
+		{
+			name:        "deleted row kept",
+			plan:        rowPlan,
+			resolutions: []MergeResolution{{ConflictID: itemsRowConflict, ChosenSource: ResolutionKeep}},
+			wantSides:   map[string]string{itemsRowConflict: "target"},
+			wantSummary: MergeSummary{Conflicts: 1, ConflictsTarget: 1},
+		},
+		{
+			name:        "deleted row deleted by the source strategy",
+			plan:        rowPlan,
+			strategies:  []MergeStrategy{{Prefer: PreferSource}},
+			wantSides:   map[string]string{itemsRowConflict: "source"},
+			wantSummary: MergeSummary{Conflicts: 1, ConflictsSource: 1},
+		},
+		{
+			name:        "deleted row can not take a custom value",
+			plan:        rowPlan,
+			resolutions: []MergeResolution{{ConflictID: itemsRowConflict, ChosenSource: ResolutionCustom, Value: &custom}},
+			wantErr:     true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			plan := tc.plan()
+			resolutions, sides, err := resolveConflicts(&plan, tc.resolutions, tc.strategies)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got sides %v", sides)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(resolutions) != len(plan.conflicts) {
+				t.Errorf("got %d resolutions for %d conflicts", len(resolutions), len(plan.conflicts))
+			}
+			for id, want := range tc.wantSides {
+				if sides[id] != want {
+					t.Errorf("side of %s = %q, want %q", id, sides[id], want)
+				}
+			}
+			if plan.summary != tc.wantSummary {
+				t.Errorf("summary = %+v, want %+v", plan.summary, tc.wantSummary)
+			}
+		})
+	}
+}
+
+func TestStrategyChoice(t *testing.T) {
+	source := buildTestSide(itemsSheet("source"), sheetSpec{key: "other", id: "source other", name: "other"})
+	target := buildTestSide(itemsSheet("target"), sheetSpec{key: "other", id: "target other", name: "other"})
+	sheetKeys := mergeSheetKeys(source, target)
+
+	now := time.Now()
+	cellConflict := MergeConflict{
+		SheetID:         testId("target"),
+		SourceUpdatedAt: now,
+		TargetUpdatedAt: now.Add(-time.Hour),
+	}
+	// Sheets the target deleted are named by their id in the source.
+	sheetDeleted := MergeConflict{SheetID: testId("source"), Property: DeletedInTarget}
+	rowDeleted := MergeConflict{SheetID: testId("target"), Property: DeletedInSource}
+
+	forSheet := func(id string, prefer string) MergeStrategy {
+		return MergeStrategy{SheetID: uuid.NullUUID{UUID: testId(id), Valid: true}, Prefer: prefer}
+	}
+
+	cases := []struct {
+		name       string
+		conflict   MergeConflict
+		strategies []MergeStrategy
+		want       string
+	}{
+		{"no strategy", cellConflict, nil, ""},
+		{"all sheets", cellConflict, []MergeStrategy{{Prefer: PreferSource}}, "source"},
+		{"sheet by target id", cellConflict, []MergeStrategy{{Prefer: PreferSource}, forSheet("target", PreferTarget)}, "target"},
+		{"sheet by source id", cellConflict, []MergeStrategy{{Prefer: PreferSource}, forSheet("source", PreferTarget)}, "target"},
+		{"source sheet id of a deleted sheet", sheetDeleted, []MergeStrategy{forSheet("target", PreferSource)}, ResolutionKeep},
+		{"other sheet", cellConflict, []MergeStrategy{forSheet("target other", PreferTarget), {Prefer: PreferSource}}, "source"},
+		{"only other sheets", cellConflict, []MergeStrategy{forSheet("source other", PreferTarget)}, ""},
+		{"newest source", cellConflict, []MergeStrategy{{Prefer: PreferNewest}}, "source"},
+		{"newest target", MergeConflict{SheetID: testId("target"), TargetUpdatedAt: now}, []MergeStrategy{{Prefer: PreferNewest}}, "target"},
+		{"newest keeps deleted items", rowDeleted, []MergeStrategy{{Prefer: PreferNewest}}, ResolutionKeep},
+		{"deleting side preferred", rowDeleted, []MergeStrategy{{Prefer: PreferSource}}, ResolutionDelete},
+		{"keeping side preferred", rowDeleted, []MergeStrategy{{Prefer: PreferTarget}}, ResolutionKeep},
+		{"target deleted, source preferred", sheetDeleted, []MergeStrategy{{Prefer: PreferSource}}, ResolutionKeep},
+		{"target deleted, target preferred", sheetDeleted, []MergeStrategy{{Prefer: PreferTarget}}, ResolutionDelete},
+	}
+	for _, tc := range cases {
+		got := strategyChoice(tc.conflict, tc.strategies, sheetKeys)
+		if got != tc.want {
+			t.Errorf("%s: strategyChoice = %q, want %q", tc.name, got, tc.want)
+		}
+	}
+}
@@ -71,10 +71,22 @@ func (cfg *apiConfig) checkSheetPermission(userId, sheetId uuid.UUID, permType s
 	return cfg.checkBranchPermission(userId, sheet.BranchID, permType, ctx)
 }
 
+// checkBranchPermission treats protected branches as read only for everyone
+// but the owners, their data only changes through a merge which is checked
+// with the "merge" permission type.
 func (cfg *apiConfig) checkBranchPermission(userId, branchId uuid.UUID, permType string, ctx context.Context) bool {
 	branch, err := cfg.db.GetBranch(ctx, branchId)
 	if err != nil {
 		return false
 	}
+
+	switch permType {
+	case "write":
+		if branch.IsProtected {
+			return cfg.checkTablePermission(userId, branch.TableID, OwnerPermission, ctx)
+		}
+	case "merge":
+		permType = "write"
+	}
 	return cfg.checkTablePermission(userId, branch.TableID, permType, ctx)
 }
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Dass33/administratum/backend/internal/database"
+	"github.com/google/uuid"
+)
+
+type publishReleaseParams struct {
+	BranchId string `json:"branch_id"`
+	Message  string `json:"message"`
+}
+
+type Release struct {
+	ID          uuid.UUID `json:"id"`
+	Version     int64     `json:"version"`
+	Message     string    `json:"message"`
+	AuthorEmail string    `json:"author_email"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+func (cfg *apiConfig) publishReleaseHandler(w http.ResponseWriter, r *http.Request, userId uuid.UUID) {
+	decoder := json.NewDecoder(r.Body)
+	params := publishReleaseParams{}
+	err := decoder.Decode(&params)
+	if err != nil {
+		msg := fmt.Sprintf("Error decoding parameters: %s", err)
+		respondWithError(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	branchId, err := uuid.Parse(params.BranchId)
+	if err != nil {
+		msg := fmt.Sprintf("Could not parse the branch id: %s", err)
+		respondWithError(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	if !cfg.checkBranchPermission(userId, branchId, "write", r.Context()) {
+		respondWithError(w, http.StatusForbidden, "Insufficient write permissions")
+		return
+	}
+
+	// keys are checked when the table exports the object shape by default
+	shape, err := cfg.resolveExportShape(branchId, "", r.Context())
+	if err != nil {
+		msg := fmt.Sprintf("Could not get export shape: %s", err)
+		respondWithError(w, http.StatusInternalServerError, msg)
+		return
+	}
+
+	sheets, err := cfg.getBranchJson(branchId, shape, r.Context())
+	if err != nil {
+		respondWithBranchJsonError(w, err)
+		return
+	}
+
+	data, err := json.Marshal(sheets)
+	if err != nil {
+		msg := fmt.Sprintf("Could not encode branch json: %s", err)
+		respondWithError(w, http.StatusInternalServerError, msg)
+		return
+	}
+
+	// The snapshot keeps the column definitions the json schema of the
+	// release is built from.
+	branchSnapshot, err := getBranchSnapshot(r.Context(), cfg.db, branchId)
+	if err != nil {
+		msg := fmt.Sprintf("Could not get branch data: %s", err)
+		respondWithError(w, http.StatusInternalServerError, msg)
+		return
+	}
+	snapshot, err := json.Marshal(branchSnapshot)
+	if err != nil {
+		msg := fmt.Sprintf("Could not encode branch snapshot: %s", err)
+		respondWithError(w, http.StatusInternalServerError, msg)
+		return
+	}
+
+	createReleaseParams := database.CreateReleaseParams{
+		BranchID: branchId,
+		AuthorID: uuid.NullUUID{UUID: userId, Valid: true},
+		Message:  params.Message,
+		Data:     string(data),
+		Snapshot: sql.NullString{String: string(snapshot), Valid: true},
+	}
+	release, err := cfg.db.CreateRelease(r.Context(), createReleaseParams)
+	if err != nil {
+		msg := fmt.Sprintf("Could not create release: %s", err)
+		respondWithError(w, http.StatusInternalServerError, msg)
+		return
+	}
+
+	cfg.invalidateBranchJson(branchId)
+
+	user, err := cfg.db.GetUser(r.Context(), userId)
+	if err != nil {
+		msg := fmt.Sprintf("User with id not found: %s", err)
+		respondWithError(w, http.StatusInternalServerError, msg)
+		return
+	}
+
+	response := Release{
+		ID:          release.ID,
+		Version:     release.Version,
+		Message:     release.Message,
+		AuthorEmail: user.Email,
+		CreatedAt:   release.CreatedAt,
+	}
+	respondWithJSON(w, http.StatusCreated, response)
+}
@@ -0,0 +1,13 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+// redoHandler makes the last undone edits of the user on the branch again,
+// with the same conflict checks as undoHandler.
+func (cfg *apiConfig) redoHandler(w http.ResponseWriter, r *http.Request, userId uuid.UUID) {
+	cfg.undoRedo(w, r, userId, false)
+}
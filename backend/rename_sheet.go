@@ -37,15 +37,51 @@ func (cfg *apiConfig) renameSheetHandler(w http.ResponseWriter, r *http.Request,
 		return
 	}
 
+	sheet, err := cfg.db.GetSheet(r.Context(), sheetId)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Sheet not found")
+		return
+	}
+
+	tx, err := cfg.rawDB.BeginTx(r.Context(), nil)
+	if err != nil {
+		msg := fmt.Sprintf("Could not begin transaction: %s", err)
+		respondWithError(w, http.StatusInternalServerError, msg)
+		return
+	}
+	defer tx.Rollback()
+	txQueries := cfg.db.WithTx(tx)
+
 	renameSheetParams := database.RenameSheetParams{
 		Name: params.Name,
 		ID:   sheetId,
 	}
-	err = cfg.db.RenameSheet(r.Context(), renameSheetParams)
+	err = txQueries.RenameSheet(r.Context(), renameSheetParams)
 	if err != nil {
 		msg := fmt.Sprintf("Sheet could not be renamed: %s", err)
 		respondWithError(w, http.StatusInternalServerError, msg)
 		return
 	}
+
+	err = recordUndoableHistory(r.Context(), txQueries, userId, historyEntry{
+		branchId: sheet.BranchID,
+		sheetId:  sheet.ID,
+		entity:   HistoryEntitySheet,
+		action:   HistoryActionRename,
+		oldValue: HistorySheetValue{Name: sheet.Name, Type: sheet.Type},
+		newValue: HistorySheetValue{Name: params.Name, Type: sheet.Type},
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		msg := fmt.Sprintf("Could not commit sheet rename: %s", err)
+		respondWithError(w, http.StatusInternalServerError, msg)
+		return
+	}
+	cfg.invalidateSheetJson(sheetId, r.Context())
 	respondWithJSON(w, http.StatusOK, "")
 }
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/Dass33/administratum/backend/internal/database"
+	"github.com/google/uuid"
+)
+
+type restoreTagParams struct {
+	TagId    string `json:"tag_id"`
+	BranchId string `json:"branch_id"`
+}
+
+// restoreTagHandler replaces the sheets of a branch of the project with the
+// ones recorded in the tag.
+func (cfg *apiConfig) restoreTagHandler(w http.ResponseWriter, r *http.Request, userId uuid.UUID) {
+	decoder := json.NewDecoder(r.Body)
+	params := restoreTagParams{}
+	err := decoder.Decode(&params)
+	if err != nil {
+		msg := fmt.Sprintf("Error decoding parameters: %s", err)
+		respondWithError(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	tagId, err := uuid.Parse(params.TagId)
+	if err != nil {
+		msg := fmt.Sprintf("Could not parse the tag id: %s", err)
+		respondWithError(w, http.StatusBadRequest, msg)
+		return
+	}
+	branchId, err := uuid.Parse(params.BranchId)
+	if err != nil {
+		msg := fmt.Sprintf("Could not parse the branch id: %s", err)
+		respondWithError(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	ctx := r.Context()
+
+	tag, err := cfg.db.GetTag(ctx, tagId)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Tag not found")
+		return
+	}
+	branch, err := cfg.db.GetBranch(ctx, branchId)
+	if err != nil || branch.TableID != tag.TableID {
+		respondWithError(w, http.StatusNotFound, "Branch not found")
+		return
+	}
+
+	if !cfg.checkBranchPermission(userId, branch.ID, "write", ctx) {
+		respondWithError(w, http.StatusForbidden, "Insufficient write permissions")
+		return
+	}
+
+	snapshot, err := decodeTagSnapshot(tag)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	// A merge of the table must not read the branch halfway restored.
+	if !cfg.mergeLocks.tryLock(branch.TableID) {
+		respondWithError(w, http.StatusConflict, "A merge into this table is in progress")
+		return
+	}
+	defer cfg.mergeLocks.unlock(branch.TableID)
+
+	tx, err := cfg.rawDB.BeginTx(ctx, nil)
+	if err != nil {
+		msg := fmt.Sprintf("Could not begin transaction: %s", err)
+		respondWithError(w, http.StatusInternalServerError, msg)
+		return
+	}
+	defer tx.Rollback()
+	txQueries := cfg.db.WithTx(tx)
+
+	before, err := loadBranchSide(ctx, txQueries, branch.ID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	sheets, err := txQueries.GetSheetsFromBranch(ctx, branch.ID)
+	if err != nil {
+		msg := fmt.Sprintf("Could not get sheets from branch: %s", err)
+		respondWithError(w, http.StatusInternalServerError, msg)
+		return
+	}
+	for i := range sheets {
+		// The replaced sheets stay in the trash, so a restore can be undone.
+		err = txQueries.TrashSheet(ctx, database.TrashSheetParams{
+			DeletedBy: uuid.NullUUID{UUID: userId, Valid: true},
+			ID:        sheets[i].ID,
+		})
+		if err != nil {
+			msg := fmt.Sprintf("Could not delete sheet %s: %s", sheets[i].Name, err)
+			respondWithError(w, http.StatusInternalServerError, msg)
+			return
+		}
+	}
+
+	err = cfg.copySnapshotSheetsInTx(ctx, tx, txQueries, snapshot, branch.ID)
+	if err != nil {
+		msg := fmt.Sprintf("Could not restore tag: %s", err)
+		respondWithError(w, http.StatusInternalServerError, msg)
+		return
+	}
+
+	err = recordSheetChanges(ctx, txQueries, userId, branch.ID, before, HistoryActionRestoreTag, tag.Name)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		msg := fmt.Sprintf("Could not commit restore: %s", err)
+		respondWithError(w, http.StatusInternalServerError, msg)
+		return
+	}
+	cfg.invalidateBranchJson(branch.ID)
+
+	cfg.switchBranch(w, r, branch.ID, userId, http.StatusOK)
+}
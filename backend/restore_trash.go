@@ -0,0 +1,232 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/Dass33/administratum/backend/internal/database"
+	"github.com/google/uuid"
+)
+
+type restoreTrashParams struct {
+	Kind string `json:"kind"`
+	ID   string `json:"id"`
+}
+
+// restoreTrashHandler takes an item out of the trash. Items inside a deleted
+// project, branch or sheet can only be restored after it.
+func (cfg *apiConfig) restoreTrashHandler(w http.ResponseWriter, r *http.Request, userId uuid.UUID) {
+	decoder := json.NewDecoder(r.Body)
+	params := restoreTrashParams{}
+	err := decoder.Decode(&params)
+	if err != nil {
+		msg := fmt.Sprintf("Error decoding parameters: %s", err)
+		respondWithError(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	id, err := uuid.Parse(params.ID)
+	if err != nil {
+		msg := fmt.Sprintf("Could not parse the id: %s", err)
+		respondWithError(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	switch params.Kind {
+	case TrashKindProject:
+		cfg.restoreTrashedProject(w, r, userId, id)
+	case TrashKindBranch:
+		cfg.restoreTrashedBranch(w, r, userId, id)
+	case TrashKindSheet:
+		cfg.restoreTrashedSheet(w, r, userId, id)
+	case TrashKindColumn:
+		cfg.restoreTrashedColumn(w, r, userId, id)
+	default:
+		respondWithError(w, http.StatusBadRequest, "Invalid trash item kind")
+	}
+}
+
+func (cfg *apiConfig) restoreTrashedProject(w http.ResponseWriter, r *http.Request, userId, tableId uuid.UUID) {
+	table, err := cfg.db.GetTrashedTable(r.Context(), tableId)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Project not found in the trash")
+		return
+	}
+	if !cfg.checkTrashOwner(userId, table.ID, r.Context()) {
+		respondWithError(w, http.StatusForbidden, "Only owners can restore from the trash")
+		return
+	}
+
+	err = cfg.db.RestoreTrashedTable(r.Context(), table.ID)
+	if err != nil {
+		msg := fmt.Sprintf("Project could not be restored: %s", err)
+		respondWithError(w, http.StatusInternalServerError, msg)
+		return
+	}
+
+	branches, err := cfg.db.GetBranchesFromTable(r.Context(), table.ID)
+	if err != nil {
+		msg := fmt.Sprintf("Could not get branches from project: %s", err)
+		respondWithError(w, http.StatusInternalServerError, msg)
+		return
+	}
+	for _, branch := range branches {
+		cfg.invalidateBranchJson(branch.ID)
+	}
+	respondWithJSON(w, http.StatusNoContent, "")
+}
+
+func (cfg *apiConfig) restoreTrashedBranch(w http.ResponseWriter, r *http.Request, userId, branchId uuid.UUID) {
+	branch, err := cfg.db.GetTrashedBranch(r.Context(), branchId)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Branch not found in the trash")
+		return
+	}
+	if !cfg.checkTrashOwner(userId, branch.TableID, r.Context()) {
+		respondWithError(w, http.StatusForbidden, "Only owners can restore from the trash")
+		return
+	}
+	if branch.TableTrashed {
+		respondWithError(w, http.StatusConflict, "The project of the branch is in the trash")
+		return
+	}
+
+	err = cfg.db.RestoreTrashedBranch(r.Context(), branch.ID)
+	if err != nil {
+		msg := fmt.Sprintf("Branch could not be restored: %s", err)
+		respondWithError(w, http.StatusInternalServerError, msg)
+		return
+	}
+	cfg.invalidateBranchJson(branch.ID)
+	respondWithJSON(w, http.StatusNoContent, "")
+}
+
+func (cfg *apiConfig) restoreTrashedSheet(w http.ResponseWriter, r *http.Request, userId, sheetId uuid.UUID) {
+	sheet, err := cfg.db.GetTrashedSheet(r.Context(), sheetId)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Sheet not found in the trash")
+		return
+	}
+	if !cfg.checkTrashOwner(userId, sheet.TableID, r.Context()) {
+		respondWithError(w, http.StatusForbidden, "Only owners can restore from the trash")
+		return
+	}
+	if sheet.ParentTrashed {
+		respondWithError(w, http.StatusConflict, "The branch of the sheet is in the trash")
+		return
+	}
+
+	tx, err := cfg.rawDB.BeginTx(r.Context(), nil)
+	if err != nil {
+		msg := fmt.Sprintf("Could not begin transaction: %s", err)
+		respondWithError(w, http.StatusInternalServerError, msg)
+		return
+	}
+	defer tx.Rollback()
+	txQueries := cfg.db.WithTx(tx)
+
+	err = txQueries.RestoreTrashedSheet(r.Context(), sheet.ID)
+	if err != nil {
+		msg := fmt.Sprintf("Sheet could not be restored: %s", err)
+		respondWithError(w, http.StatusInternalServerError, msg)
+		return
+	}
+
+	err = recordHistory(r.Context(), txQueries, userId, historyEntry{
+		branchId: sheet.BranchID,
+		sheetId:  sheet.ID,
+		entity:   HistoryEntitySheet,
+		action:   HistoryActionRestore,
+		newValue: HistorySheetValue{Name: sheet.Name, Type: sheet.Type},
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		msg := fmt.Sprintf("Could not commit sheet restore: %s", err)
+		respondWithError(w, http.StatusInternalServerError, msg)
+		return
+	}
+	cfg.invalidateBranchJson(sheet.BranchID)
+	respondWithJSON(w, http.StatusNoContent, "")
+}
+
+func (cfg *apiConfig) restoreTrashedColumn(w http.ResponseWriter, r *http.Request, userId, columnId uuid.UUID) {
+	column, err := cfg.db.GetTrashedColumn(r.Context(), columnId)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Column not found in the trash")
+		return
+	}
+	if !cfg.checkTrashOwner(userId, column.TableID, r.Context()) {
+		respondWithError(w, http.StatusForbidden, "Only owners can restore from the trash")
+		return
+	}
+	if column.ParentTrashed {
+		respondWithError(w, http.StatusConflict, "The sheet of the column is in the trash")
+		return
+	}
+
+	tx, err := cfg.rawDB.BeginTx(r.Context(), nil)
+	if err != nil {
+		msg := fmt.Sprintf("Could not begin transaction: %s", err)
+		respondWithError(w, http.StatusInternalServerError, msg)
+		return
+	}
+	defer tx.Rollback()
+	txQueries := cfg.db.WithTx(tx)
+
+	columns, err := txQueries.GetColumnsFromSheet(r.Context(), column.SheetID)
+	if err != nil {
+		msg := fmt.Sprintf("Could not get columns: %s", err)
+		respondWithError(w, http.StatusInternalServerError, msg)
+		return
+	}
+	for _, col := range columns {
+		if col.Name == column.Name {
+			msg := fmt.Sprintf("A column named %s already exists", column.Name)
+			respondWithError(w, http.StatusConflict, msg)
+			return
+		}
+	}
+
+	err = txQueries.RestoreTrashedColumn(r.Context(), column.ID)
+	if err != nil {
+		msg := fmt.Sprintf("Column could not be restored: %s", err)
+		respondWithError(w, http.StatusInternalServerError, msg)
+		return
+	}
+
+	err = recordHistory(r.Context(), txQueries, userId, historyEntry{
+		branchId: column.BranchID,
+		sheetId:  column.SheetID,
+		columnId: uuid.NullUUID{UUID: column.ID, Valid: true},
+		entity:   HistoryEntityColumn,
+		action:   HistoryActionRestore,
+		newValue: historyColumnValue(database.Column{
+			ID:             column.ID,
+			Name:           column.Name,
+			Type:           column.Type,
+			Required:       column.Required,
+			OrderIndex:     column.OrderIndex,
+			IsKey:          column.IsKey,
+			OriginColumnID: column.OriginColumnID,
+		}),
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		msg := fmt.Sprintf("Could not commit column restore: %s", err)
+		respondWithError(w, http.StatusInternalServerError, msg)
+		return
+	}
+	cfg.invalidateSheetJson(column.SheetID, r.Context())
+	respondWithJSON(w, http.StatusNoContent, "")
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/Dass33/administratum/backend/internal/database"
+	"github.com/google/uuid"
+)
+
+type reviewMergeRequestParams struct {
+	MergeRequestId string `json:"merge_request_id"`
+	State          string `json:"state"`
+}
+
+// reviewMergeRequestHandler records the review of a user who may merge into
+// the target branch, a later review of the same user replaces the earlier one.
+// The review is tied to the current data of the source branch.
+func (cfg *apiConfig) reviewMergeRequestHandler(w http.ResponseWriter, r *http.Request, userId uuid.UUID) {
+	decoder := json.NewDecoder(r.Body)
+	params := reviewMergeRequestParams{}
+	err := decoder.Decode(&params)
+	if err != nil {
+		msg := fmt.Sprintf("Error decoding parameters: %s", err)
+		respondWithError(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	mergeRequestId, err := uuid.Parse(params.MergeRequestId)
+	if err != nil {
+		msg := fmt.Sprintf("Could not parse the merge request id: %s", err)
+		respondWithError(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	if params.State != ReviewApproved && params.State != ReviewChangesRequested {
+		msg := fmt.Sprintf("Review state has to be %s or %s", ReviewApproved, ReviewChangesRequested)
+		respondWithError(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	mergeRequestDb, err := cfg.db.GetMergeRequest(r.Context(), mergeRequestId)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Merge request not found")
+		return
+	}
+
+	if mergeRequestDb.Status != MergeRequestOpen {
+		respondWithError(w, http.StatusConflict, "Only open merge requests can be reviewed")
+		return
+	}
+
+	if mergeRequestDb.AuthorID.Valid && mergeRequestDb.AuthorID.UUID == userId {
+		respondWithError(w, http.StatusForbidden, "Authors can not review their own merge request")
+		return
+	}
+
+	if !mergeRequestDb.TargetBranchID.Valid ||
+		!cfg.checkBranchPermission(userId, mergeRequestDb.TargetBranchID.UUID, "merge", r.Context()) {
+		respondWithError(w, http.StatusForbidden, "No write permission on target branch")
+		return
+	}
+
+	if !mergeRequestDb.SourceBranchID.Valid {
+		respondWithError(w, http.StatusConflict, "Source branch of the merge request no longer exists")
+		return
+	}
+	sourceToken, err := getSourceToken(r.Context(), cfg.db, mergeRequestDb.SourceBranchID.UUID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	reviewParams := database.UpsertMergeRequestReviewParams{
+		MergeRequestID: mergeRequestId,
+		UserID:         userId,
+		State:          params.State,
+		SourceToken:    sourceToken,
+	}
+	err = cfg.db.UpsertMergeRequestReview(r.Context(), reviewParams)
+	if err != nil {
+		msg := fmt.Sprintf("Could not save review: %s", err)
+		respondWithError(w, http.StatusInternalServerError, msg)
+		return
+	}
+
+	mergeRequest, err := cfg.getMergeRequest(mergeRequestId, r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	respondWithJSON(w, http.StatusOK, mergeRequest)
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/Dass33/administratum/backend/internal/database"
+	"github.com/google/uuid"
+)
+
+type setKeyColumnParams struct {
+	SheetId  string        `json:"sheet_id"`
+	ColumnId uuid.NullUUID `json:"column_id"`
+}
+
+// setKeyColumnHandler designates the column whose values key the rows of a list
+// sheet in the object export shape, a null column id clears the designation.
+func (cfg *apiConfig) setKeyColumnHandler(w http.ResponseWriter, r *http.Request, userId uuid.UUID) {
+	decoder := json.NewDecoder(r.Body)
+	params := setKeyColumnParams{}
+
+	err := decoder.Decode(&params)
+	if err != nil {
+		msg := fmt.Sprintf("Error decoding parameters: %s", err)
+		respondWithError(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	sheetId, err := uuid.Parse(params.SheetId)
+	if err != nil {
+		msg := fmt.Sprintf("Could not parse the sheet id: %s", err)
+		respondWithError(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	if !cfg.checkSheetPermission(userId, sheetId, "write", r.Context()) {
+		respondWithError(w, http.StatusForbidden, "Insufficient write permissions")
+		return
+	}
+
+	sheet, err := cfg.db.GetSheet(r.Context(), sheetId)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Sheet not found")
+		return
+	}
+	if sheet.Type != SheetTypeList {
+		respondWithError(w, http.StatusBadRequest, "Only list sheets can have a key column")
+		return
+	}
+
+	columns, err := cfg.db.GetColumnsFromSheet(r.Context(), sheetId)
+	if err != nil {
+		msg := fmt.Sprintf("Could not get columns: %s", err)
+		respondWithError(w, http.StatusInternalServerError, msg)
+		return
+	}
+	oldKeyColumn := uuid.NullUUID{}
+	found := !params.ColumnId.Valid
+	for _, column := range columns {
+		if column.IsKey {
+			oldKeyColumn = uuid.NullUUID{UUID: column.ID, Valid: true}
+		}
+		if column.ID == params.ColumnId.UUID {
+			found = true
+		}
+	}
+	if !found {
+		respondWithError(w, http.StatusBadRequest, "Column does not belong to the sheet")
+		return
+	}
+
+	tx, err := cfg.rawDB.BeginTx(r.Context(), nil)
+	if err != nil {
+		msg := fmt.Sprintf("Could not begin transaction: %s", err)
+		respondWithError(w, http.StatusInternalServerError, msg)
+		return
+	}
+	defer tx.Rollback()
+	txQueries := cfg.db.WithTx(tx)
+
+	setKeyColumnParams := database.SetKeyColumnParams{
+		SheetID: sheetId,
+		ID:      params.ColumnId.UUID,
+	}
+	err = txQueries.SetKeyColumn(r.Context(), setKeyColumnParams)
+	if err != nil {
+		msg := fmt.Sprintf("Key column could not be set: %s", err)
+		respondWithError(w, http.StatusInternalServerError, msg)
+		return
+	}
+
+	err = recordHistory(r.Context(), txQueries, userId, historyEntry{
+		branchId: sheet.BranchID,
+		sheetId:  sheet.ID,
+		entity:   HistoryEntitySheet,
+		action:   HistoryActionSetKey,
+		oldValue: oldKeyColumn,
+		newValue: params.ColumnId,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		msg := fmt.Sprintf("Could not commit key column: %s", err)
+		respondWithError(w, http.StatusInternalServerError, msg)
+		return
+	}
+
+	cfg.invalidateBranchJson(sheet.BranchID)
+	respondWithJSON(w, http.StatusOK, "")
+}
@@ -1,5 +1,5 @@
 -- name: AddColumn :one
-INSERT INTO columns (id, name, type, required, sheet_id, created_at, updated_at, source_column_id, order_index)
+INSERT INTO columns (id, name, type, required, sheet_id, created_at, updated_at, source_column_id, order_index, is_key, origin_column_id)
 VALUES (
     gen_random_uuid(),
     ?1,
@@ -9,6 +9,8 @@ VALUES (
     datetime('now'),
     datetime('now'),
     ?5,
-    (select COALESCE(MAX(order_index + 1), 0) from columns where sheet_id = ?4)
+    (select COALESCE(MAX(order_index + 1), 0) from columns where sheet_id = ?4),
+    ?6,
+    ?7
 )
 RETURNING *;
@@ -5,7 +5,7 @@ VALUES (
     ?,
     ?,
     ?,
-    (SELECT id FROM columns WHERE name = ? AND sheet_id = ?),
+    (SELECT id FROM columns WHERE name = ? AND sheet_id = ? AND deleted_at IS NULL),
     datetime('now'),
     datetime('now')
 )
@@ -0,0 +1,11 @@
+-- name: SetBaseSnapshot :exec
+INSERT INTO base_snapshots (branch_id, parent_branch_id, data, created_at)
+VALUES (?, ?, ?, datetime('now'))
+ON CONFLICT (branch_id) DO UPDATE
+SET parent_branch_id = excluded.parent_branch_id,
+    data = excluded.data,
+    created_at = excluded.created_at;
+
+-- name: GetBaseSnapshot :one
+SELECT * FROM base_snapshots
+WHERE branch_id = ?;
@@ -0,0 +1,4 @@
+-- name: ChangeExportShape :exec
+UPDATE tables
+SET export_shape = ?, updated_at = CURRENT_TIMESTAMP
+WHERE id = ?;
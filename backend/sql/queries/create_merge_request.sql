@@ -0,0 +1,30 @@
+-- name: CreateMergeRequest :one
+INSERT INTO merge_requests (
+    id,
+    table_id,
+    source_branch_id,
+    source_branch_name,
+    target_branch_id,
+    target_branch_name,
+    author_id,
+    title,
+    description,
+    conflicts,
+    created_at,
+    updated_at
+)
+VALUES (
+    gen_random_uuid(),
+    ?,
+    ?,
+    ?,
+    ?,
+    ?,
+    ?,
+    ?,
+    ?,
+    ?,
+    datetime('now'),
+    datetime('now')
+)
+RETURNING *;
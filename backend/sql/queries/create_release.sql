@@ -0,0 +1,13 @@
+-- name: CreateRelease :one
+INSERT INTO releases (id, branch_id, version, author_id, message, data, snapshot, created_at)
+VALUES (
+    gen_random_uuid(),
+    ?1,
+    (SELECT COALESCE(MAX(version) + 1, 1) FROM releases WHERE branch_id = ?1),
+    ?2,
+    ?3,
+    ?4,
+    ?5,
+    datetime('now')
+)
+RETURNING *;
@@ -1,5 +1,5 @@
 -- name: CreateSheet :one
-INSERT INTO sheets (id, name, type, branch_id, created_at, updated_at, source_sheet_id)
+INSERT INTO sheets (id, name, type, branch_id, created_at, updated_at, source_sheet_id, origin_sheet_id)
 VALUES (
     gen_random_uuid(),
     ?,
@@ -7,6 +7,7 @@ VALUES (
     ?,
     datetime('now'),
     datetime('now'),
+    ?,
     ?
 )
 RETURNING *; 
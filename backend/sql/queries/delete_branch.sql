@@ -8,5 +8,6 @@ WHERE id = ?
   AND table_id IN (
     SELECT table_id FROM user_tables 
     WHERE user_id = ? 
-      AND permission IN ('owner', 'contributor')
+      AND (permission = 'owner'
+        OR (permission = 'contributor' AND branches.is_protected = false))
   ); 
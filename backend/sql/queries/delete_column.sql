@@ -1,3 +1,3 @@
 -- name: DeleteColumn :exec
 DELETE FROM columns
-WHERE name = ? AND sheet_id = ?;
+WHERE id = ? AND deleted_at IS NULL;
@@ -15,3 +15,19 @@ WHERE column_id IN (
     WHERE c.sheet_id = ?1
 ) 
 AND idx > ?2;
+
+DELETE FROM sheet_rows
+WHERE sheet_id = ?1
+AND idx = ?2;
+
+-- sheet_rows has a unique (sheet_id, idx), the rows are moved through
+-- negative indexes so no two of them share an idx midway.
+UPDATE sheet_rows
+SET idx = -idx
+WHERE sheet_id = ?1
+AND idx > ?2;
+
+UPDATE sheet_rows
+SET idx = -idx - 1
+WHERE sheet_id = ?1
+AND idx < 0;
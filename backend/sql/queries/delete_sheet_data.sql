@@ -0,0 +1,11 @@
+-- name: DeleteSheetData :exec
+DELETE FROM column_data
+WHERE column_id IN (
+    SELECT c.id
+    FROM columns c
+    WHERE c.sheet_id = ?
+);
+
+-- name: DeleteSheetRows :exec
+DELETE FROM sheet_rows
+WHERE sheet_id = ?;
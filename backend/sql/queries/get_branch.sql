@@ -1,3 +1,4 @@
 -- name: GetBranch :one
-select * from branches
-where id = ?;
+select b.* from branches b
+join tables t on t.id = b.table_id
+where b.id = ? and b.deleted_at is null and t.deleted_at is null;
@@ -6,6 +6,7 @@ SELECT
     s.created_at as sheet_created_at,
     s.updated_at as sheet_updated_at,
     s.source_sheet_id,
+    s.origin_sheet_id,
     c.id as column_id,
     c.name as column_name,
     c.type as column_type,
@@ -13,14 +14,20 @@ SELECT
     c.created_at as column_created_at,
     c.updated_at as column_updated_at,
     c.source_column_id,
+    c.origin_column_id,
     c.order_index as column_order_index,
+    c.is_key as column_is_key,
     cd.id as column_data_id,
     cd.idx as column_data_idx,
     cd.value as column_data_value,
+    cd.type as column_data_type,
     cd.created_at as column_data_created_at,
-    cd.updated_at as column_data_updated_at
+    cd.updated_at as column_data_updated_at,
+    r.id as row_id,
+    r.origin_row_id
 FROM sheets s
-LEFT JOIN columns c ON c.sheet_id = s.id
+LEFT JOIN columns c ON c.sheet_id = s.id AND c.deleted_at IS NULL
 LEFT JOIN column_data cd ON cd.column_id = c.id
-WHERE s.branch_id = ?
-ORDER BY s.id, c.order_index, cd.idx;
\ No newline at end of file
+LEFT JOIN sheet_rows r ON r.sheet_id = s.id AND r.idx = cd.idx
+WHERE s.branch_id = ? AND s.deleted_at IS NULL
+ORDER BY s.id, c.order_index, c.id, cd.idx;
\ No newline at end of file
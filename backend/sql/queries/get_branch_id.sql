@@ -0,0 +1,10 @@
+-- name: GetBranchIdFromColumn :one
+SELECT s.branch_id FROM columns c
+JOIN sheets s ON s.id = c.sheet_id
+WHERE c.id = ?;
+
+-- name: GetBranchIdFromColumnData :one
+SELECT s.branch_id FROM column_data cd
+JOIN columns c ON c.id = cd.column_id
+JOIN sheets s ON s.id = c.sheet_id
+WHERE cd.id = ?;
@@ -0,0 +1,8 @@
+-- name: TouchBranchData :exec
+UPDATE branches
+SET data_modified_at = datetime('now')
+WHERE id = ?;
+
+-- name: GetBranchDataModified :one
+SELECT data_modified_at FROM branches
+WHERE id = ?;
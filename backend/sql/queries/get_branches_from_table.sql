@@ -1,3 +1,3 @@
 -- name: GetBranchesFromTable :many
 select * from branches
-where table_id = ?;
+where table_id = ? and deleted_at is null;
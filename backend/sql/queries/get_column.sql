@@ -1,2 +1,2 @@
 -- name: GetColumn :one
-SELECT * FROM columns WHERE id = ?;
\ No newline at end of file
+SELECT * FROM columns WHERE id = ? AND deleted_at IS NULL;
\ No newline at end of file
@@ -0,0 +1,15 @@
+-- name: GetColumnDataColumn :one
+SELECT
+    c.id as column_id,
+    c.name as column_name,
+    c.type as column_type,
+    c.required as column_required,
+    s.id as sheet_id,
+    s.branch_id as branch_id,
+    cd.idx as data_idx,
+    cd.value as data_value,
+    cd.type as data_type
+FROM column_data cd
+JOIN columns c ON cd.column_id = c.id
+JOIN sheets s ON c.sheet_id = s.id
+WHERE cd.id = ? AND c.deleted_at IS NULL;
@@ -5,4 +5,5 @@ WHERE EXISTS (
     SELECT 1
     FROM sheets s
     WHERE s.id = c.sheet_id AND s.id = ?
-);
+)
+AND c.deleted_at IS NULL;
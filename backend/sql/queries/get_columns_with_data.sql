@@ -14,4 +14,5 @@ WHERE EXISTS (
     FROM sheets s
     WHERE s.id = c.sheet_id AND s.id = ?
 )
+AND c.deleted_at IS NULL
 ORDER BY c.order_index, cd.idx; 
@@ -5,11 +5,12 @@ SELECT
     c.type as column_type,
     c.required as column_required,
     c.order_index as column_order_index,
+    c.is_key as column_is_key,
     cd.id as data_id,
     cd.idx as data_idx,
     cd.value as data_value,
     cd.type as data_type
 FROM columns c
 LEFT JOIN column_data cd ON c.id = cd.column_id
-WHERE c.sheet_id = ?
+WHERE c.sheet_id = ? AND c.deleted_at IS NULL
 ORDER BY c.order_index, cd.idx;
\ No newline at end of file
@@ -0,0 +1,25 @@
+-- name: GetMergeRequest :one
+SELECT * FROM merge_requests
+WHERE id = ?;
+
+-- name: GetOpenMergeRequestFromBranch :one
+SELECT * FROM merge_requests
+WHERE source_branch_id = ? AND status = 'open';
+
+-- name: GetMergeRequestsFromTable :many
+SELECT
+    mr.id,
+    mr.source_branch_id,
+    mr.source_branch_name,
+    mr.target_branch_id,
+    mr.target_branch_name,
+    mr.title,
+    mr.status,
+    mr.created_at,
+    mr.updated_at,
+    mr.merged_at,
+    u.email AS author_email
+FROM merge_requests mr
+LEFT JOIN users u ON u.id = mr.author_id
+WHERE mr.table_id = ?
+ORDER BY mr.created_at DESC;
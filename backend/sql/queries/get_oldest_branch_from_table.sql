@@ -1,5 +1,5 @@
 -- name: GetOldestBranchFromTable :one
 select * from branches
-where table_id = ?
+where table_id = ? and deleted_at is null
 order by created_at asc
 limit 1;
\ No newline at end of file
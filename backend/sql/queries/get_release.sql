@@ -0,0 +1,9 @@
+-- name: GetLatestRelease :one
+SELECT * FROM releases
+WHERE branch_id = ?
+ORDER BY version DESC
+LIMIT 1;
+
+-- name: GetReleaseByVersion :one
+SELECT * FROM releases
+WHERE branch_id = ? AND version = ?;
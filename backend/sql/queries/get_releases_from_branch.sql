@@ -0,0 +1,11 @@
+-- name: GetReleasesFromBranch :many
+SELECT
+    r.id,
+    r.version,
+    r.message,
+    r.created_at,
+    u.email AS author_email
+FROM releases r
+LEFT JOIN users u ON u.id = r.author_id
+WHERE r.branch_id = ?
+ORDER BY r.version DESC;
@@ -1,3 +1,3 @@
 -- name: GetSheet :one
 select * from sheets
-where id = ?;
+where id = ? and deleted_at is null;
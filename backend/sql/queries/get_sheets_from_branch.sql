@@ -2,4 +2,5 @@
 select
     s.*
 from branches b
-join sheets s on b.id = s.branch_id and b.id = ?;
+join sheets s on b.id = s.branch_id and b.id = ?
+where s.deleted_at is null;
@@ -3,5 +3,5 @@ select s.*
 from tables t
 join branches b on t.id = b.table_id
 join sheets s on b.id = s.branch_id
-where t.id = ?
+where t.id = ? and b.deleted_at is null and s.deleted_at is null
 order by s.updated_at desc; 
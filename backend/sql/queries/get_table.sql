@@ -1,3 +1,3 @@
 -- name: GetTable :one
 select * from tables
-where id = ?;
+where id = ? and deleted_at is null;
@@ -2,4 +2,4 @@
 select t.* from sheets s
 join branches b on s.branch_id = b.id
 join tables t on t.id = b.table_id
-where s.id = ?;
+where s.id = ? and s.deleted_at is null and b.deleted_at is null and t.deleted_at is null;
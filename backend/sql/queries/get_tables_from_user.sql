@@ -6,4 +6,5 @@ select
     t.updated_at updated_at,
     t.name name
 from user_tables ut
-join tables t on t.id = ut.table_id and ut.user_id = ?;
+join tables t on t.id = ut.table_id and ut.user_id = ?
+where t.deleted_at is null;
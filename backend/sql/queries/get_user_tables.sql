@@ -1,3 +1,4 @@
 -- name: GetUserTables :one
-select * from user_tables
-where user_id = ? and table_id = ?;
+select ut.* from user_tables ut
+join tables t on t.id = ut.table_id
+where ut.user_id = ? and ut.table_id = ? and t.deleted_at is null;
@@ -0,0 +1,79 @@
+-- name: CreateHistoryEntry :one
+INSERT INTO history (id, seq, branch_id, sheet_id, column_id, row_id, cell_id, user_id, entity, action, old_value, new_value, created_at)
+VALUES (
+    gen_random_uuid(),
+    (SELECT COALESCE(MAX(seq), 0) + 1 FROM history),
+    ?,
+    ?,
+    ?,
+    ?,
+    ?,
+    ?,
+    ?,
+    ?,
+    ?,
+    ?,
+    datetime('now')
+)
+RETURNING *;
+
+-- name: GetCellHistory :many
+SELECT
+    h.id,
+    h.seq,
+    h.branch_id,
+    h.sheet_id,
+    h.column_id,
+    h.row_id,
+    h.cell_id,
+    h.entity,
+    h.action,
+    h.old_value,
+    h.new_value,
+    h.created_at,
+    u.email AS user_email
+FROM history h
+LEFT JOIN users u ON u.id = h.user_id
+WHERE h.cell_id = ?
+ORDER BY h.seq DESC;
+
+-- name: GetSheetHistory :many
+SELECT
+    h.id,
+    h.seq,
+    h.branch_id,
+    h.sheet_id,
+    h.column_id,
+    h.row_id,
+    h.cell_id,
+    h.entity,
+    h.action,
+    h.old_value,
+    h.new_value,
+    h.created_at,
+    u.email AS user_email
+FROM history h
+LEFT JOIN users u ON u.id = h.user_id
+WHERE h.sheet_id = sqlc.arg(sheet_id)
+AND h.seq < sqlc.arg(before_seq)
+ORDER BY h.seq DESC
+LIMIT sqlc.arg(limit_count);
+
+-- name: GetSheetHistoryAfter :many
+SELECT
+    h.id,
+    h.seq,
+    h.column_id,
+    h.row_id,
+    h.cell_id,
+    h.user_id,
+    h.entity,
+    h.action,
+    h.old_value,
+    h.new_value,
+    h.created_at,
+    u.email AS user_email
+FROM history h
+LEFT JOIN users u ON u.id = h.user_id
+WHERE h.sheet_id = ? AND h.seq > ?
+ORDER BY h.seq;
@@ -0,0 +1,15 @@
+-- name: CreateMergeRequestComment :one
+INSERT INTO merge_request_comments (id, merge_request_id, author_id, body, created_at)
+VALUES (gen_random_uuid(), ?, ?, ?, datetime('now'))
+RETURNING *;
+
+-- name: GetMergeRequestComments :many
+SELECT
+    c.id,
+    c.body,
+    c.created_at,
+    u.email AS author_email
+FROM merge_request_comments c
+LEFT JOIN users u ON u.id = c.author_id
+WHERE c.merge_request_id = ?
+ORDER BY c.created_at ASC;
@@ -0,0 +1,19 @@
+-- name: UpsertMergeRequestReview :exec
+INSERT INTO merge_request_reviews (merge_request_id, user_id, state, source_token, created_at, updated_at)
+VALUES (?, ?, ?, ?, datetime('now'), datetime('now'))
+ON CONFLICT (merge_request_id, user_id) DO UPDATE
+SET state = excluded.state,
+    source_token = excluded.source_token,
+    updated_at = datetime('now');
+
+-- name: GetMergeRequestReviews :many
+SELECT
+    r.user_id,
+    r.state,
+    r.source_token,
+    r.updated_at,
+    u.email AS user_email
+FROM merge_request_reviews r
+JOIN users u ON u.id = r.user_id
+WHERE r.merge_request_id = ?
+ORDER BY r.updated_at ASC;
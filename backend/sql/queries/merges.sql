@@ -0,0 +1,49 @@
+-- name: CreateMerge :one
+INSERT INTO merges (
+    id,
+    table_id,
+    source_branch_id,
+    source_branch_name,
+    target_branch_id,
+    target_branch_name,
+    user_id,
+    merge_request_id,
+    source_kept,
+    resolutions,
+    summary,
+    created_at
+)
+VALUES (
+    gen_random_uuid(),
+    ?,
+    ?,
+    ?,
+    ?,
+    ?,
+    ?,
+    ?,
+    ?,
+    ?,
+    ?,
+    datetime('now')
+)
+RETURNING *;
+
+-- name: GetMergesFromTable :many
+SELECT
+    m.id,
+    m.source_branch_id,
+    m.source_branch_name,
+    m.target_branch_id,
+    m.target_branch_name,
+    m.user_id,
+    m.merge_request_id,
+    m.source_kept,
+    m.resolutions,
+    m.summary,
+    m.created_at,
+    u.email AS user_email
+FROM merges m
+LEFT JOIN users u ON u.id = m.user_id
+WHERE m.table_id = ?
+ORDER BY m.created_at DESC;
@@ -0,0 +1,5 @@
+-- name: SetColumnOrderIndex :exec
+UPDATE columns
+SET order_index = ?,
+    updated_at = datetime('now')
+WHERE id = ?;
@@ -0,0 +1,6 @@
+-- name: SetKeyColumn :exec
+UPDATE columns
+SET is_key = (columns.id = ?2),
+    updated_at = datetime('now')
+WHERE columns.sheet_id = ?1
+  AND columns.is_key != (columns.id = ?2);
@@ -0,0 +1,53 @@
+-- name: EnsureSheetRows :exec
+INSERT INTO sheet_rows (id, sheet_id, idx, created_at, updated_at)
+SELECT gen_random_uuid(), c.sheet_id, cd.idx, MIN(cd.created_at), datetime('now')
+FROM column_data cd
+JOIN columns c ON c.id = cd.column_id
+JOIN sheets s ON s.id = c.sheet_id
+WHERE s.branch_id = ?
+AND NOT EXISTS (
+    SELECT 1 FROM sheet_rows r
+    WHERE r.sheet_id = c.sheet_id AND r.idx = cd.idx
+)
+GROUP BY c.sheet_id, cd.idx
+ON CONFLICT (sheet_id, idx) DO NOTHING;
+
+-- name: CopySheetRows :exec
+INSERT INTO sheet_rows (id, sheet_id, idx, origin_row_id, created_at, updated_at)
+SELECT gen_random_uuid(), sqlc.arg(target_sheet_id), idx, COALESCE(origin_row_id, id), datetime('now'), datetime('now')
+FROM sheet_rows
+WHERE sheet_id = sqlc.arg(source_sheet_id);
+
+-- name: CreateSheetRow :one
+INSERT INTO sheet_rows (id, sheet_id, idx, origin_row_id, created_at, updated_at)
+VALUES (gen_random_uuid(), ?, ?, ?, datetime('now'), datetime('now'))
+RETURNING *;
+
+-- name: GetSheetRow :one
+SELECT * FROM sheet_rows
+WHERE id = ?;
+
+-- name: GetNextRowIdx :one
+SELECT CAST(COALESCE(MAX(idx) + 1, 0) AS INTEGER) AS next_idx
+FROM (
+    SELECT idx FROM sheet_rows WHERE sheet_rows.sheet_id = ?1
+    UNION ALL
+    SELECT cd.idx FROM column_data cd
+    JOIN columns c ON c.id = cd.column_id
+    WHERE c.sheet_id = ?1
+);
+
+-- name: GetSheetRows :many
+SELECT * FROM sheet_rows
+WHERE sheet_id = ?
+ORDER BY idx;
+
+-- name: GetSheetRowByIdx :one
+SELECT * FROM sheet_rows
+WHERE sheet_id = ? AND idx = ?;
+
+-- name: GetRowCells :many
+SELECT cd.* FROM column_data cd
+JOIN columns c ON c.id = cd.column_id
+WHERE c.sheet_id = ? AND cd.idx = ?
+ORDER BY c.order_index;
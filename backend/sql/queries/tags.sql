@@ -0,0 +1,41 @@
+-- name: CreateTag :one
+INSERT INTO tags (id, table_id, branch_id, branch_name, name, message, author_id, data, export_data, created_at)
+VALUES (
+    gen_random_uuid(),
+    ?,
+    ?,
+    ?,
+    ?,
+    ?,
+    ?,
+    ?,
+    ?,
+    datetime('now')
+)
+RETURNING *;
+
+-- name: GetTag :one
+SELECT * FROM tags
+WHERE id = ?;
+
+-- name: GetTagByName :one
+SELECT * FROM tags
+WHERE table_id = ? AND name = ?;
+
+-- name: GetTagsFromTable :many
+SELECT
+    t.id,
+    t.branch_id,
+    t.branch_name,
+    t.name,
+    t.message,
+    t.created_at,
+    u.email AS author_email
+FROM tags t
+LEFT JOIN users u ON u.id = t.author_id
+WHERE t.table_id = ?
+ORDER BY t.created_at DESC;
+
+-- name: DeleteTag :exec
+DELETE FROM tags
+WHERE id = ?;
@@ -0,0 +1,148 @@
+-- name: TrashTableWithPermissionCheck :execrows
+-- Only owners can see and restore the trash, so only they can put a
+-- project into it.
+UPDATE tables
+SET deleted_at = datetime('now'),
+    deleted_by = sqlc.arg(user_id),
+    updated_at = datetime('now')
+WHERE id = sqlc.arg(id)
+  AND deleted_at IS NULL
+  AND id IN (
+    SELECT table_id FROM user_tables
+    WHERE user_id = sqlc.arg(user_id)
+      AND permission = 'owner'
+  );
+
+-- name: TrashBranchWithPermissionCheck :execrows
+UPDATE branches
+SET deleted_at = datetime('now'),
+    deleted_by = sqlc.arg(user_id),
+    updated_at = datetime('now')
+WHERE id = sqlc.arg(id)
+  AND deleted_at IS NULL
+  AND table_id IN (
+    SELECT table_id FROM user_tables
+    WHERE user_id = sqlc.arg(user_id)
+      AND (permission = 'owner'
+        OR (permission = 'contributor' AND branches.is_protected = false))
+  );
+
+-- name: TrashSheet :exec
+UPDATE sheets
+SET deleted_at = datetime('now'),
+    deleted_by = ?,
+    updated_at = datetime('now')
+WHERE id = ? AND deleted_at IS NULL;
+
+-- name: TrashColumn :exec
+UPDATE columns
+SET deleted_at = datetime('now'),
+    deleted_by = ?,
+    updated_at = datetime('now')
+WHERE id = ? AND deleted_at IS NULL;
+
+-- name: GetTrashedTables :many
+SELECT t.id, t.name, t.deleted_at, u.email AS deleted_by_email
+FROM user_tables ut
+JOIN tables t ON t.id = ut.table_id
+LEFT JOIN users u ON u.id = t.deleted_by
+WHERE ut.user_id = ? AND ut.permission = 'owner' AND t.deleted_at IS NOT NULL
+ORDER BY t.deleted_at DESC;
+
+-- name: GetTrashedBranches :many
+SELECT b.id, b.name, b.deleted_at, u.email AS deleted_by_email
+FROM branches b
+LEFT JOIN users u ON u.id = b.deleted_by
+WHERE b.table_id = ? AND b.deleted_at IS NOT NULL
+ORDER BY b.deleted_at DESC;
+
+-- name: GetTrashedSheets :many
+SELECT s.id, s.name, s.branch_id, b.name AS branch_name, s.deleted_at, u.email AS deleted_by_email
+FROM sheets s
+JOIN branches b ON b.id = s.branch_id
+LEFT JOIN users u ON u.id = s.deleted_by
+WHERE b.table_id = ? AND b.deleted_at IS NULL AND s.deleted_at IS NOT NULL
+ORDER BY s.deleted_at DESC;
+
+-- name: GetTrashedColumns :many
+SELECT c.id, c.name, c.sheet_id, s.name AS sheet_name, s.branch_id, b.name AS branch_name, c.deleted_at, u.email AS deleted_by_email
+FROM columns c
+JOIN sheets s ON s.id = c.sheet_id
+JOIN branches b ON b.id = s.branch_id
+LEFT JOIN users u ON u.id = c.deleted_by
+WHERE b.table_id = ? AND b.deleted_at IS NULL AND s.deleted_at IS NULL AND c.deleted_at IS NOT NULL
+ORDER BY c.deleted_at DESC;
+
+-- name: GetTrashUserTable :one
+SELECT * FROM user_tables
+WHERE user_id = ? AND table_id = ?;
+
+-- name: GetTrashedTable :one
+SELECT * FROM tables
+WHERE id = ? AND deleted_at IS NOT NULL;
+
+-- name: GetTrashedBranch :one
+SELECT b.*, t.deleted_at IS NOT NULL AS table_trashed
+FROM branches b
+JOIN tables t ON t.id = b.table_id
+WHERE b.id = ? AND b.deleted_at IS NOT NULL;
+
+-- name: GetTrashedSheet :one
+SELECT s.*, b.table_id, (b.deleted_at IS NOT NULL OR t.deleted_at IS NOT NULL) AS parent_trashed
+FROM sheets s
+JOIN branches b ON b.id = s.branch_id
+JOIN tables t ON t.id = b.table_id
+WHERE s.id = ? AND s.deleted_at IS NOT NULL;
+
+-- name: GetTrashedColumn :one
+SELECT c.*, s.branch_id, b.table_id, (s.deleted_at IS NOT NULL OR b.deleted_at IS NOT NULL OR t.deleted_at IS NOT NULL) AS parent_trashed
+FROM columns c
+JOIN sheets s ON s.id = c.sheet_id
+JOIN branches b ON b.id = s.branch_id
+JOIN tables t ON t.id = b.table_id
+WHERE c.id = ? AND c.deleted_at IS NOT NULL;
+
+-- name: GetSheetColumnIds :many
+SELECT id FROM columns
+WHERE sheet_id = ?;
+
+-- name: RestoreTrashedTable :exec
+UPDATE tables
+SET deleted_at = NULL, deleted_by = NULL, updated_at = datetime('now')
+WHERE id = ?;
+
+-- name: RestoreTrashedBranch :exec
+UPDATE branches
+SET deleted_at = NULL, deleted_by = NULL, updated_at = datetime('now')
+WHERE id = ?;
+
+-- name: RestoreTrashedSheet :exec
+UPDATE sheets
+SET deleted_at = NULL, deleted_by = NULL, updated_at = datetime('now')
+WHERE id = ?;
+
+-- name: RestoreTrashedColumn :exec
+UPDATE columns
+SET deleted_at = NULL, deleted_by = NULL, updated_at = datetime('now')
+WHERE id = ?;
+
+-- name: PurgeTrashedTables :execrows
+-- retention is a sqlite modifier, like '-720 hours'.
+DELETE FROM tables
+WHERE deleted_at IS NOT NULL
+  AND deleted_at < datetime('now', CAST(sqlc.arg(retention) AS TEXT));
+
+-- name: PurgeTrashedBranches :execrows
+DELETE FROM branches
+WHERE deleted_at IS NOT NULL
+  AND deleted_at < datetime('now', CAST(sqlc.arg(retention) AS TEXT));
+
+-- name: PurgeTrashedSheets :execrows
+DELETE FROM sheets
+WHERE deleted_at IS NOT NULL
+  AND deleted_at < datetime('now', CAST(sqlc.arg(retention) AS TEXT));
+
+-- name: PurgeTrashedColumns :execrows
+DELETE FROM columns
+WHERE deleted_at IS NOT NULL
+  AND deleted_at < datetime('now', CAST(sqlc.arg(retention) AS TEXT));
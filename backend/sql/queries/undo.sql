@@ -0,0 +1,97 @@
+-- name: CreateUndoEntry :exec
+INSERT INTO undo_entries (id, user_id, branch_id, history_id, undone, created_at, updated_at)
+VALUES (gen_random_uuid(), ?, ?, ?, false, datetime('now'), datetime('now'));
+
+-- name: ClearRedoEntries :exec
+DELETE FROM undo_entries
+WHERE user_id = ? AND branch_id = ? AND undone = true;
+
+-- name: PruneUndoEntries :exec
+DELETE FROM undo_entries
+WHERE undo_entries.user_id = sqlc.arg(user_id)
+AND undo_entries.branch_id = sqlc.arg(branch_id)
+AND undo_entries.id NOT IN (
+    SELECT u.id FROM undo_entries u
+    JOIN history h ON h.id = u.history_id
+    WHERE u.user_id = sqlc.arg(user_id) AND u.branch_id = sqlc.arg(branch_id)
+    ORDER BY h.seq DESC
+    LIMIT sqlc.arg(keep)
+);
+
+-- name: GetLastUndoEntry :one
+SELECT
+    u.id AS undo_entry_id,
+    h.id,
+    h.seq,
+    h.branch_id,
+    h.sheet_id,
+    h.column_id,
+    h.row_id,
+    h.cell_id,
+    h.entity,
+    h.action,
+    h.old_value,
+    h.new_value
+FROM undo_entries u
+JOIN history h ON h.id = u.history_id
+WHERE u.user_id = ? AND u.branch_id = ? AND u.undone = false
+ORDER BY h.seq DESC
+LIMIT 1;
+
+-- name: GetLastRedoEntry :one
+SELECT
+    u.id AS undo_entry_id,
+    h.id,
+    h.seq,
+    h.branch_id,
+    h.sheet_id,
+    h.column_id,
+    h.row_id,
+    h.cell_id,
+    h.entity,
+    h.action,
+    h.old_value,
+    h.new_value
+FROM undo_entries u
+JOIN history h ON h.id = u.history_id
+WHERE u.user_id = ? AND u.branch_id = ? AND u.undone = true
+ORDER BY h.seq
+LIMIT 1;
+
+-- name: SetUndoEntryUndone :exec
+UPDATE undo_entries
+SET undone = ?, updated_at = datetime('now')
+WHERE id = ?;
+
+-- name: RestoreColumn :exec
+INSERT INTO columns (id, name, type, required, sheet_id, created_at, updated_at, order_index, is_key, origin_column_id)
+VALUES (?, ?, ?, ?, ?, datetime('now'), datetime('now'), ?, ?, ?);
+
+-- name: RestoreColumnData :exec
+INSERT INTO column_data (id, idx, value, type, column_id, created_at, updated_at)
+VALUES (?, ?, ?, ?, ?, datetime('now'), datetime('now'));
+
+-- name: RestoreSheetRow :exec
+INSERT INTO sheet_rows (id, sheet_id, idx, origin_row_id, created_at, updated_at)
+VALUES (?, ?, ?, ?, datetime('now'), datetime('now'));
+
+-- name: InsertRowSlot :exec
+UPDATE column_data
+SET idx = idx + 1
+WHERE column_id IN (
+    SELECT c.id
+    FROM columns c
+    WHERE c.sheet_id = ?1
+)
+AND idx >= ?2;
+
+-- Moved through negative indexes like in DeleteRow.
+UPDATE sheet_rows
+SET idx = -idx - 1
+WHERE sheet_id = ?1
+AND idx >= ?2;
+
+UPDATE sheet_rows
+SET idx = -idx
+WHERE sheet_id = ?1
+AND idx < 0;
@@ -2,5 +2,6 @@
 UPDATE branches
 SET name = ?,
     is_protected = ?,
+    required_approvals = COALESCE(?, required_approvals),
     updated_at = datetime('now')
 WHERE id = ?;
@@ -13,10 +13,12 @@ SET name = ?,
     required = ?,
     updated_at = datetime('now')
 WHERE columns.id = ? 
+  AND columns.deleted_at IS NULL
   AND columns.sheet_id IN (
     SELECT sheets.id FROM sheets
     JOIN branches ON sheets.branch_id = branches.id
     JOIN user_tables ON branches.table_id = user_tables.table_id
     WHERE user_tables.user_id = ? 
-      AND user_tables.permission IN ('owner', 'contributor')
+      AND (user_tables.permission = 'owner'
+        OR (user_tables.permission = 'contributor' AND branches.is_protected = false))
   );
@@ -1,12 +1,14 @@
 -- name: UpdateColumnData :exec
 UPDATE column_data
 SET value = ?,
+    type = COALESCE(?, type),
     updated_at = datetime('now')
 WHERE id = ?;
 
 -- name: UpdateColumnDataWithPermissionCheck :execrows
 UPDATE column_data
 SET value = ?,
+    type = COALESCE(?, type),
     updated_at = datetime('now')
 WHERE column_data.id = ? 
   AND column_data.column_id IN (
@@ -15,5 +17,6 @@ WHERE column_data.id = ?
     JOIN branches ON sheets.branch_id = branches.id
     JOIN user_tables ON branches.table_id = user_tables.table_id
     WHERE user_tables.user_id = ? 
-      AND user_tables.permission IN ('owner', 'contributor')
+      AND (user_tables.permission = 'owner'
+        OR (user_tables.permission = 'contributor' AND branches.is_protected = false))
   );
@@ -0,0 +1,17 @@
+-- name: UpdateMergeRequest :exec
+UPDATE merge_requests
+SET title = ?,
+    description = ?,
+    status = ?,
+    conflicts = ?,
+    updated_at = datetime('now')
+WHERE id = ?;
+
+-- name: SetMergeRequestMerged :exec
+UPDATE merge_requests
+SET status = 'merged',
+    conflicts = ?,
+    merged_by = ?,
+    merged_at = datetime('now'),
+    updated_at = datetime('now')
+WHERE id = ?;
@@ -0,0 +1,33 @@
+-- +goose Up
+CREATE TABLE releases (
+    id UUID PRIMARY KEY,
+    branch_id UUID NOT NULL,
+    version INTEGER NOT NULL,
+    author_id UUID,
+    message TEXT NOT NULL DEFAULT '',
+    data TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    CONSTRAINT fk_releases_branch_id
+        FOREIGN KEY (branch_id)
+        REFERENCES branches(id)
+        ON DELETE CASCADE,
+    CONSTRAINT fk_releases_author_id
+        FOREIGN KEY (author_id)
+        REFERENCES users(id)
+        ON DELETE SET NULL
+);
+
+CREATE UNIQUE INDEX releases_branch_id_version_unique ON releases (branch_id, version);
+
+-- +goose StatementBegin
+CREATE TRIGGER releases_immutable
+BEFORE UPDATE ON releases
+BEGIN
+    SELECT RAISE(ABORT, 'releases are immutable');
+END;
+-- +goose StatementEnd
+
+-- +goose Down
+DROP TRIGGER releases_immutable;
+DROP INDEX releases_branch_id_version_unique;
+DROP TABLE releases;
@@ -0,0 +1,7 @@
+-- +goose Up
+ALTER TABLE tables ADD COLUMN export_shape TEXT NOT NULL DEFAULT 'array';
+ALTER TABLE columns ADD COLUMN is_key BOOLEAN NOT NULL DEFAULT false;
+
+-- +goose Down
+ALTER TABLE tables DROP COLUMN export_shape;
+ALTER TABLE columns DROP COLUMN is_key;
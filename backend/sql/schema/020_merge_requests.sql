@@ -0,0 +1,85 @@
+-- +goose Up
+ALTER TABLE branches
+ADD COLUMN required_approvals INTEGER NOT NULL DEFAULT 0;
+
+CREATE TABLE merge_requests (
+    id UUID PRIMARY KEY,
+    table_id UUID NOT NULL,
+    source_branch_id UUID,
+    source_branch_name TEXT NOT NULL,
+    target_branch_id UUID,
+    target_branch_name TEXT NOT NULL,
+    author_id UUID,
+    title TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    status TEXT NOT NULL DEFAULT 'open',
+    conflicts TEXT NOT NULL DEFAULT '[]',
+    merged_by UUID,
+    merged_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    CONSTRAINT fk_merge_requests_table_id
+        FOREIGN KEY (table_id)
+        REFERENCES tables(id)
+        ON DELETE CASCADE,
+    CONSTRAINT fk_merge_requests_source_branch_id
+        FOREIGN KEY (source_branch_id)
+        REFERENCES branches(id)
+        ON DELETE SET NULL,
+    CONSTRAINT fk_merge_requests_target_branch_id
+        FOREIGN KEY (target_branch_id)
+        REFERENCES branches(id)
+        ON DELETE SET NULL,
+    CONSTRAINT fk_merge_requests_author_id
+        FOREIGN KEY (author_id)
+        REFERENCES users(id)
+        ON DELETE SET NULL,
+    CONSTRAINT fk_merge_requests_merged_by
+        FOREIGN KEY (merged_by)
+        REFERENCES users(id)
+        ON DELETE SET NULL
+);
+
+CREATE UNIQUE INDEX merge_requests_open_source_unique ON merge_requests (source_branch_id)
+WHERE status = 'open';
+
+CREATE TABLE merge_request_reviews (
+    merge_request_id UUID NOT NULL,
+    user_id UUID NOT NULL,
+    state TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (merge_request_id, user_id),
+    CONSTRAINT fk_merge_request_reviews_merge_request_id
+        FOREIGN KEY (merge_request_id)
+        REFERENCES merge_requests(id)
+        ON DELETE CASCADE,
+    CONSTRAINT fk_merge_request_reviews_user_id
+        FOREIGN KEY (user_id)
+        REFERENCES users(id)
+        ON DELETE CASCADE
+);
+
+CREATE TABLE merge_request_comments (
+    id UUID PRIMARY KEY,
+    merge_request_id UUID NOT NULL,
+    author_id UUID,
+    body TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    CONSTRAINT fk_merge_request_comments_merge_request_id
+        FOREIGN KEY (merge_request_id)
+        REFERENCES merge_requests(id)
+        ON DELETE CASCADE,
+    CONSTRAINT fk_merge_request_comments_author_id
+        FOREIGN KEY (author_id)
+        REFERENCES users(id)
+        ON DELETE SET NULL
+);
+
+-- +goose Down
+DROP TABLE merge_request_comments;
+DROP TABLE merge_request_reviews;
+DROP INDEX merge_requests_open_source_unique;
+DROP TABLE merge_requests;
+ALTER TABLE branches
+DROP COLUMN required_approvals;
@@ -0,0 +1,18 @@
+-- +goose Up
+CREATE TABLE base_snapshots (
+    branch_id UUID PRIMARY KEY,
+    parent_branch_id UUID,
+    data TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    CONSTRAINT fk_base_snapshots_branch_id
+        FOREIGN KEY (branch_id)
+        REFERENCES branches(id)
+        ON DELETE CASCADE,
+    CONSTRAINT fk_base_snapshots_parent_branch_id
+        FOREIGN KEY (parent_branch_id)
+        REFERENCES branches(id)
+        ON DELETE SET NULL
+);
+
+-- +goose Down
+DROP TABLE base_snapshots;
@@ -0,0 +1,46 @@
+-- +goose Up
+-- origin ids point at the sheet or column every copy descends from, so
+-- copies in any two branches can be matched even when the branches in
+-- between were deleted. NULL means the row is an origin itself.
+ALTER TABLE sheets ADD COLUMN origin_sheet_id UUID;
+ALTER TABLE columns ADD COLUMN origin_column_id UUID;
+
+UPDATE sheets SET origin_sheet_id = (
+    SELECT chain.origin FROM (
+        WITH RECURSIVE sheet_chain(id, origin, depth) AS (
+            SELECT id, source_sheet_id, 1 FROM sheets WHERE source_sheet_id IS NOT NULL
+            UNION ALL
+            SELECT sheet_chain.id, parent.source_sheet_id, sheet_chain.depth + 1
+            FROM sheet_chain
+            JOIN sheets parent ON parent.id = sheet_chain.origin
+            WHERE parent.source_sheet_id IS NOT NULL
+        )
+        SELECT id, origin, depth FROM sheet_chain
+    ) chain
+    WHERE chain.id = sheets.id
+    ORDER BY chain.depth DESC
+    LIMIT 1
+)
+WHERE source_sheet_id IS NOT NULL;
+
+UPDATE columns SET origin_column_id = (
+    SELECT chain.origin FROM (
+        WITH RECURSIVE column_chain(id, origin, depth) AS (
+            SELECT id, source_column_id, 1 FROM columns WHERE source_column_id IS NOT NULL
+            UNION ALL
+            SELECT column_chain.id, parent.source_column_id, column_chain.depth + 1
+            FROM column_chain
+            JOIN columns parent ON parent.id = column_chain.origin
+            WHERE parent.source_column_id IS NOT NULL
+        )
+        SELECT id, origin, depth FROM column_chain
+    ) chain
+    WHERE chain.id = columns.id
+    ORDER BY chain.depth DESC
+    LIMIT 1
+)
+WHERE source_column_id IS NOT NULL;
+
+-- +goose Down
+ALTER TABLE columns DROP COLUMN origin_column_id;
+ALTER TABLE sheets DROP COLUMN origin_sheet_id;
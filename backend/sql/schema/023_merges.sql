@@ -0,0 +1,41 @@
+-- +goose Up
+CREATE TABLE merges (
+    id UUID PRIMARY KEY,
+    table_id UUID NOT NULL,
+    source_branch_id UUID,
+    source_branch_name TEXT NOT NULL,
+    target_branch_id UUID,
+    target_branch_name TEXT NOT NULL,
+    user_id UUID,
+    merge_request_id UUID,
+    source_kept BOOLEAN NOT NULL DEFAULT FALSE,
+    resolutions TEXT NOT NULL DEFAULT '[]',
+    summary TEXT NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP NOT NULL,
+    CONSTRAINT fk_merges_table_id
+        FOREIGN KEY (table_id)
+        REFERENCES tables(id)
+        ON DELETE CASCADE,
+    CONSTRAINT fk_merges_source_branch_id
+        FOREIGN KEY (source_branch_id)
+        REFERENCES branches(id)
+        ON DELETE SET NULL,
+    CONSTRAINT fk_merges_target_branch_id
+        FOREIGN KEY (target_branch_id)
+        REFERENCES branches(id)
+        ON DELETE SET NULL,
+    CONSTRAINT fk_merges_user_id
+        FOREIGN KEY (user_id)
+        REFERENCES users(id)
+        ON DELETE SET NULL,
+    CONSTRAINT fk_merges_merge_request_id
+        FOREIGN KEY (merge_request_id)
+        REFERENCES merge_requests(id)
+        ON DELETE SET NULL
+);
+
+CREATE INDEX merges_table_id ON merges (table_id, created_at);
+
+-- +goose Down
+DROP INDEX merges_table_id;
+DROP TABLE merges;
@@ -0,0 +1,40 @@
+-- +goose Up
+-- sheet_rows gives every row of a sheet an id that stays the same when rows
+-- before it are deleted and their idx shifts. Rows are matched to cells by
+-- sheet and idx, origin_row_id works like origin_sheet_id for copies.
+CREATE TABLE sheet_rows (
+    id UUID PRIMARY KEY,
+    sheet_id UUID NOT NULL,
+    idx INTEGER NOT NULL,
+    origin_row_id UUID,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    CONSTRAINT fk_sheet_rows_sheet_id
+        FOREIGN KEY (sheet_id)
+        REFERENCES sheets(id)
+        ON DELETE CASCADE
+);
+
+CREATE INDEX sheet_rows_sheet_id_idx ON sheet_rows (sheet_id, idx);
+
+INSERT INTO sheet_rows (id, sheet_id, idx, created_at, updated_at)
+SELECT gen_random_uuid(), c.sheet_id, cd.idx, MIN(cd.created_at), datetime('now')
+FROM column_data cd
+JOIN columns c ON c.id = cd.column_id
+GROUP BY c.sheet_id, cd.idx;
+
+-- Copies made before rows had ids are matched to their origin by idx.
+UPDATE sheet_rows SET origin_row_id = (
+    SELECT origin.id
+    FROM sheets s
+    JOIN sheet_rows origin ON origin.sheet_id = s.origin_sheet_id AND origin.idx = sheet_rows.idx
+    WHERE s.id = sheet_rows.sheet_id
+);
+
+-- Base snapshots taken before rows had ids can not be matched to rows, the
+-- merge falls back to guessing the base from timestamps.
+DELETE FROM base_snapshots;
+
+-- +goose Down
+DROP INDEX sheet_rows_sheet_id_idx;
+DROP TABLE sheet_rows;
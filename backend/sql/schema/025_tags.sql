@@ -0,0 +1,40 @@
+-- +goose Up
+CREATE TABLE tags (
+    id UUID PRIMARY KEY,
+    table_id UUID NOT NULL,
+    branch_id UUID,
+    branch_name TEXT NOT NULL,
+    name TEXT NOT NULL,
+    message TEXT NOT NULL DEFAULT '',
+    author_id UUID,
+    data TEXT NOT NULL,
+    export_data TEXT,
+    created_at TIMESTAMP NOT NULL,
+    CONSTRAINT fk_tags_table_id
+        FOREIGN KEY (table_id)
+        REFERENCES tables(id)
+        ON DELETE CASCADE,
+    CONSTRAINT fk_tags_branch_id
+        FOREIGN KEY (branch_id)
+        REFERENCES branches(id)
+        ON DELETE SET NULL,
+    CONSTRAINT fk_tags_author_id
+        FOREIGN KEY (author_id)
+        REFERENCES users(id)
+        ON DELETE SET NULL
+);
+
+CREATE UNIQUE INDEX tags_table_id_name_unique ON tags (table_id, name);
+
+-- +goose StatementBegin
+CREATE TRIGGER tags_immutable
+BEFORE UPDATE OF table_id, branch_name, name, message, data, export_data, created_at ON tags
+BEGIN
+    SELECT RAISE(ABORT, 'tags are immutable');
+END;
+-- +goose StatementEnd
+
+-- +goose Down
+DROP TRIGGER tags_immutable;
+DROP INDEX tags_table_id_name_unique;
+DROP TABLE tags;
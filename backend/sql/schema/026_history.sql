@@ -0,0 +1,46 @@
+-- +goose Up
+-- history is an append-only log of the edits made to the sheets of a branch.
+-- sheet_id, column_id, row_id and cell_id have no foreign keys so the
+-- entries outlive what they describe, old_value and new_value hold json.
+CREATE TABLE history (
+    id UUID PRIMARY KEY,
+    seq INTEGER NOT NULL,
+    branch_id UUID NOT NULL,
+    sheet_id UUID NOT NULL,
+    column_id UUID,
+    row_id UUID,
+    cell_id UUID,
+    user_id UUID,
+    entity TEXT NOT NULL,
+    action TEXT NOT NULL,
+    old_value TEXT,
+    new_value TEXT,
+    created_at TIMESTAMP NOT NULL,
+    CONSTRAINT fk_history_branch_id
+        FOREIGN KEY (branch_id)
+        REFERENCES branches(id)
+        ON DELETE CASCADE,
+    CONSTRAINT fk_history_user_id
+        FOREIGN KEY (user_id)
+        REFERENCES users(id)
+        ON DELETE SET NULL
+);
+
+CREATE UNIQUE INDEX history_seq_unique ON history (seq);
+CREATE INDEX history_sheet_id_seq ON history (sheet_id, seq);
+CREATE INDEX history_cell_id_seq ON history (cell_id, seq);
+
+-- +goose StatementBegin
+CREATE TRIGGER history_append_only
+BEFORE UPDATE OF seq, branch_id, sheet_id, column_id, row_id, cell_id, entity, action, old_value, new_value, created_at ON history
+BEGIN
+    SELECT RAISE(ABORT, 'history is append-only');
+END;
+-- +goose StatementEnd
+
+-- +goose Down
+DROP TRIGGER history_append_only;
+DROP INDEX history_cell_id_seq;
+DROP INDEX history_sheet_id_seq;
+DROP INDEX history_seq_unique;
+DROP TABLE history;
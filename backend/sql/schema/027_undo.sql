@@ -0,0 +1,30 @@
+-- +goose Up
+-- undo_entries are the undo and redo stacks of a user on a branch. Each entry
+-- points at the history entry of an edit, undone entries form the redo stack.
+CREATE TABLE undo_entries (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL,
+    branch_id UUID NOT NULL,
+    history_id UUID NOT NULL,
+    undone BOOLEAN NOT NULL DEFAULT false,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    CONSTRAINT fk_undo_entries_user_id
+        FOREIGN KEY (user_id)
+        REFERENCES users(id)
+        ON DELETE CASCADE,
+    CONSTRAINT fk_undo_entries_branch_id
+        FOREIGN KEY (branch_id)
+        REFERENCES branches(id)
+        ON DELETE CASCADE,
+    CONSTRAINT fk_undo_entries_history_id
+        FOREIGN KEY (history_id)
+        REFERENCES history(id)
+        ON DELETE CASCADE
+);
+
+CREATE INDEX undo_entries_user_id_branch_id ON undo_entries (user_id, branch_id);
+
+-- +goose Down
+DROP INDEX undo_entries_user_id_branch_id;
+DROP TABLE undo_entries;
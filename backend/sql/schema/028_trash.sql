@@ -0,0 +1,44 @@
+-- +goose Up
+-- Deleted projects, branches, sheets and columns stay in the trash with
+-- deleted_at set until they are restored or purged after the retention
+-- period, the queries skip them.
+ALTER TABLE tables ADD COLUMN deleted_at TIMESTAMP;
+ALTER TABLE tables ADD COLUMN deleted_by UUID REFERENCES users(id) ON DELETE SET NULL;
+ALTER TABLE branches ADD COLUMN deleted_at TIMESTAMP;
+ALTER TABLE branches ADD COLUMN deleted_by UUID REFERENCES users(id) ON DELETE SET NULL;
+ALTER TABLE sheets ADD COLUMN deleted_at TIMESTAMP;
+ALTER TABLE sheets ADD COLUMN deleted_by UUID REFERENCES users(id) ON DELETE SET NULL;
+ALTER TABLE columns ADD COLUMN deleted_at TIMESTAMP;
+ALTER TABLE columns ADD COLUMN deleted_by UUID REFERENCES users(id) ON DELETE SET NULL;
+
+-- A column in the trash does not keep its name taken.
+DROP INDEX columns_name_sheet_id_unique;
+CREATE UNIQUE INDEX columns_name_sheet_id_unique ON columns (name, sheet_id) WHERE deleted_at IS NULL;
+
+CREATE INDEX tables_deleted_at ON tables (deleted_at);
+CREATE INDEX branches_deleted_at ON branches (deleted_at);
+CREATE INDEX sheets_deleted_at ON sheets (deleted_at);
+CREATE INDEX columns_deleted_at ON columns (deleted_at);
+
+-- +goose Down
+DROP INDEX columns_deleted_at;
+DROP INDEX sheets_deleted_at;
+DROP INDEX branches_deleted_at;
+DROP INDEX tables_deleted_at;
+
+DELETE FROM columns WHERE deleted_at IS NOT NULL;
+DELETE FROM sheets WHERE deleted_at IS NOT NULL;
+DELETE FROM branches WHERE deleted_at IS NOT NULL;
+DELETE FROM tables WHERE deleted_at IS NOT NULL;
+
+DROP INDEX columns_name_sheet_id_unique;
+CREATE UNIQUE INDEX columns_name_sheet_id_unique ON columns (name, sheet_id);
+
+ALTER TABLE columns DROP COLUMN deleted_by;
+ALTER TABLE columns DROP COLUMN deleted_at;
+ALTER TABLE sheets DROP COLUMN deleted_by;
+ALTER TABLE sheets DROP COLUMN deleted_at;
+ALTER TABLE branches DROP COLUMN deleted_by;
+ALTER TABLE branches DROP COLUMN deleted_at;
+ALTER TABLE tables DROP COLUMN deleted_by;
+ALTER TABLE tables DROP COLUMN deleted_at;
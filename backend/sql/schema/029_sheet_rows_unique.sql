@@ -0,0 +1,18 @@
+-- +goose Up
+-- Row ids were given outside of transactions, so concurrent requests could
+-- give the same row two ids. The oldest id of a row is kept.
+DELETE FROM sheet_rows
+WHERE EXISTS (
+    SELECT 1 FROM sheet_rows older
+    WHERE older.sheet_id = sheet_rows.sheet_id
+    AND older.idx = sheet_rows.idx
+    AND (older.created_at < sheet_rows.created_at
+        OR (older.created_at = sheet_rows.created_at AND older.id < sheet_rows.id))
+);
+
+DROP INDEX sheet_rows_sheet_id_idx;
+CREATE UNIQUE INDEX sheet_rows_sheet_id_idx ON sheet_rows (sheet_id, idx);
+
+-- +goose Down
+DROP INDEX sheet_rows_sheet_id_idx;
+CREATE INDEX sheet_rows_sheet_id_idx ON sheet_rows (sheet_id, idx);
@@ -0,0 +1,7 @@
+-- +goose Up
+-- Reviews are given on the data the source branch had at the time, an
+-- approval stops counting once the source branch changes.
+ALTER TABLE merge_request_reviews ADD COLUMN source_token TEXT NOT NULL DEFAULT '';
+
+-- +goose Down
+ALTER TABLE merge_request_reviews DROP COLUMN source_token;
@@ -0,0 +1,9 @@
+-- +goose Up
+-- data_modified_at is bumped by every write to the data of the branch, it is
+-- the Last-Modified of /json. The newest updated_at of the rows could go
+-- backwards when rows were deleted or trashed.
+ALTER TABLE branches ADD COLUMN data_modified_at TIMESTAMP;
+UPDATE branches SET data_modified_at = datetime('now');
+
+-- +goose Down
+ALTER TABLE branches DROP COLUMN data_modified_at;
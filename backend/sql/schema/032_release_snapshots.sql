@@ -0,0 +1,7 @@
+-- +goose Up
+-- Releases keep the sheets and columns they were published from, so the
+-- json schema of an older release still describes its data.
+ALTER TABLE releases ADD COLUMN snapshot TEXT;
+
+-- +goose Down
+ALTER TABLE releases DROP COLUMN snapshot;
@@ -61,7 +61,29 @@ func (cfg *apiConfig) swapColumnsHandler(w http.ResponseWriter, r *http.Request,
 		}
 	}
 
-	_, err = cfg.db.SwapColumnsWithPermissionCheck(r.Context(), database.SwapColumnsWithPermissionCheckParams{
+	column, err := cfg.db.GetColumn(r.Context(), params.ColumnID1)
+	if err != nil {
+		msg := fmt.Sprintf("Could not get column: %s", err)
+		respondWithError(w, http.StatusInternalServerError, msg)
+		return
+	}
+	sheet, err := cfg.db.GetSheet(r.Context(), column.SheetID)
+	if err != nil {
+		msg := fmt.Sprintf("Could not get sheet of column: %s", err)
+		respondWithError(w, http.StatusInternalServerError, msg)
+		return
+	}
+
+	tx, err := cfg.rawDB.BeginTx(r.Context(), nil)
+	if err != nil {
+		msg := fmt.Sprintf("Could not begin transaction: %s", err)
+		respondWithError(w, http.StatusInternalServerError, msg)
+		return
+	}
+	defer tx.Rollback()
+	txQueries := cfg.db.WithTx(tx)
+
+	_, err = txQueries.SwapColumnsWithPermissionCheck(r.Context(), database.SwapColumnsWithPermissionCheckParams{
 		ID:           params.ColumnID1,
 		ID_2:         params.ColumnID2,
 		OrderIndex:   col2Order,
@@ -74,5 +96,27 @@ func (cfg *apiConfig) swapColumnsHandler(w http.ResponseWriter, r *http.Request,
 		return
 	}
 
+	err = recordUndoableHistory(r.Context(), txQueries, id, historyEntry{
+		branchId: sheet.BranchID,
+		sheetId:  sheet.ID,
+		columnId: uuid.NullUUID{UUID: params.ColumnID1, Valid: true},
+		entity:   HistoryEntityColumn,
+		action:   HistoryActionSwap,
+		oldValue: map[uuid.UUID]int64{params.ColumnID1: col1Order, params.ColumnID2: col2Order},
+		newValue: map[uuid.UUID]int64{params.ColumnID1: col2Order, params.ColumnID2: col1Order},
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		msg := fmt.Sprintf("Could not commit column swap: %s", err)
+		respondWithError(w, http.StatusInternalServerError, msg)
+		return
+	}
+
+	cfg.invalidateColumnJson(params.ColumnID1, r.Context())
 	respondWithJSON(w, http.StatusOK, "")
 }
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/Dass33/administratum/backend/internal/database"
+	"github.com/google/uuid"
+)
+
+// SyncRequest brings the changes of the parent into the branch. It is a merge
+// of the parent into the branch, so the "source" side of a conflict is the
+// parent and the "target" side the branch.
+type SyncRequest struct {
+	BranchID uuid.UUID `json:"branch_id"`
+	// ParentBranchID defaults to the branch the base of the branch was taken
+	// from, or the oldest branch of the table.
+	ParentBranchID uuid.NullUUID     `json:"parent_branch_id"`
+	Resolutions    []MergeResolution `json:"resolutions"`
+	Strategies     []MergeStrategy   `json:"strategies"`
+	PreviewToken   string            `json:"preview_token"`
+}
+
+func (cfg *apiConfig) syncPreviewHandler(w http.ResponseWriter, r *http.Request, userId uuid.UUID) {
+	var req SyncRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		msg := fmt.Sprintf("Error decoding parameters: %s", err)
+		respondWithError(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	ctx := r.Context()
+
+	branch, parentBranch, ok := cfg.getSyncBranches(w, userId, req, ctx)
+	if !ok {
+		return
+	}
+
+	plan, err := cfg.getMergePlan(parentBranch, branch, MergeSelection{}, ctx)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, MergePreviewResponse{
+		Conflicts:    plan.conflictList(),
+		PreviewToken: plan.token,
+	})
+}
+
+func (cfg *apiConfig) syncExecuteHandler(w http.ResponseWriter, r *http.Request, userId uuid.UUID) {
+	var req SyncRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		msg := fmt.Sprintf("Error decoding parameters: %s", err)
+		respondWithError(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	ctx := r.Context()
+
+	branch, parentBranch, ok := cfg.getSyncBranches(w, userId, req, ctx)
+	if !ok {
+		return
+	}
+
+	if branch.RequiredApprovals > 0 {
+		msg := fmt.Sprintf("Branch requires %d approvals, open a merge request instead", branch.RequiredApprovals)
+		respondWithError(w, http.StatusForbidden, msg)
+		return
+	}
+
+	mergeReq := MergeExecuteRequest{
+		SourceBranchID: parentBranch.ID,
+		TargetBranchID: uuid.NullUUID{UUID: branch.ID, Valid: true},
+		Resolutions:    req.Resolutions,
+		Strategies:     req.Strategies,
+		PreviewToken:   req.PreviewToken,
+		KeepSource:     true,
+	}
+	// The branch now holds everything of the parent, so the current state
+	// of the parent becomes its new base.
+	finish := func(ctx context.Context, q *database.Queries, plan mergePlan, resolutions []MergeResolution) error {
+		err := cfg.setBaseSnapshotInTx(ctx, q, parentBranch.ID, branch.ID)
+		if err != nil {
+			return fmt.Errorf("Could not update the base of the branch: %v", err)
+		}
+		return recordMerge(ctx, q, userId, parentBranch, branch, mergeReq, true, resolutions, plan.summary)
+	}
+
+	if !cfg.runMerge(w, userId, parentBranch, branch, mergeReq, finish, ctx) {
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, MergeExecuteResponse{
+		Success:        true,
+		Message:        fmt.Sprintf("Branch synced with %s", parentBranch.Name),
+		TargetBranchID: branch.ID,
+	})
+}
+
+// getSyncBranches loads the branch to sync and its parent and checks the
+// user may merge into the branch. It writes the error response itself and
+// returns false when the sync is not possible.
+func (cfg *apiConfig) getSyncBranches(w http.ResponseWriter, userId uuid.UUID, req SyncRequest, ctx context.Context) (database.Branch, database.Branch, bool) {
+	branch, err := cfg.db.GetBranch(ctx, req.BranchID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Branch not found")
+		return database.Branch{}, database.Branch{}, false
+	}
+
+	parentBranchId := req.ParentBranchID
+	if !parentBranchId.Valid {
+		baseSnapshot, err := cfg.db.GetBaseSnapshot(ctx, branch.ID)
+		if err != nil && err != sql.ErrNoRows {
+			msg := fmt.Sprintf("Could not get base snapshot: %s", err)
+			respondWithError(w, http.StatusInternalServerError, msg)
+			return database.Branch{}, database.Branch{}, false
+		}
+		if err == nil {
+			parentBranchId = baseSnapshot.ParentBranchID
+		}
+	}
+
+	parentBranch, ok := cfg.getMergeTarget(w, branch, parentBranchId, ctx)
+	if !ok {
+		return database.Branch{}, database.Branch{}, false
+	}
+
+	if !cfg.checkBranchPermission(userId, parentBranch.ID, "read", ctx) {
+		respondWithError(w, http.StatusForbidden, "No read permission on parent branch")
+		return database.Branch{}, database.Branch{}, false
+	}
+
+	if !cfg.checkBranchPermission(userId, branch.ID, "merge", ctx) {
+		respondWithError(w, http.StatusForbidden, "No write permission on branch")
+		return database.Branch{}, database.Branch{}, false
+	}
+	return branch, parentBranch, true
+}
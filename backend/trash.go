@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Dass33/administratum/backend/internal/database"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+const (
+	TrashKindProject = "project"
+	TrashKindBranch  = "branch"
+	TrashKindSheet   = "sheet"
+	TrashKindColumn  = "column"
+)
+
+// TrashItem is a deleted project, branch, sheet or column. It can be
+// restored until PurgeAt, when the trash sweeper deletes it for good.
+type TrashItem struct {
+	Kind           string        `json:"kind"`
+	ID             uuid.UUID     `json:"id"`
+	Name           string        `json:"name"`
+	BranchID       uuid.NullUUID `json:"branch_id,omitempty"`
+	BranchName     string        `json:"branch_name,omitempty"`
+	SheetID        uuid.NullUUID `json:"sheet_id,omitempty"`
+	SheetName      string        `json:"sheet_name,omitempty"`
+	DeletedByEmail string        `json:"deleted_by_email"`
+	DeletedAt      time.Time     `json:"deleted_at"`
+	PurgeAt        time.Time     `json:"purge_at"`
+}
+
+// ProjectTrash lists the trash of a project. Items of a deleted branch or
+// sheet are not listed on their own, they come back with it.
+type ProjectTrash struct {
+	Branches []TrashItem `json:"branches"`
+	Sheets   []TrashItem `json:"sheets"`
+	Columns  []TrashItem `json:"columns"`
+}
+
+func newTrashItem(kind string, id uuid.UUID, name string, deletedAt sql.NullTime, deletedBy sql.NullString) TrashItem {
+	return TrashItem{
+		Kind:           kind,
+		ID:             id,
+		Name:           name,
+		DeletedByEmail: deletedBy.String,
+		DeletedAt:      deletedAt.Time,
+		PurgeAt:        deletedAt.Time.Add(trashRetention),
+	}
+}
+
+// checkTrashOwner tells if the user owns the project, also when the project
+// itself is in the trash.
+func (cfg *apiConfig) checkTrashOwner(userId, tableId uuid.UUID, ctx context.Context) bool {
+	userTable, err := cfg.db.GetTrashUserTable(ctx, database.GetTrashUserTableParams{
+		UserID:  userId,
+		TableID: tableId,
+	})
+	if err != nil {
+		return false
+	}
+	return userTable.Permission == OwnerPermission
+}
+
+// getTrashHandler lists the deleted projects the user owns.
+func (cfg *apiConfig) getTrashHandler(w http.ResponseWriter, r *http.Request, userId uuid.UUID) {
+	tablesDb, err := cfg.db.GetTrashedTables(r.Context(), userId)
+	if err != nil {
+		msg := fmt.Sprintf("Could not get deleted projects: %s", err)
+		respondWithError(w, http.StatusInternalServerError, msg)
+		return
+	}
+
+	items := make([]TrashItem, 0, len(tablesDb))
+	for _, table := range tablesDb {
+		items = append(items, newTrashItem(TrashKindProject, table.ID, table.Name, table.DeletedAt, table.DeletedByEmail))
+	}
+	respondWithJSON(w, http.StatusOK, items)
+}
+
+func (cfg *apiConfig) getProjectTrashHandler(w http.ResponseWriter, r *http.Request, userId uuid.UUID) {
+	tableIdStr := chi.URLParam(r, "table_id")
+	tableId, err := uuid.Parse(tableIdStr)
+	if err != nil {
+		msg := fmt.Sprintf("Could not parse the table id from url: %s", err)
+		respondWithError(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	if !cfg.checkTrashOwner(userId, tableId, r.Context()) {
+		respondWithError(w, http.StatusForbidden, "Only owners can see the trash")
+		return
+	}
+
+	branchesDb, err := cfg.db.GetTrashedBranches(r.Context(), tableId)
+	if err != nil {
+		msg := fmt.Sprintf("Could not get deleted branches: %s", err)
+		respondWithError(w, http.StatusInternalServerError, msg)
+		return
+	}
+	sheetsDb, err := cfg.db.GetTrashedSheets(r.Context(), tableId)
+	if err != nil {
+		msg := fmt.Sprintf("Could not get deleted sheets: %s", err)
+		respondWithError(w, http.StatusInternalServerError, msg)
+		return
+	}
+	columnsDb, err := cfg.db.GetTrashedColumns(r.Context(), tableId)
+	if err != nil {
+		msg := fmt.Sprintf("Could not get deleted columns: %s", err)
+		respondWithError(w, http.StatusInternalServerError, msg)
+		return
+	}
+
+	trash := ProjectTrash{
+		Branches: make([]TrashItem, 0, len(branchesDb)),
+		Sheets:   make([]TrashItem, 0, len(sheetsDb)),
+		Columns:  make([]TrashItem, 0, len(columnsDb)),
+	}
+	for _, branch := range branchesDb {
+		trash.Branches = append(trash.Branches, newTrashItem(TrashKindBranch, branch.ID, branch.Name, branch.DeletedAt, branch.DeletedByEmail))
+	}
+	for _, sheet := range sheetsDb {
+		item := newTrashItem(TrashKindSheet, sheet.ID, sheet.Name, sheet.DeletedAt, sheet.DeletedByEmail)
+		item.BranchID = uuid.NullUUID{UUID: sheet.BranchID, Valid: true}
+		item.BranchName = sheet.BranchName
+		trash.Sheets = append(trash.Sheets, item)
+	}
+	for _, column := range columnsDb {
+		item := newTrashItem(TrashKindColumn, column.ID, column.Name, column.DeletedAt, column.DeletedByEmail)
+		item.BranchID = uuid.NullUUID{UUID: column.BranchID, Valid: true}
+		item.BranchName = column.BranchName
+		item.SheetID = uuid.NullUUID{UUID: column.SheetID, Valid: true}
+		item.SheetName = column.SheetName
+		trash.Columns = append(trash.Columns, item)
+	}
+	respondWithJSON(w, http.StatusOK, trash)
+}
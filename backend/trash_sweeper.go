@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+)
+
+const (
+	trashRetention     = 30 * 24 * time.Hour
+	trashSweepInterval = time.Hour
+)
+
+// startTrashSweeper purges the items that stayed in the trash longer than
+// trashRetention, once on start and then every trashSweepInterval.
+func (cfg *apiConfig) startTrashSweeper(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(trashSweepInterval)
+		defer ticker.Stop()
+		for {
+			if err := cfg.purgeTrash(ctx); err != nil {
+				log.Printf("Could not purge the trash: %s", err)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// purgeTrash deletes columns before their sheets, sheets before their
+// branches and branches before their projects, the cascades take what is
+// left inside them.
+func (cfg *apiConfig) purgeTrash(ctx context.Context) error {
+	retention := retentionModifier(trashRetention)
+
+	purges := []struct {
+		kind  string
+		purge func(context.Context, string) (int64, error)
+	}{
+		{TrashKindColumn, cfg.db.PurgeTrashedColumns},
+		{TrashKindSheet, cfg.db.PurgeTrashedSheets},
+		{TrashKindBranch, cfg.db.PurgeTrashedBranches},
+		{TrashKindProject, cfg.db.PurgeTrashedTables},
+	}
+	for _, p := range purges {
+		purged, err := p.purge(ctx, retention)
+		if err != nil {
+			return fmt.Errorf("could not purge %s trash: %w", p.kind, err)
+		}
+		if purged > 0 {
+			log.Printf("Purged %d %s items from the trash", purged, p.kind)
+		}
+	}
+	return nil
+}
+
+// retentionModifier is the sqlite datetime modifier going back by retention,
+// the purge queries delete what was trashed before that time.
+func retentionModifier(retention time.Duration) string {
+	return fmt.Sprintf("-%d seconds", int64(retention.Seconds()))
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestRetentionModifier(t *testing.T) {
+	tests := []struct {
+		retention time.Duration
+		want      string
+	}{
+		{retention: trashRetention, want: "-2592000 seconds"},
+		{retention: time.Hour, want: "-3600 seconds"},
+		{retention: 1500 * time.Millisecond, want: "-1 seconds"},
+	}
+
+	for _, tt := range tests {
+		if got := retentionModifier(tt.retention); got != tt.want {
+			t.Errorf("retentionModifier(%s) = %q, want %q", tt.retention, got, tt.want)
+		}
+	}
+}
+
+func TestTrashItemPurgeAt(t *testing.T) {
+	deletedAt := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	item := newTrashItem(TrashKindSheet, uuid.New(), "items", sql.NullTime{Time: deletedAt, Valid: true}, sql.NullString{String: "owner@example.com", Valid: true})
+
+	if want := time.Date(2025, 3, 31, 12, 0, 0, 0, time.UTC); !item.PurgeAt.Equal(want) {
+		t.Errorf("purge at = %s, want %s", item.PurgeAt, want)
+	}
+	if item.DeletedByEmail != "owner@example.com" {
+		t.Errorf("deleted by = %q, want owner@example.com", item.DeletedByEmail)
+	}
+}
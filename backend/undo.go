@@ -0,0 +1,765 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Dass33/administratum/backend/internal/database"
+	"github.com/google/uuid"
+)
+
+// undoStackSize is how many edits of a user on a branch can be undone.
+const undoStackSize = 100
+
+type undoParams struct {
+	BranchId string `json:"branch_id"`
+	// Count is how many edits to undo or redo, one when left empty.
+	Count int `json:"count"`
+}
+
+// UndoResponse tells how many edits were undone or redone. Conflict explains
+// why it stopped before the requested count.
+type UndoResponse struct {
+	Count    int    `json:"count"`
+	Conflict string `json:"conflict,omitempty"`
+}
+
+// UndoConflictError refuses to undo or redo an edit when someone else has
+// changed the same cells since, the edit stays on the stack.
+type UndoConflictError struct {
+	Reason string
+}
+
+func (e *UndoConflictError) Error() string {
+	return e.Reason
+}
+
+// changedOutsideHistory is the conflict of an edit whose item no longer
+// looks like the history left it. Merges, syncs, imports and tag restores
+// do not record every change in the history, so findUndoConflict can miss
+// them.
+func changedOutsideHistory(target string) error {
+	return &UndoConflictError{Reason: target + " was changed since, by a merge, sync, import or tag restore"}
+}
+
+// recordUndoableHistory records the edit and pushes it on the undo stack of
+// the user on the branch. A new edit clears the redo stack.
+func recordUndoableHistory(ctx context.Context, q *database.Queries, userId uuid.UUID, entry historyEntry) error {
+	history, err := createHistoryEntry(ctx, q, userId, entry)
+	if err != nil {
+		return err
+	}
+
+	err = q.ClearRedoEntries(ctx, database.ClearRedoEntriesParams{
+		UserID:   userId,
+		BranchID: entry.branchId,
+	})
+	if err != nil {
+		return fmt.Errorf("could not clear redo stack: %w", err)
+	}
+	err = q.CreateUndoEntry(ctx, database.CreateUndoEntryParams{
+		UserID:    userId,
+		BranchID:  entry.branchId,
+		HistoryID: history.ID,
+	})
+	if err != nil {
+		return fmt.Errorf("could not push undo entry: %w", err)
+	}
+	err = q.PruneUndoEntries(ctx, database.PruneUndoEntriesParams{
+		UserID:   userId,
+		BranchID: entry.branchId,
+		Keep:     undoStackSize,
+	})
+	if err != nil {
+		return fmt.Errorf("could not prune undo stack: %w", err)
+	}
+	return nil
+}
+
+func (cfg *apiConfig) undoHandler(w http.ResponseWriter, r *http.Request, userId uuid.UUID) {
+	cfg.undoRedo(w, r, userId, true)
+}
+
+// undoRedo undoes or redoes the last edits of the user on the branch in one
+// transaction, stopping at the first edit that conflicts with a later edit
+// of someone else.
+func (cfg *apiConfig) undoRedo(w http.ResponseWriter, r *http.Request, userId uuid.UUID, undo bool) {
+	decoder := json.NewDecoder(r.Body)
+	params := undoParams{}
+	err := decoder.Decode(&params)
+	if err != nil {
+		msg := fmt.Sprintf("Error decoding parameters: %s", err)
+		respondWithError(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	branchId, err := uuid.Parse(params.BranchId)
+	if err != nil {
+		msg := fmt.Sprintf("Could not parse the branch id: %s", err)
+		respondWithError(w, http.StatusBadRequest, msg)
+		return
+	}
+	if params.Count == 0 {
+		params.Count = 1
+	}
+	if params.Count < 0 || params.Count > undoStackSize {
+		msg := fmt.Sprintf("Count has to be between 1 and %d", undoStackSize)
+		respondWithError(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	if !cfg.checkBranchPermission(userId, branchId, "write", r.Context()) {
+		respondWithError(w, http.StatusForbidden, "Insufficient write permissions")
+		return
+	}
+
+	ctx := r.Context()
+	tx, err := cfg.rawDB.BeginTx(ctx, nil)
+	if err != nil {
+		msg := fmt.Sprintf("Could not begin transaction: %s", err)
+		respondWithError(w, http.StatusInternalServerError, msg)
+		return
+	}
+	defer tx.Rollback()
+	txQueries := cfg.db.WithTx(tx)
+
+	response := UndoResponse{}
+	for response.Count < params.Count {
+		entry, err := lastUndoEntry(ctx, txQueries, userId, branchId, undo)
+		if err == sql.ErrNoRows {
+			break
+		}
+		if err != nil {
+			msg := fmt.Sprintf("Could not get undo entry: %s", err)
+			respondWithError(w, http.StatusInternalServerError, msg)
+			return
+		}
+
+		err = findUndoConflict(ctx, txQueries, userId, entry)
+		if err == nil {
+			err = replayHistoryEntry(ctx, txQueries, userId, entry, undo)
+		}
+		var conflict *UndoConflictError
+		if errors.As(err, &conflict) {
+			response.Conflict = conflict.Reason
+			break
+		}
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		err = txQueries.SetUndoEntryUndone(ctx, database.SetUndoEntryUndoneParams{
+			Undone: undo,
+			ID:     entry.UndoEntryID,
+		})
+		if err != nil {
+			msg := fmt.Sprintf("Could not update undo entry: %s", err)
+			respondWithError(w, http.StatusInternalServerError, msg)
+			return
+		}
+		response.Count++
+	}
+
+	if response.Count == 0 {
+		switch {
+		case response.Conflict != "":
+			respondWithError(w, http.StatusConflict, response.Conflict)
+		case undo:
+			respondWithError(w, http.StatusConflict, "Nothing to undo")
+		default:
+			respondWithError(w, http.StatusConflict, "Nothing to redo")
+		}
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		msg := fmt.Sprintf("Could not commit undo: %s", err)
+		respondWithError(w, http.StatusInternalServerError, msg)
+		return
+	}
+	cfg.invalidateBranchJson(branchId)
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+// lastUndoEntry returns the top of the undo stack, or of the redo stack when
+// undo is false.
+func lastUndoEntry(ctx context.Context, q *database.Queries, userId, branchId uuid.UUID, undo bool) (database.GetLastUndoEntryRow, error) {
+	if undo {
+		return q.GetLastUndoEntry(ctx, database.GetLastUndoEntryParams{
+			UserID:   userId,
+			BranchID: branchId,
+		})
+	}
+	entry, err := q.GetLastRedoEntry(ctx, database.GetLastRedoEntryParams{
+		UserID:   userId,
+		BranchID: branchId,
+	})
+	return database.GetLastUndoEntryRow(entry), err
+}
+
+// findUndoConflict looks for a later edit of someone else that touches what
+// the entry changed. Undoing or redoing the entry would overwrite it.
+func findUndoConflict(ctx context.Context, q *database.Queries, userId uuid.UUID, entry database.GetLastUndoEntryRow) error {
+	later, err := q.GetSheetHistoryAfter(ctx, database.GetSheetHistoryAfterParams{
+		SheetID: entry.SheetID,
+		Seq:     entry.Seq,
+	})
+	if err != nil {
+		return fmt.Errorf("Could not get later history: %s", err)
+	}
+
+	for _, edit := range later {
+		if edit.UserID.Valid && edit.UserID.UUID == userId {
+			continue
+		}
+		if !historyConflicts(entry, edit) {
+			continue
+		}
+		author := edit.UserEmail.String
+		if author == "" {
+			author = "a deleted user"
+		}
+		return &UndoConflictError{Reason: fmt.Sprintf(
+			"%s was changed by %s at %s",
+			describeHistoryTarget(entry), author, edit.CreatedAt.Format(time.RFC3339),
+		)}
+	}
+	return nil
+}
+
+func historyConflicts(entry database.GetLastUndoEntryRow, edit database.GetSheetHistoryAfterRow) bool {
+	if edit.Entity == HistoryEntitySheet && edit.Action == HistoryActionDelete {
+		return true
+	}
+
+	switch {
+	case entry.Entity == HistoryEntityCell:
+		return sameId(edit.CellID, entry.CellID) ||
+			(edit.Entity == HistoryEntityRow && sameId(edit.RowID, entry.RowID)) ||
+			(edit.Entity == HistoryEntityColumn && edit.Action != HistoryActionSwap && sameId(edit.ColumnID, entry.ColumnID))
+	case entry.Entity == HistoryEntityRow:
+		// Other rows moving shifts the idx the row is restored at.
+		return edit.Entity == HistoryEntityRow || sameId(edit.RowID, entry.RowID) ||
+			(edit.Entity == HistoryEntityColumn && edit.Action == HistoryActionDelete)
+	case entry.Entity == HistoryEntityColumn && entry.Action == HistoryActionSwap:
+		order := swappedColumns(entry.OldValue)
+		for columnId := range order {
+			if edit.Entity == HistoryEntityColumn && touchesColumn(edit, columnId) {
+				return true
+			}
+		}
+		return false
+	case entry.Entity == HistoryEntityColumn:
+		return touchesColumn(edit, entry.ColumnID.UUID) ||
+			(edit.Entity == HistoryEntitySheet && edit.Action == HistoryActionSetKey)
+	case entry.Entity == HistoryEntitySheet:
+		return edit.Entity == HistoryEntitySheet && edit.Action == HistoryActionRename
+	}
+	return false
+}
+
+func touchesColumn(edit database.GetSheetHistoryAfterRow, columnId uuid.UUID) bool {
+	if edit.ColumnID.Valid && edit.ColumnID.UUID == columnId {
+		return true
+	}
+	if edit.Action != HistoryActionSwap {
+		return false
+	}
+	_, ok := swappedColumns(edit.OldValue)[columnId]
+	return ok
+}
+
+func swappedColumns(value sql.NullString) map[uuid.UUID]int64 {
+	order := map[uuid.UUID]int64{}
+	json.Unmarshal([]byte(value.String), &order)
+	return order
+}
+
+func sameId(a, b uuid.NullUUID) bool {
+	return a.Valid && b.Valid && a.UUID == b.UUID
+}
+
+func describeHistoryTarget(entry database.GetLastUndoEntryRow) string {
+	switch {
+	case entry.Entity == HistoryEntityCell:
+		return "The cell"
+	case entry.Entity == HistoryEntityRow:
+		return "The rows of the sheet"
+	case entry.Action == HistoryActionSwap:
+		return "The column order"
+	case entry.Entity == HistoryEntityColumn:
+		return "The column"
+	}
+	return "The sheet name"
+}
+
+// replayHistoryEntry reverts the edit of the entry when undo is true and
+// makes it again otherwise. The change is recorded in the history without
+// touching the undo stacks.
+func replayHistoryEntry(ctx context.Context, q *database.Queries, userId uuid.UUID, entry database.GetLastUndoEntryRow, undo bool) error {
+	from, to := entry.NewValue, entry.OldValue
+	if !undo {
+		from, to = to, from
+	}
+
+	switch {
+	case entry.Entity == HistoryEntityCell && entry.Action == HistoryActionUpdate:
+		return replayCellUpdate(ctx, q, userId, entry, from, to)
+	case entry.Entity == HistoryEntityRow && entry.Action == HistoryActionDelete:
+		if undo {
+			return restoreRow(ctx, q, userId, entry)
+		}
+		return deleteRowAgain(ctx, q, userId, entry)
+	case entry.Entity == HistoryEntityColumn && entry.Action == HistoryActionDelete:
+		if undo {
+			return restoreColumn(ctx, q, userId, entry)
+		}
+		return deleteColumnAgain(ctx, q, userId, entry)
+	case entry.Entity == HistoryEntityColumn && entry.Action == HistoryActionSwap:
+		return replayColumnSwap(ctx, q, userId, entry, from, to)
+	case entry.Entity == HistoryEntitySheet && entry.Action == HistoryActionRename:
+		return replaySheetRename(ctx, q, userId, entry, from, to)
+	}
+	return &UndoConflictError{Reason: fmt.Sprintf("A %s %s can not be undone", entry.Entity, entry.Action)}
+}
+
+func decodeHistoryValue(value sql.NullString, v any) error {
+	err := json.Unmarshal([]byte(value.String), v)
+	if err != nil {
+		return fmt.Errorf("Could not decode history value: %s", err)
+	}
+	return nil
+}
+
+func replayCellUpdate(ctx context.Context, q *database.Queries, userId uuid.UUID, entry database.GetLastUndoEntryRow, from, to sql.NullString) error {
+	expected := HistoryCellValue{}
+	err := decodeHistoryValue(from, &expected)
+	if err != nil {
+		return err
+	}
+	value := HistoryCellValue{}
+	err = decodeHistoryValue(to, &value)
+	if err != nil {
+		return err
+	}
+
+	column, err := q.GetColumnDataColumn(ctx, entry.CellID.UUID)
+	if err == sql.ErrNoRows {
+		return &UndoConflictError{Reason: "The cell no longer exists"}
+	}
+	if err != nil {
+		return fmt.Errorf("Could not get cell: %s", err)
+	}
+	if historyCellValue(column.DataValue, column.DataType) != expected {
+		return changedOutsideHistory("The cell")
+	}
+
+	err = q.UpdateColumnData(ctx, database.UpdateColumnDataParams{
+		Value: sql.NullString{String: value.Value, Valid: true},
+		Type:  nullCellType(value.Type),
+		ID:    entry.CellID.UUID,
+	})
+	if err != nil {
+		return fmt.Errorf("Could not update cell: %s", err)
+	}
+
+	return recordHistory(ctx, q, userId, historyEntry{
+		branchId: entry.BranchID,
+		sheetId:  entry.SheetID,
+		columnId: entry.ColumnID,
+		rowId:    entry.RowID,
+		cellId:   entry.CellID,
+		entity:   HistoryEntityCell,
+		action:   HistoryActionUpdate,
+		oldValue: historyCellValue(column.DataValue, column.DataType),
+		newValue: value,
+	})
+}
+
+// restoreRow puts the deleted row back at its idx with its ids, the rows
+// from there on move down by one.
+func restoreRow(ctx context.Context, q *database.Queries, userId uuid.UUID, entry database.GetLastUndoEntryRow) error {
+	value := HistoryRowValue{}
+	err := decodeHistoryValue(entry.OldValue, &value)
+	if err != nil {
+		return err
+	}
+
+	_, err = q.GetSheetRow(ctx, entry.RowID.UUID)
+	if err == nil {
+		return changedOutsideHistory("The deleted row")
+	}
+	if err != sql.ErrNoRows {
+		return fmt.Errorf("Could not get row: %s", err)
+	}
+	nextIdx, err := q.GetNextRowIdx(ctx, entry.SheetID)
+	if err != nil {
+		return fmt.Errorf("Could not get row count: %s", err)
+	}
+	if value.Idx > nextIdx {
+		return changedOutsideHistory("The rows of the sheet")
+	}
+
+	// Columns in the trash get their cell back too, so they still line up
+	// with the rows when restored.
+	sheetColumnIds, err := q.GetSheetColumnIds(ctx, entry.SheetID)
+	if err != nil {
+		return fmt.Errorf("Could not get columns: %s", err)
+	}
+	columnIds := make(map[uuid.UUID]bool, len(sheetColumnIds))
+	for _, columnId := range sheetColumnIds {
+		columnIds[columnId] = true
+	}
+
+	err = q.InsertRowSlot(ctx, database.InsertRowSlotParams{
+		SheetID: entry.SheetID,
+		Idx:     value.Idx,
+	})
+	if err != nil {
+		return fmt.Errorf("Could not make room for the row: %s", err)
+	}
+	err = q.RestoreSheetRow(ctx, database.RestoreSheetRowParams{
+		ID:          entry.RowID.UUID,
+		SheetID:     entry.SheetID,
+		Idx:         value.Idx,
+		OriginRowID: restoredOrigin(value.OriginID, entry.RowID.UUID),
+	})
+	if err != nil {
+		return fmt.Errorf("Could not restore row: %s", err)
+	}
+
+	for _, cell := range value.Cells {
+		if !columnIds[cell.ColumnID] {
+			continue
+		}
+		err = q.RestoreColumnData(ctx, database.RestoreColumnDataParams{
+			ID:       cell.CellID,
+			Idx:      value.Idx,
+			Value:    sql.NullString{String: cell.Value, Valid: true},
+			Type:     nullCellType(cell.Type),
+			ColumnID: cell.ColumnID,
+		})
+		if err != nil {
+			return fmt.Errorf("Could not restore cell: %s", err)
+		}
+	}
+
+	return recordHistory(ctx, q, userId, historyEntry{
+		branchId: entry.BranchID,
+		sheetId:  entry.SheetID,
+		rowId:    entry.RowID,
+		entity:   HistoryEntityRow,
+		action:   HistoryActionRestore,
+		newValue: value,
+	})
+}
+
+func deleteRowAgain(ctx context.Context, q *database.Queries, userId uuid.UUID, entry database.GetLastUndoEntryRow) error {
+	row, err := q.GetSheetRow(ctx, entry.RowID.UUID)
+	if err == sql.ErrNoRows {
+		return &UndoConflictError{Reason: "The row no longer exists"}
+	}
+	if err != nil {
+		return fmt.Errorf("Could not get row: %s", err)
+	}
+	sheet, err := q.GetSheet(ctx, row.SheetID)
+	if err != nil {
+		return fmt.Errorf("Could not get sheet: %s", err)
+	}
+
+	deleted, err := deletedRowValue(ctx, q, sheet, row.Idx)
+	if err != nil {
+		return err
+	}
+	restored := HistoryRowValue{}
+	err = decodeHistoryValue(entry.OldValue, &restored)
+	if err != nil {
+		return err
+	}
+	if !sameRowCells(deleted.oldValue.(HistoryRowValue).Cells, restored.Cells) {
+		return changedOutsideHistory("The row")
+	}
+	err = q.DeleteRow(ctx, database.DeleteRowParams{
+		SheetID: sheet.ID,
+		Idx:     row.Idx,
+	})
+	if err != nil {
+		return fmt.Errorf("Could not delete row: %s", err)
+	}
+	return recordHistory(ctx, q, userId, deleted)
+}
+
+// restoreColumn brings the deleted column back from the trash, or with its
+// ids once it was purged, its cells are then put in the rows they had that
+// still exist.
+func restoreColumn(ctx context.Context, q *database.Queries, userId uuid.UUID, entry database.GetLastUndoEntryRow) error {
+	value := HistoryColumnValue{}
+	err := decodeHistoryValue(entry.OldValue, &value)
+	if err != nil {
+		return err
+	}
+
+	_, err = q.GetColumn(ctx, entry.ColumnID.UUID)
+	if err == nil {
+		return changedOutsideHistory("The deleted column")
+	}
+	if err != sql.ErrNoRows {
+		return fmt.Errorf("Could not get column: %s", err)
+	}
+
+	columns, err := q.GetColumnsFromSheet(ctx, entry.SheetID)
+	if err != nil {
+		return fmt.Errorf("Could not get columns: %s", err)
+	}
+	isKey := value.IsKey
+	for _, column := range columns {
+		if column.Name == value.Name {
+			msg := fmt.Sprintf("A column named %s was added since", value.Name)
+			return &UndoConflictError{Reason: msg}
+		}
+		if column.IsKey {
+			isKey = false
+		}
+	}
+
+	trashed, err := q.GetTrashedColumn(ctx, entry.ColumnID.UUID)
+	switch {
+	case err == nil:
+		err = q.RestoreTrashedColumn(ctx, trashed.ID)
+		if err != nil {
+			return fmt.Errorf("Could not restore column: %s", err)
+		}
+		isKey = trashed.IsKey
+	case err == sql.ErrNoRows:
+		err = insertDeletedColumn(ctx, q, entry, value, isKey)
+		if err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("Could not get trashed column: %s", err)
+	}
+
+	value.IsKey = isKey
+	return recordHistory(ctx, q, userId, historyEntry{
+		branchId: entry.BranchID,
+		sheetId:  entry.SheetID,
+		columnId: entry.ColumnID,
+		entity:   HistoryEntityColumn,
+		action:   HistoryActionRestore,
+		newValue: value,
+	})
+}
+
+func insertDeletedColumn(ctx context.Context, q *database.Queries, entry database.GetLastUndoEntryRow, value HistoryColumnValue, isKey bool) error {
+	err := q.RestoreColumn(ctx, database.RestoreColumnParams{
+		ID:             entry.ColumnID.UUID,
+		Name:           value.Name,
+		Type:           value.Type,
+		Required:       value.Required,
+		SheetID:        entry.SheetID,
+		OrderIndex:     value.OrderIndex,
+		IsKey:          isKey,
+		OriginColumnID: restoredOrigin(value.OriginID, entry.ColumnID.UUID),
+	})
+	if err != nil {
+		return fmt.Errorf("Could not restore column: %s", err)
+	}
+
+	for _, cell := range value.Cells {
+		row, err := q.GetSheetRow(ctx, cell.RowID)
+		if err == sql.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("Could not get row: %s", err)
+		}
+		err = q.RestoreColumnData(ctx, database.RestoreColumnDataParams{
+			ID:       cell.CellID,
+			Idx:      row.Idx,
+			Value:    sql.NullString{String: cell.Value, Valid: true},
+			Type:     nullCellType(cell.Type),
+			ColumnID: entry.ColumnID.UUID,
+		})
+		if err != nil {
+			return fmt.Errorf("Could not restore cell: %s", err)
+		}
+	}
+	return nil
+}
+
+func deleteColumnAgain(ctx context.Context, q *database.Queries, userId uuid.UUID, entry database.GetLastUndoEntryRow) error {
+	column, err := q.GetColumn(ctx, entry.ColumnID.UUID)
+	if err == sql.ErrNoRows {
+		return &UndoConflictError{Reason: "The column no longer exists"}
+	}
+	if err != nil {
+		return fmt.Errorf("Could not get column: %s", err)
+	}
+	sheet, err := q.GetSheet(ctx, column.SheetID)
+	if err != nil {
+		return fmt.Errorf("Could not get sheet: %s", err)
+	}
+
+	deleted, err := deletedColumnValue(ctx, q, sheet, column.Name)
+	if err != nil {
+		return err
+	}
+	restored := HistoryColumnValue{}
+	err = decodeHistoryValue(entry.OldValue, &restored)
+	if err != nil {
+		return err
+	}
+	current := deleted.oldValue.(HistoryColumnValue)
+	if current.Name != restored.Name || current.Type != restored.Type ||
+		!sameColumnCells(current.Cells, restored.Cells) {
+		return changedOutsideHistory("The column")
+	}
+	err = q.TrashColumn(ctx, database.TrashColumnParams{
+		DeletedBy: uuid.NullUUID{UUID: userId, Valid: true},
+		ID:        column.ID,
+	})
+	if err != nil {
+		return fmt.Errorf("Could not delete column: %s", err)
+	}
+	return recordHistory(ctx, q, userId, *deleted)
+}
+
+// sameRowCells tells if the cells of a row are the ones recorded for it.
+// Recorded cells of columns purged from the trash since are not compared.
+func sameRowCells(current, recorded []HistoryRowCell) bool {
+	values := make(map[uuid.UUID]HistoryCellValue, len(recorded))
+	for _, cell := range recorded {
+		values[cell.CellID] = cell.HistoryCellValue
+	}
+	for _, cell := range current {
+		value, ok := values[cell.CellID]
+		if !ok || value != cell.HistoryCellValue {
+			return false
+		}
+	}
+	return true
+}
+
+// sameColumnCells compares the cells of a column by id, their idx may have
+// moved with deleted rows. Cells of rows deleted since are not compared.
+func sameColumnCells(current, recorded []HistoryColumnCell) bool {
+	values := make(map[uuid.UUID]HistoryCellValue, len(recorded))
+	for _, cell := range recorded {
+		values[cell.CellID] = cell.HistoryCellValue
+	}
+	for _, cell := range current {
+		value, ok := values[cell.CellID]
+		if !ok || value != cell.HistoryCellValue {
+			return false
+		}
+	}
+	return true
+}
+
+// restoredOrigin keeps origin_row_id and origin_column_id null for items
+// that are their own origin, like they were before the delete.
+func restoredOrigin(originId, id uuid.UUID) uuid.NullUUID {
+	if originId == id || originId == uuid.Nil {
+		return uuid.NullUUID{}
+	}
+	return uuid.NullUUID{UUID: originId, Valid: true}
+}
+
+func replayColumnSwap(ctx context.Context, q *database.Queries, userId uuid.UUID, entry database.GetLastUndoEntryRow, from, to sql.NullString) error {
+	expected := map[uuid.UUID]int64{}
+	err := decodeHistoryValue(from, &expected)
+	if err != nil {
+		return err
+	}
+	order := map[uuid.UUID]int64{}
+	err = decodeHistoryValue(to, &order)
+	if err != nil {
+		return err
+	}
+
+	ids := make([]uuid.UUID, 0, 2)
+	for columnId := range order {
+		column, err := q.GetColumn(ctx, columnId)
+		if err == sql.ErrNoRows {
+			return &UndoConflictError{Reason: "One of the swapped columns no longer exists"}
+		}
+		if err != nil {
+			return fmt.Errorf("Could not get column: %s", err)
+		}
+		if column.OrderIndex != expected[columnId] {
+			return changedOutsideHistory("The column order")
+		}
+		ids = append(ids, columnId)
+	}
+	if len(ids) != 2 {
+		return fmt.Errorf("Swap of %d columns can not be replayed", len(ids))
+	}
+
+	err = q.SwapColumns(ctx, database.SwapColumnsParams{
+		ID:           ids[0],
+		ID_2:         ids[1],
+		OrderIndex:   order[ids[0]],
+		OrderIndex_2: order[ids[1]],
+	})
+	if err != nil {
+		return fmt.Errorf("Could not swap columns: %s", err)
+	}
+
+	return recordHistory(ctx, q, userId, historyEntry{
+		branchId: entry.BranchID,
+		sheetId:  entry.SheetID,
+		columnId: entry.ColumnID,
+		entity:   HistoryEntityColumn,
+		action:   HistoryActionSwap,
+		oldValue: json.RawMessage(from.String),
+		newValue: order,
+	})
+}
+
+func replaySheetRename(ctx context.Context, q *database.Queries, userId uuid.UUID, entry database.GetLastUndoEntryRow, from, to sql.NullString) error {
+	expected := HistorySheetValue{}
+	err := decodeHistoryValue(from, &expected)
+	if err != nil {
+		return err
+	}
+	value := HistorySheetValue{}
+	err = decodeHistoryValue(to, &value)
+	if err != nil {
+		return err
+	}
+
+	sheet, err := q.GetSheet(ctx, entry.SheetID)
+	if err == sql.ErrNoRows {
+		return &UndoConflictError{Reason: "The sheet no longer exists"}
+	}
+	if err != nil {
+		return fmt.Errorf("Could not get sheet: %s", err)
+	}
+	if sheet.Name != expected.Name {
+		return changedOutsideHistory("The sheet name")
+	}
+
+	err = q.RenameSheet(ctx, database.RenameSheetParams{
+		Name: value.Name,
+		ID:   entry.SheetID,
+	})
+	if err != nil {
+		return fmt.Errorf("Could not rename sheet: %s", err)
+	}
+
+	return recordHistory(ctx, q, userId, historyEntry{
+		branchId: entry.BranchID,
+		sheetId:  entry.SheetID,
+		entity:   HistoryEntitySheet,
+		action:   HistoryActionRename,
+		oldValue: json.RawMessage(from.String),
+		newValue: value,
+	})
+}
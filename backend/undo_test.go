@@ -0,0 +1,56 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"testing"
+
+	"github.com/Dass33/administratum/backend/internal/database"
+	"github.com/google/uuid"
+)
+
+func TestHistoryConflicts(t *testing.T) {
+	id := func(u uuid.UUID) uuid.NullUUID { return uuid.NullUUID{UUID: u, Valid: true} }
+	column, otherColumn := uuid.New(), uuid.New()
+	row, otherRow := uuid.New(), uuid.New()
+	cell, otherCell := uuid.New(), uuid.New()
+	swap := sql.NullString{String: fmt.Sprintf(`{"%s": 0, "%s": 1}`, column, otherColumn), Valid: true}
+
+	cellEntry := database.GetLastUndoEntryRow{Entity: HistoryEntityCell, Action: HistoryActionUpdate, ColumnID: id(column), RowID: id(row), CellID: id(cell)}
+	rowEntry := database.GetLastUndoEntryRow{Entity: HistoryEntityRow, Action: HistoryActionDelete, RowID: id(row)}
+	columnEntry := database.GetLastUndoEntryRow{Entity: HistoryEntityColumn, Action: HistoryActionRename, ColumnID: id(column)}
+	swapEntry := database.GetLastUndoEntryRow{Entity: HistoryEntityColumn, Action: HistoryActionSwap, OldValue: swap}
+	sheetEntry := database.GetLastUndoEntryRow{Entity: HistoryEntitySheet, Action: HistoryActionRename}
+
+	tests := []struct {
+		name  string
+		entry database.GetLastUndoEntryRow
+		edit  database.GetSheetHistoryAfterRow
+		want  bool
+	}{
+		{"same cell", cellEntry, database.GetSheetHistoryAfterRow{Entity: HistoryEntityCell, Action: HistoryActionUpdate, CellID: id(cell)}, true},
+		{"other cell", cellEntry, database.GetSheetHistoryAfterRow{Entity: HistoryEntityCell, Action: HistoryActionUpdate, CellID: id(otherCell)}, false},
+		{"row of the cell deleted", cellEntry, database.GetSheetHistoryAfterRow{Entity: HistoryEntityRow, Action: HistoryActionDelete, RowID: id(row)}, true},
+		{"column of the cell deleted", cellEntry, database.GetSheetHistoryAfterRow{Entity: HistoryEntityColumn, Action: HistoryActionDelete, ColumnID: id(column)}, true},
+		{"column of the cell swapped", cellEntry, database.GetSheetHistoryAfterRow{Entity: HistoryEntityColumn, Action: HistoryActionSwap, ColumnID: id(column)}, false},
+		{"sheet deleted", cellEntry, database.GetSheetHistoryAfterRow{Entity: HistoryEntitySheet, Action: HistoryActionDelete}, true},
+		{"other row moved", rowEntry, database.GetSheetHistoryAfterRow{Entity: HistoryEntityRow, Action: HistoryActionCreate, RowID: id(otherRow)}, true},
+		{"cell of the row", rowEntry, database.GetSheetHistoryAfterRow{Entity: HistoryEntityCell, Action: HistoryActionUpdate, RowID: id(row)}, true},
+		{"cell of another row", rowEntry, database.GetSheetHistoryAfterRow{Entity: HistoryEntityCell, Action: HistoryActionUpdate, RowID: id(otherRow)}, false},
+		{"column deleted under the row", rowEntry, database.GetSheetHistoryAfterRow{Entity: HistoryEntityColumn, Action: HistoryActionDelete, ColumnID: id(column)}, true},
+		{"cell of the column", columnEntry, database.GetSheetHistoryAfterRow{Entity: HistoryEntityCell, Action: HistoryActionUpdate, ColumnID: id(column)}, true},
+		{"other column", columnEntry, database.GetSheetHistoryAfterRow{Entity: HistoryEntityColumn, Action: HistoryActionRename, ColumnID: id(otherColumn)}, false},
+		{"key column changed", columnEntry, database.GetSheetHistoryAfterRow{Entity: HistoryEntitySheet, Action: HistoryActionSetKey}, true},
+		{"column swapped away", columnEntry, database.GetSheetHistoryAfterRow{Entity: HistoryEntityColumn, Action: HistoryActionSwap, OldValue: swap}, true},
+		{"swapped column renamed", swapEntry, database.GetSheetHistoryAfterRow{Entity: HistoryEntityColumn, Action: HistoryActionRename, ColumnID: id(otherColumn)}, true},
+		{"cell of a swapped column", swapEntry, database.GetSheetHistoryAfterRow{Entity: HistoryEntityCell, Action: HistoryActionUpdate, ColumnID: id(column)}, false},
+		{"sheet renamed again", sheetEntry, database.GetSheetHistoryAfterRow{Entity: HistoryEntitySheet, Action: HistoryActionRename}, true},
+		{"cell of the renamed sheet", sheetEntry, database.GetSheetHistoryAfterRow{Entity: HistoryEntityCell, Action: HistoryActionUpdate, CellID: id(cell)}, false},
+	}
+
+	for _, tt := range tests {
+		if got := historyConflicts(tt.entry, tt.edit); got != tt.want {
+			t.Errorf("%s: historyConflicts = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
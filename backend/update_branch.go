@@ -1,6 +1,7 @@
 package main
 
 import (
+	"database/sql"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -13,6 +14,9 @@ type adjustBranch struct {
 	Name        string `json:"name"`
 	BranchId    string `json:"branch_id"`
 	IsProtected bool   `json:"is_protected"`
+	// RequiredApprovals is the number of approvals a merge request into the
+	// branch needs, it is left unchanged when not sent.
+	RequiredApprovals *int64 `json:"required_approvals"`
 }
 
 func (cfg *apiConfig) updateBranchHandler(w http.ResponseWriter, r *http.Request, id uuid.UUID) {
@@ -38,10 +42,31 @@ func (cfg *apiConfig) updateBranchHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	var requiredApprovals sql.NullInt64
+	if params.RequiredApprovals != nil {
+		if *params.RequiredApprovals < 0 {
+			respondWithError(w, http.StatusBadRequest, "Required approvals can not be negative")
+			return
+		}
+
+		branch, err := cfg.db.GetBranch(r.Context(), branchId)
+		if err != nil {
+			msg := fmt.Sprintf("Could not get branch: %s", err)
+			respondWithError(w, http.StatusInternalServerError, msg)
+			return
+		}
+		if !cfg.checkTablePermission(id, branch.TableID, OwnerPermission, r.Context()) {
+			respondWithError(w, http.StatusForbidden, "Only owners can change the required approvals")
+			return
+		}
+		requiredApprovals = sql.NullInt64{Int64: *params.RequiredApprovals, Valid: true}
+	}
+
 	updateBranchParams := database.UpdateBranchParams{
-		Name:        params.Name,
-		IsProtected: params.IsProtected,
-		ID:          branchId,
+		Name:              params.Name,
+		IsProtected:       params.IsProtected,
+		RequiredApprovals: requiredApprovals,
+		ID:                branchId,
 	}
 	err = cfg.db.UpdateBranch(r.Context(), updateBranchParams)
 	if err != nil {
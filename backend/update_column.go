@@ -20,7 +20,28 @@ func (cfg *apiConfig) updateColumnHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	rowsAffected, err := cfg.db.UpdateColumnWithPermissionCheck(r.Context(), database.UpdateColumnWithPermissionCheckParams{
+	oldColumn, err := cfg.db.GetColumn(r.Context(), col.ID)
+	if err != nil {
+		respondWithError(w, http.StatusForbidden, "Column not found or insufficient permissions")
+		return
+	}
+	sheet, err := cfg.db.GetSheet(r.Context(), oldColumn.SheetID)
+	if err != nil {
+		msg := fmt.Sprintf("Could not get sheet of column: %s", err)
+		respondWithError(w, http.StatusInternalServerError, msg)
+		return
+	}
+
+	tx, err := cfg.rawDB.BeginTx(r.Context(), nil)
+	if err != nil {
+		msg := fmt.Sprintf("Could not begin transaction: %s", err)
+		respondWithError(w, http.StatusInternalServerError, msg)
+		return
+	}
+	defer tx.Rollback()
+	txQueries := cfg.db.WithTx(tx)
+
+	rowsAffected, err := txQueries.UpdateColumnWithPermissionCheck(r.Context(), database.UpdateColumnWithPermissionCheckParams{
 		Name:     col.Name,
 		Type:     col.Type,
 		Required: col.Required,
@@ -37,5 +58,31 @@ func (cfg *apiConfig) updateColumnHandler(w http.ResponseWriter, r *http.Request
 		respondWithError(w, http.StatusForbidden, "Column not found or insufficient permissions")
 		return
 	}
+
+	newColumn := oldColumn
+	newColumn.Name = col.Name
+	newColumn.Type = col.Type
+	newColumn.Required = col.Required
+	err = recordHistory(r.Context(), txQueries, id, historyEntry{
+		branchId: sheet.BranchID,
+		sheetId:  sheet.ID,
+		columnId: uuid.NullUUID{UUID: col.ID, Valid: true},
+		entity:   HistoryEntityColumn,
+		action:   HistoryActionUpdate,
+		oldValue: historyColumnValue(oldColumn),
+		newValue: historyColumnValue(newColumn),
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		msg := fmt.Sprintf("Could not commit column update: %s", err)
+		respondWithError(w, http.StatusInternalServerError, msg)
+		return
+	}
+	cfg.invalidateColumnJson(col.ID, r.Context())
 	respondWithJSON(w, http.StatusOK, "")
 }
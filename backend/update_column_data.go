@@ -1,6 +1,7 @@
 package main
 
 import (
+	"database/sql"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -20,8 +21,52 @@ func (cfg *apiConfig) updateColumnDataHandler(w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	rowsAffected, err := cfg.db.UpdateColumnDataWithPermissionCheck(r.Context(), database.UpdateColumnDataWithPermissionCheckParams{
+	column, err := cfg.db.GetColumnDataColumn(r.Context(), colData.ID)
+	if err == sql.ErrNoRows {
+		respondWithError(w, http.StatusForbidden, "Column data not found or insufficient permissions")
+		return
+	}
+	if err != nil {
+		msg := fmt.Sprintf("Could not get column of column data: %s", err)
+		respondWithError(w, http.StatusInternalServerError, msg)
+		return
+	}
+	// the column is only described to users who can write to its branch
+	if !cfg.checkBranchPermission(id, column.BranchID, "write", r.Context()) {
+		respondWithError(w, http.StatusForbidden, "Column data not found or insufficient permissions")
+		return
+	}
+
+	cellType := column.DataType
+	if colData.Type.Valid {
+		cellType = colData.Type
+	}
+	valueType := cellValueType(column.ColumnType, cellType)
+	enumVals, err := cfg.getCellEnumVals(column.BranchID, valueType, r.Context())
+	if err != nil {
+		msg := fmt.Sprintf("Could not get enums for branch: %s", err)
+		respondWithError(w, http.StatusInternalServerError, msg)
+		return
+	}
+	err = validateCell(colData.Value, valueType, column.ColumnRequired, enumVals)
+	if err != nil {
+		msg := fmt.Sprintf("Invalid value for column %s: %s", column.ColumnName, err)
+		respondWithError(w, http.StatusUnprocessableEntity, msg)
+		return
+	}
+
+	tx, err := cfg.rawDB.BeginTx(r.Context(), nil)
+	if err != nil {
+		msg := fmt.Sprintf("Could not begin transaction: %s", err)
+		respondWithError(w, http.StatusInternalServerError, msg)
+		return
+	}
+	defer tx.Rollback()
+	txQueries := cfg.db.WithTx(tx)
+
+	rowsAffected, err := txQueries.UpdateColumnDataWithPermissionCheck(r.Context(), database.UpdateColumnDataWithPermissionCheckParams{
 		Value:  colData.Value,
+		Type:   colData.Type,
 		ID:     colData.ID,
 		UserID: id,
 	})
@@ -35,5 +80,34 @@ func (cfg *apiConfig) updateColumnDataHandler(w http.ResponseWriter, r *http.Req
 		respondWithError(w, http.StatusForbidden, "Column data not found or insufficient permissions")
 		return
 	}
+
+	row, err := sheetRow(r.Context(), txQueries, column.SheetID, column.DataIdx)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	err = recordUndoableHistory(r.Context(), txQueries, id, historyEntry{
+		branchId: column.BranchID,
+		sheetId:  column.SheetID,
+		columnId: uuid.NullUUID{UUID: column.ColumnID, Valid: true},
+		rowId:    uuid.NullUUID{UUID: row.ID, Valid: true},
+		cellId:   uuid.NullUUID{UUID: colData.ID, Valid: true},
+		entity:   HistoryEntityCell,
+		action:   HistoryActionUpdate,
+		oldValue: historyCellValue(column.DataValue, column.DataType),
+		newValue: historyCellValue(colData.Value, cellType),
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		msg := fmt.Sprintf("Could not commit column data update: %s", err)
+		respondWithError(w, http.StatusInternalServerError, msg)
+		return
+	}
+	cfg.invalidateColumnDataJson(colData.ID, r.Context())
 	respondWithJSON(w, http.StatusOK, "")
 }
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/Dass33/administratum/backend/internal/database"
+	"github.com/google/uuid"
+)
+
+type updateMergeRequestParams struct {
+	MergeRequestId string  `json:"merge_request_id"`
+	Title          *string `json:"title"`
+	Description    *string `json:"description"`
+	Status         *string `json:"status"`
+}
+
+// updateMergeRequestHandler lets the author or an owner edit, close and
+// reopen a merge request. The stored conflicts are refreshed whenever the
+// request stays open, so the preview follows new changes on both branches.
+func (cfg *apiConfig) updateMergeRequestHandler(w http.ResponseWriter, r *http.Request, userId uuid.UUID) {
+	decoder := json.NewDecoder(r.Body)
+	params := updateMergeRequestParams{}
+	err := decoder.Decode(&params)
+	if err != nil {
+		msg := fmt.Sprintf("Error decoding parameters: %s", err)
+		respondWithError(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	mergeRequestId, err := uuid.Parse(params.MergeRequestId)
+	if err != nil {
+		msg := fmt.Sprintf("Could not parse the merge request id: %s", err)
+		respondWithError(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	mergeRequestDb, err := cfg.db.GetMergeRequest(r.Context(), mergeRequestId)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Merge request not found")
+		return
+	}
+
+	isAuthor := mergeRequestDb.AuthorID.Valid && mergeRequestDb.AuthorID.UUID == userId
+	if !isAuthor && !cfg.checkTablePermission(userId, mergeRequestDb.TableID, OwnerPermission, r.Context()) {
+		respondWithError(w, http.StatusForbidden, "Only the author or an owner can update the merge request")
+		return
+	}
+
+	if mergeRequestDb.Status == MergeRequestMerged {
+		respondWithError(w, http.StatusConflict, "Merge request is already merged")
+		return
+	}
+
+	updateParams := database.UpdateMergeRequestParams{
+		Title:       mergeRequestDb.Title,
+		Description: mergeRequestDb.Description,
+		Status:      mergeRequestDb.Status,
+		Conflicts:   mergeRequestDb.Conflicts,
+		ID:          mergeRequestId,
+	}
+	if params.Title != nil {
+		updateParams.Title = strings.TrimSpace(*params.Title)
+		if updateParams.Title == "" {
+			respondWithError(w, http.StatusBadRequest, "Merge request needs a title")
+			return
+		}
+	}
+	if params.Description != nil {
+		updateParams.Description = *params.Description
+	}
+	if params.Status != nil {
+		if *params.Status != MergeRequestOpen && *params.Status != MergeRequestClosed {
+			msg := fmt.Sprintf("Merge request status can only be set to %s or %s", MergeRequestOpen, MergeRequestClosed)
+			respondWithError(w, http.StatusBadRequest, msg)
+			return
+		}
+		updateParams.Status = *params.Status
+	}
+
+	if updateParams.Status == MergeRequestOpen {
+		if !mergeRequestDb.SourceBranchID.Valid || !mergeRequestDb.TargetBranchID.Valid {
+			respondWithError(w, http.StatusConflict, "Branch of the merge request no longer exists")
+			return
+		}
+
+		open, err := cfg.db.GetOpenMergeRequestFromBranch(r.Context(), mergeRequestDb.SourceBranchID)
+		if err == nil && open.ID != mergeRequestId {
+			respondWithError(w, http.StatusConflict, "Branch already has an open merge request")
+			return
+		} else if err != nil && err != sql.ErrNoRows {
+			msg := fmt.Sprintf("Could not get open merge requests: %s", err)
+			respondWithError(w, http.StatusInternalServerError, msg)
+			return
+		}
+
+		sourceBranch, err := cfg.db.GetBranch(r.Context(), mergeRequestDb.SourceBranchID.UUID)
+		if err != nil {
+			respondWithError(w, http.StatusNotFound, "Source branch not found")
+			return
+		}
+		targetBranch, err := cfg.db.GetBranch(r.Context(), mergeRequestDb.TargetBranchID.UUID)
+		if err != nil {
+			respondWithError(w, http.StatusNotFound, "Target branch not found")
+			return
+		}
+
+		conflicts, err := cfg.getMergeConflicts(sourceBranch, targetBranch, r.Context())
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		conflictsJson, err := json.Marshal(conflicts)
+		if err != nil {
+			msg := fmt.Sprintf("Could not encode conflicts: %s", err)
+			respondWithError(w, http.StatusInternalServerError, msg)
+			return
+		}
+		updateParams.Conflicts = string(conflictsJson)
+	}
+
+	err = cfg.db.UpdateMergeRequest(r.Context(), updateParams)
+	if err != nil {
+		msg := fmt.Sprintf("Merge request could not be updated: %s", err)
+		respondWithError(w, http.StatusInternalServerError, msg)
+		return
+	}
+
+	mergeRequest, err := cfg.getMergeRequest(mergeRequestId, r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	respondWithJSON(w, http.StatusOK, mergeRequest)
+}
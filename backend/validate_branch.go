@@ -0,0 +1,245 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/Dass33/administratum/backend/internal/database"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+const (
+	FindingInvalidValue     = "invalid_value"
+	FindingInvalidEnumValue = "invalid_enum_value"
+	FindingRequired         = "required"
+	FindingDuplicateKey     = "duplicate_key"
+	FindingNotEnoughColumns = "not_enough_columns"
+	FindingMissingType      = "missing_type"
+	FindingEmptyRow         = "empty_row"
+)
+
+// ValidationFinding points at the sheet, column and row of a problem, the
+// column and row are left out for findings about a whole sheet or row.
+type ValidationFinding struct {
+	Severity string        `json:"severity"`
+	Code     string        `json:"code"`
+	SheetID  uuid.UUID     `json:"sheet_id"`
+	Sheet    string        `json:"sheet"`
+	ColumnID uuid.NullUUID `json:"column_id"`
+	Column   string        `json:"column,omitempty"`
+	Row      *int64        `json:"row,omitempty"`
+	Value    string        `json:"value,omitempty"`
+	Message  string        `json:"message"`
+}
+
+type ValidationReport struct {
+	BranchID     uuid.UUID           `json:"branch_id"`
+	Valid        bool                `json:"valid"`
+	ErrorCount   int                 `json:"error_count"`
+	WarningCount int                 `json:"warning_count"`
+	Findings     []ValidationFinding `json:"findings"`
+}
+
+func (report *ValidationReport) add(finding ValidationFinding) {
+	if finding.Severity == SeverityError {
+		report.ErrorCount++
+	} else {
+		report.WarningCount++
+	}
+	report.Valid = report.ErrorCount == 0
+	report.Findings = append(report.Findings, finding)
+}
+
+func (cfg *apiConfig) validateBranchHandler(w http.ResponseWriter, r *http.Request, userId uuid.UUID) {
+	branchIdStr := chi.URLParam(r, "branch_id")
+	branchId, err := uuid.Parse(branchIdStr)
+	if err != nil {
+		msg := fmt.Sprintf("Could not parse the branch id from url: %s", err)
+		respondWithError(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	if !cfg.checkBranchPermission(userId, branchId, "read", r.Context()) {
+		respondWithError(w, http.StatusForbidden, "Insufficient read permissions")
+		return
+	}
+
+	report, err := cfg.validateBranch(branchId, r.Context())
+	if err != nil {
+		msg := fmt.Sprintf("Could not validate branch: %s", err)
+		respondWithError(w, http.StatusInternalServerError, msg)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, report)
+}
+
+// validateBranch scans every sheet of the branch for values the export would
+// reject or silently drop.
+func (cfg *apiConfig) validateBranch(branchId uuid.UUID, ctx context.Context) (ValidationReport, error) {
+	report := ValidationReport{
+		BranchID: branchId,
+		Valid:    true,
+		Findings: []ValidationFinding{},
+	}
+
+	sheets, err := cfg.db.GetSheetsFromBranch(ctx, branchId)
+	if err != nil {
+		return report, fmt.Errorf("Could not get sheets from branch id: %s", err)
+	}
+
+	enums, err := cfg.getEnumsForBranch(branchId, ctx)
+	if err != nil {
+		return report, fmt.Errorf("Could not get enums for branch: %s", err)
+	}
+	enumVals := getEnumVals(enums)
+
+	for _, sheet := range sheets {
+		columns, err := cfg.GetColumns(sheet.ID, ctx)
+		if err != nil {
+			return report, err
+		}
+
+		if sheet.Type == SheetTypeMap {
+			validateMapSheet(&report, sheet, columns, enumVals)
+		} else {
+			validateListSheet(&report, sheet, columns, enumVals)
+		}
+	}
+	return report, nil
+}
+
+func validateListSheet(report *ValidationReport, sheet database.Sheet, columns []Column, enumVals map[string][]string) {
+	keys := make(map[string]int64)
+	for i := range sheetRowCount(columns) {
+		if isEmptyRow(columns, i) {
+			report.add(rowFinding(sheet, SeverityWarning, FindingEmptyRow, i, "Row is empty"))
+			continue
+		}
+
+		for e := range columns {
+			col := &columns[e]
+			cell, _ := getDataAtColIdx(col.Data, i)
+			if empty, err := checkEmptyCell(cell.Value, col.Required); empty {
+				if err != nil {
+					report.add(cellFinding(sheet, col, cell, i, FindingRequired, "Value is required"))
+				}
+				continue
+			}
+
+			if finding, ok := validateFindingValue(sheet, col, cell, i, col.Type, enumVals); !ok {
+				report.add(finding)
+				continue
+			}
+
+			if col.IsKey {
+				if firstRow, exists := keys[cell.Value.String]; exists {
+					msg := fmt.Sprintf("Key '%s' is already used in row %d", cell.Value.String, firstRow)
+					report.add(cellFinding(sheet, col, cell, i, FindingDuplicateKey, msg))
+				} else {
+					keys[cell.Value.String] = i
+				}
+			}
+		}
+	}
+}
+
+func validateMapSheet(report *ValidationReport, sheet database.Sheet, columns []Column, enumVals map[string][]string) {
+	if len(columns) < 2 {
+		report.add(ValidationFinding{
+			Severity: SeverityError,
+			Code:     FindingNotEnoughColumns,
+			SheetID:  sheet.ID,
+			Sheet:    sheet.Name,
+			Message:  "Map sheets need a name and a value column",
+		})
+		return
+	}
+
+	nameCol := &columns[0]
+	valCol := &columns[1]
+	names := make(map[string]int64)
+	for i := range sheetRowCount(columns) {
+		if isEmptyRow(columns, i) {
+			report.add(rowFinding(sheet, SeverityWarning, FindingEmptyRow, i, "Row is empty"))
+			continue
+		}
+
+		nameCell, _ := getDataAtColIdx(nameCol.Data, i)
+		if nameCell.Value.String == "" {
+			report.add(cellFinding(sheet, nameCol, nameCell, i, FindingRequired, "Name is required"))
+		} else if firstRow, exists := names[nameCell.Value.String]; exists {
+			msg := fmt.Sprintf("Name '%s' is already used in row %d and would overwrite it", nameCell.Value.String, firstRow)
+			report.add(cellFinding(sheet, nameCol, nameCell, i, FindingDuplicateKey, msg))
+		} else {
+			names[nameCell.Value.String] = i
+		}
+
+		valCell, _ := getDataAtColIdx(valCol.Data, i)
+		// the value of an entry is always required, as in the export
+		if empty, _ := checkEmptyCell(valCell.Value, true); empty {
+			report.add(cellFinding(sheet, valCol, valCell, i, FindingRequired, "Value is required"))
+			continue
+		}
+		if !valCell.Type.Valid {
+			report.add(cellFinding(sheet, valCol, valCell, i, FindingMissingType, "Value has no type and is left out of the export"))
+			continue
+		}
+		if finding, ok := validateFindingValue(sheet, valCol, valCell, i, valCell.Type.String, enumVals); !ok {
+			report.add(finding)
+		}
+	}
+}
+
+func validateFindingValue(sheet database.Sheet, col *Column, cell ColumnData, row int64, valueType string, enumVals map[string][]string) (ValidationFinding, bool) {
+	err := validateCellValue(cell.Value.String, valueType, enumVals)
+	if err == nil {
+		return ValidationFinding{}, true
+	}
+
+	code := FindingInvalidValue
+	if _, ok := enumVals[valueType]; ok {
+		code = FindingInvalidEnumValue
+	}
+	return cellFinding(sheet, col, cell, row, code, err.Error()), false
+}
+
+func isEmptyRow(columns []Column, row int64) bool {
+	for e := range columns {
+		if cell, ok := getDataAtColIdx(columns[e].Data, row); ok && cell.Value.String != "" {
+			return false
+		}
+	}
+	return true
+}
+
+func cellFinding(sheet database.Sheet, col *Column, cell ColumnData, row int64, code string, msg string) ValidationFinding {
+	return ValidationFinding{
+		Severity: SeverityError,
+		Code:     code,
+		SheetID:  sheet.ID,
+		Sheet:    sheet.Name,
+		ColumnID: uuid.NullUUID{UUID: col.ID, Valid: true},
+		Column:   col.Name,
+		Row:      &row,
+		Value:    cell.Value.String,
+		Message:  msg,
+	}
+}
+
+func rowFinding(sheet database.Sheet, severity string, code string, row int64, msg string) ValidationFinding {
+	return ValidationFinding{
+		Severity: severity,
+		Code:     code,
+		SheetID:  sheet.ID,
+		Sheet:    sheet.Name,
+		Row:      &row,
+		Message:  msg,
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/Dass33/administratum/backend/internal/database"
+	"github.com/google/uuid"
+)
+
+// TestValidateListSheetMatchesExport checks that the branch validation and
+// the export agree on which empty cells are fine.
+func TestValidateListSheetMatchesExport(t *testing.T) {
+	tests := []struct {
+		name     string
+		required bool
+		valid    bool
+	}{
+		{name: "optional", required: false, valid: true},
+		{name: "required", required: true, valid: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sheet := database.Sheet{ID: uuid.New(), Name: "items"}
+			columns := []Column{
+				{ID: uuid.New(), Name: "name", Type: "text", Data: []ColumnData{testCell(0, "sword"), testCell(1, "shield")}},
+				{ID: uuid.New(), Name: "price", Type: "number", Required: tt.required, Data: []ColumnData{testCell(0, "10"), testCell(1, "")}},
+			}
+
+			report := ValidationReport{Valid: true}
+			validateListSheet(&report, sheet, columns, nil)
+			_, cellErrors := listSheetJsonRows(sheet, columns, 2, nil, false)
+
+			if report.Valid != tt.valid {
+				t.Errorf("validation valid = %v, want %v: %v", report.Valid, tt.valid, report.Findings)
+			}
+			if exported := len(cellErrors) == 0; exported != tt.valid {
+				t.Errorf("export succeeded = %v, want %v: %v", exported, tt.valid, cellErrors)
+			}
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/Dass33/administratum/backend/internal/database"
+	"github.com/google/uuid"
+)
+
+// CellError locates a cell whose value does not fit its column.
+type CellError struct {
+	SheetID  uuid.UUID `json:"sheet_id"`
+	Sheet    string    `json:"sheet"`
+	ColumnID uuid.UUID `json:"column_id"`
+	Column   string    `json:"column"`
+	Row      int64     `json:"row"`
+	Value    string    `json:"value"`
+	Error    string    `json:"error"`
+}
+
+func newCellError(sheet database.Sheet, col *Column, cell ColumnData, err error) CellError {
+	return CellError{
+		SheetID:  sheet.ID,
+		Sheet:    sheet.Name,
+		ColumnID: col.ID,
+		Column:   col.Name,
+		Row:      cell.Idx,
+		Value:    cell.Value.String,
+		Error:    err.Error(),
+	}
+}
+
+// InvalidCellsError is returned when a branch can not be exported because
+// some of its cells do not parse.
+type InvalidCellsError struct {
+	Cells []CellError
+}
+
+func (e *InvalidCellsError) Error() string {
+	return fmt.Sprintf("%d cells hold invalid values", len(e.Cells))
+}
+
+type invalidCellsResponse struct {
+	Error        string      `json:"error"`
+	InvalidCells []CellError `json:"invalid_cells"`
+}
+
+// respondWithBranchJsonError lists the invalid cells when err is an
+// InvalidCellsError and falls back to an internal error otherwise.
+func respondWithBranchJsonError(w http.ResponseWriter, err error) {
+	var invalidCells *InvalidCellsError
+	if errors.As(err, &invalidCells) {
+		respondWithJSON(w, http.StatusUnprocessableEntity, invalidCellsResponse{
+			Error:        "Branch contains invalid values",
+			InvalidCells: invalidCells.Cells,
+		})
+		return
+	}
+	respondWithError(w, http.StatusInternalServerError, err.Error())
+}
+
+func isValidCellType(valueType string, enumVals map[string][]string) bool {
+	if _, ok := enumVals[valueType]; ok {
+		return true
+	}
+	switch strings.ToLower(valueType) {
+	case "text", "string", "number", "int", "float", "array", "bool", "boolean":
+		return true
+	}
+	return false
+}
+
+// cellValueType returns the type a cell is parsed with. Columns of type any,
+// like the value column of map sheets, leave the type to every cell.
+func cellValueType(colType string, cellType sql.NullString) string {
+	if strings.EqualFold(colType, "any") && cellType.Valid {
+		return cellType.String
+	}
+	return colType
+}
+
+func validateCellValue(input string, valueType string, enumVals map[string][]string) error {
+	if vals, ok := enumVals[valueType]; ok {
+		if !slices.Contains(vals, input) {
+			return fmt.Errorf("'%s' is not a value of enum %s", input, valueType)
+		}
+		return nil
+	}
+	_, err := ParseValue(input, valueType)
+	return err
+}
+
+var errValueRequired = errors.New("value is required")
+
+// checkEmptyCell is the rule for cells without a value shared by the writes,
+// the branch validation and the export: empty cells are left out, and only
+// fail in required columns. The bool tells whether the cell is empty.
+func checkEmptyCell(value sql.NullString, required bool) (bool, error) {
+	if value.Valid && value.String != "" {
+		return false, nil
+	}
+	if required {
+		return true, errValueRequired
+	}
+	return true, nil
+}
+
+// validateCell checks a value before it is written, empty values are only
+// rejected in required columns.
+func validateCell(value sql.NullString, valueType string, required bool, enumVals map[string][]string) error {
+	if empty, err := checkEmptyCell(value, required); empty {
+		return err
+	}
+	return validateCellValue(value.String, valueType, enumVals)
+}
+
+// getCellEnumVals loads the enums of the branch only when the value type is
+// not one of the plain types, so most writes do not read the enum sheets.
+func (cfg *apiConfig) getCellEnumVals(branchId uuid.UUID, valueType string, ctx context.Context) (map[string][]string, error) {
+	if isValidCellType(valueType, nil) {
+		return nil, nil
+	}
+	enums, err := cfg.getEnumsForBranch(branchId, ctx)
+	if err != nil {
+		return nil, err
+	}
+	return getEnumVals(enums), nil
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"database/sql"
+	"testing"
+)
+
+func TestValidateCell(t *testing.T) {
+	enumVals := map[string][]string{"rarity": {"common", "rare"}}
+	tests := []struct {
+		name      string
+		value     sql.NullString
+		valueType string
+		required  bool
+		wantErr   bool
+	}{
+		{name: "number", value: sql.NullString{String: "1,5", Valid: true}, valueType: "number"},
+		{name: "invalid number", value: sql.NullString{String: "abc", Valid: true}, valueType: "number", wantErr: true},
+		{name: "empty optional", value: sql.NullString{String: "", Valid: true}, valueType: "number"},
+		{name: "null optional", value: sql.NullString{}, valueType: "number"},
+		{name: "empty required", value: sql.NullString{String: "", Valid: true}, valueType: "text", required: true, wantErr: true},
+		{name: "null required", value: sql.NullString{}, valueType: "text", required: true, wantErr: true},
+		{name: "enum value", value: sql.NullString{String: "rare", Valid: true}, valueType: "rarity"},
+		{name: "not an enum value", value: sql.NullString{String: "epic", Valid: true}, valueType: "rarity", wantErr: true},
+		{name: "array", value: sql.NullString{String: "[1, 2]", Valid: true}, valueType: "array"},
+		{name: "unsupported type", value: sql.NullString{String: "x", Valid: true}, valueType: "date", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateCell(tt.value, tt.valueType, tt.required, enumVals)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateCell(%q, %s) error = %v, want error %v", tt.value.String, tt.valueType, err, tt.wantErr)
+			}
+		})
+	}
+}
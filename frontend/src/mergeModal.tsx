@@ -18,15 +18,51 @@ type MergeConflict = {
     target_updated_at: string;
 };
 
+type ResolutionOption = {
+    value: string;
+    label: string;
+    detail: string;
+};
+
+const isDeleteConflict = (conflict: MergeConflict) =>
+    conflict.property === 'deleted_in_source' || conflict.property === 'deleted_in_target';
+
+const resolutionOptions = (conflict: MergeConflict): ResolutionOption[] => {
+    if (isDeleteConflict(conflict)) {
+        const deletedBy = conflict.property === 'deleted_in_source' ? 'source branch' : 'current branch';
+        const changedBy = conflict.property === 'deleted_in_source' ? 'current branch' : 'source branch';
+        return [
+            { value: 'keep', label: 'Keep', detail: `Keep the changes of the ${changedBy}` },
+            { value: 'delete', label: 'Delete', detail: `Delete as in the ${deletedBy}` },
+        ];
+    }
+    return [
+        { value: 'source', label: 'Keep from source branch', detail: `"${conflict.source_value}"` },
+        { value: 'target', label: 'Keep current value', detail: `"${conflict.target_value}"` },
+    ];
+};
+
 type MergePreviewResponse = {
     conflicts: MergeConflict[];
+    preview_token: string;
 };
 
 type MergeResolution = {
     conflict_id: string;
     chosen_source: string;
+    value?: string;
 };
 
+type MergeStrategy = {
+    sheet_id?: string;
+    prefer: string;
+};
+
+const allowsCustomValue = (conflict: MergeConflict) =>
+    conflict.type === 'cell_data' ||
+    (conflict.type === 'column_property' && (conflict.property === 'name' || conflict.property === 'type')) ||
+    (conflict.type === 'sheet_property' && conflict.property === 'name');
+
 type MergeTarget = {
     id: string;
     name: string;
@@ -63,7 +99,10 @@ export default function MergeModal() {
 
     const [selectedBranch, setSelectedBranch] = useState<string>("");
     const [conflicts, setConflicts] = useState<MergeConflict[]>([]);
+    const [previewToken, setPreviewToken] = useState<string>("");
     const [resolutions, setResolutions] = useState<Record<string, string>>({});
+    const [customValues, setCustomValues] = useState<Record<string, string>>({});
+    const [strategy, setStrategy] = useState<string>("");
     const [loading, setModalLoading] = useState(false);
     const [step, setStep] = useState<'select' | 'conflicts' | 'no-conflicts' | 'merging'>('select');
     const [error, setError] = useState<string>("");
@@ -151,6 +190,7 @@ export default function MergeModal() {
             const data: MergePreviewResponse = await response.json();
             const conflicts = data.conflicts || [];
             setConflicts(conflicts);
+            setPreviewToken(data.preview_token);
 
             if (conflicts.length === 0) {
                 setStep('no-conflicts');
@@ -172,16 +212,22 @@ export default function MergeModal() {
         setModalLoading(true);
         setError("");
 
-        const mergeResolutions: MergeResolution[] = conflicts.map(conflict => ({
-            conflict_id: conflict.id,
-            chosen_source: resolutions[conflict.id] || 'target'
-        }));
+        const mergeResolutions: MergeResolution[] = conflicts
+            .filter(conflict => resolutions[conflict.id])
+            .map(conflict => ({
+                conflict_id: conflict.id,
+                chosen_source: resolutions[conflict.id],
+                value: resolutions[conflict.id] === 'custom' ? customValues[conflict.id] ?? '' : undefined
+            }));
+        const strategies: MergeStrategy[] = strategy ? [{ prefer: strategy }] : [];
 
 
         try {
             const requestBody = {
                 source_branch_id: selectedBranch,
-                resolutions: mergeResolutions
+                resolutions: mergeResolutions,
+                strategies: strategies,
+                preview_token: previewToken
             };
 
             const response = await fetch(`${Domain}/merge_execute`, {
@@ -203,6 +249,10 @@ export default function MergeModal() {
                     throw new Error('This branch cannot be merged here. You can only merge a branch into its direct parent branch.');
                 }
 
+                if (response.status === 409) {
+                    throw new Error('The branches changed or another merge is running, preview the merge again.');
+                }
+
                 throw new Error(`Failed to execute merge: ${response.status}`);
             }
 
@@ -231,7 +281,7 @@ export default function MergeModal() {
         }));
     };
 
-    const allConflictsResolved = !conflicts || conflicts.length === 0 || conflicts.every(conflict =>
+    const allConflictsResolved = !conflicts || conflicts.length === 0 || strategy !== '' || conflicts.every(conflict =>
         resolutions[conflict.id]
     );
 
@@ -305,6 +355,21 @@ export default function MergeModal() {
                                 Found {conflicts?.length || 0} conflict{(conflicts?.length || 0) !== 1 ? 's' : ''} that need resolution:
                             </p>
 
+                            <div className="flex items-center justify-between mb-4">
+                                <span>Resolve the remaining conflicts:</span>
+                                <Dropdown
+                                    options={[
+                                        { label: "Choose each", value: "" },
+                                        { label: "Prefer source", value: "source" },
+                                        { label: "Prefer current", value: "target" },
+                                        { label: "Prefer newest", value: "newest" },
+                                    ]}
+                                    placeholder="Choose each"
+                                    onSelect={(option: DropdownOption) => setStrategy(option.value)}
+                                    usePortal={true}
+                                />
+                            </div>
+
                             <div className="space-y-4 max-h-96 overflow-y-auto">
                                 {conflicts?.map(conflict => (
                                     <div key={conflict.id} className="border border-figma-gray rounded-lg p-4 bg-figma-white">
@@ -313,44 +378,54 @@ export default function MergeModal() {
                                                 {conflict.type === 'cell_data' && `Cell conflict in ${conflict.sheet_name}/${conflict.column_name} (row ${conflict.row_index})`}
                                                 {conflict.type === 'column_property' && `Column ${conflict.property} conflict in ${conflict.sheet_name}/${conflict.column_name}`}
                                                 {conflict.type === 'sheet_property' && `Sheet ${conflict.property} conflict in ${conflict.sheet_name}`}
+                                                {conflict.type === 'sheet_delete' && `Sheet ${conflict.sheet_name} was deleted on one branch and changed on the other`}
+                                                {conflict.type === 'column_delete' && `Column ${conflict.sheet_name}/${conflict.column_name} was deleted on one branch and changed on the other`}
+                                                {conflict.type === 'row_insert' && `Row with the same ${conflict.column_name} was added on both branches in ${conflict.sheet_name}`}
+                                                {conflict.type === 'row_delete' && `Row ${conflict.row_index} in ${conflict.sheet_name} was deleted on one branch and changed on the other`}
                                             </strong>
                                         </div>
 
                                         <div className="grid grid-cols-2 gap-4">
-                                            <div>
-                                                <label className="flex items-center space-x-2 cursor-pointer">
-                                                    <input
-                                                        type="radio"
-                                                        name={conflict.id}
-                                                        value="source"
-                                                        checked={resolutions[conflict.id] === 'source'}
-                                                        onChange={() => handleResolution(conflict.id, 'source')}
-                                                        className="text-green-600 focus:ring-green-500"
-                                                    />
-                                                    <div>
-                                                        <div className="font-medium text-figma-black">Keep from source branch</div>
-                                                        <div className="text-sm text-gray-600">"{conflict.source_value}"</div>
-                                                    </div>
-                                                </label>
-                                            </div>
-
-                                            <div>
-                                                <label className="flex items-center space-x-2 cursor-pointer">
-                                                    <input
-                                                        type="radio"
-                                                        name={conflict.id}
-                                                        value="target"
-                                                        checked={resolutions[conflict.id] === 'target'}
-                                                        onChange={() => handleResolution(conflict.id, 'target')}
-                                                        className="text-green-600 focus:ring-green-500"
-                                                    />
-                                                    <div>
-                                                        <div className="font-medium text-figma-black">Keep current value</div>
-                                                        <div className="text-sm text-gray-600">"{conflict.target_value}"</div>
-                                                    </div>
-                                                </label>
-                                            </div>
+                                            {resolutionOptions(conflict).map(option => (
+                                                <div key={option.value}>
+                                                    <label className="flex items-center space-x-2 cursor-pointer">
+                                                        <input
+                                                            type="radio"
+                                                            name={conflict.id}
+                                                            value={option.value}
+                                                            checked={resolutions[conflict.id] === option.value}
+                                                            onChange={() => handleResolution(conflict.id, option.value)}
+                                                            className="text-green-600 focus:ring-green-500"
+                                                        />
+                                                        <div>
+                                                            <div className="font-medium text-figma-black">{option.label}</div>
+                                                            <div className="text-sm text-gray-600">{option.detail}</div>
+                                                        </div>
+                                                    </label>
+                                                </div>
+                                            ))}
                                         </div>
+
+                                        {allowsCustomValue(conflict) && (
+                                            <label className="flex items-center space-x-2 cursor-pointer mt-2">
+                                                <input
+                                                    type="radio"
+                                                    name={conflict.id}
+                                                    value="custom"
+                                                    checked={resolutions[conflict.id] === 'custom'}
+                                                    onChange={() => handleResolution(conflict.id, 'custom')}
+                                                    className="text-green-600 focus:ring-green-500"
+                                                />
+                                                <input
+                                                    type="text"
+                                                    placeholder="Custom value"
+                                                    value={customValues[conflict.id] ?? ''}
+                                                    onFocus={() => handleResolution(conflict.id, 'custom')}
+                                                    onChange={(e) => setCustomValues(prev => ({ ...prev, [conflict.id]: e.target.value }))}
+                                                    className="flex-1 px-2 py-1 border border-figma-gray rounded-lg focus:outline-none"
+                                                />
+                                            </label>
+                                        )}
                                     </div>
                                 ))}
                             </div>